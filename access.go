@@ -0,0 +1,88 @@
+package tinybtree
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	accessSamples = 4096 // number of most recent samples kept
+	hotRangeParts = 64   // number of ranges the keys are split into
+)
+
+// accessStats samples the keys of Get and Set calls. It's safe to record
+// from concurrent readers.
+type accessStats struct {
+	rate    uint64
+	count   uint64
+	samples [accessSamples]int64
+}
+
+func (s *accessStats) record(key int64) {
+	if n := atomic.AddUint64(&s.count, 1); n%s.rate == 0 {
+		atomic.StoreInt64(&s.samples[(n/s.rate-1)%accessSamples], key)
+	}
+}
+
+// SampleAccess starts recording the key of one in every rate Get and Set
+// calls, keeping the most recent samples for HotRanges. Any previous samples
+// are dropped. A rate of zero or less stops the sampling.
+func (tr *BTree) SampleAccess(rate int) {
+	if rate <= 0 {
+		tr.access = nil
+		return
+	}
+	tr.access = &accessStats{rate: uint64(rate)}
+}
+
+// HotRange is a range of keys [Min, Max] and the number of sampled accesses
+// that fell within it.
+type HotRange struct {
+	Min, Max int64
+	Hits     int
+}
+
+// HotRanges splits the keys of the tree into ranges holding about the same
+// number of items and returns up to n of them with the most sampled
+// accesses, hottest first. Accesses to keys outside of the tree count
+// towards the nearest range. Returns nil when sampling is off.
+func (tr *BTree) HotRanges(n int) []HotRange {
+	if tr.access == nil || tr.root == nil || n <= 0 {
+		return nil
+	}
+	parts := hotRangeParts
+	if parts > tr.length {
+		parts = tr.length
+	}
+	ranges := make([]HotRange, parts)
+	for i := range ranges {
+		lo := i * tr.length / parts
+		hi := (i+1)*tr.length/parts - 1
+		ranges[i].Min = tr.root.at(lo, tr.height).key
+		ranges[i].Max = tr.root.at(hi, tr.height).key
+	}
+	count := atomic.LoadUint64(&tr.access.count) / tr.access.rate
+	if count > accessSamples {
+		count = accessSamples
+	}
+	for i := uint64(0); i < count; i++ {
+		key := atomic.LoadInt64(&tr.access.samples[i])
+		j := sort.Search(len(ranges), func(j int) bool {
+			return ranges[j].Min > key
+		})
+		if j > 0 {
+			j--
+		}
+		ranges[j].Hits++
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Hits > ranges[j].Hits
+	})
+	for len(ranges) > 0 && ranges[len(ranges)-1].Hits == 0 {
+		ranges = ranges[:len(ranges)-1]
+	}
+	if len(ranges) > n {
+		ranges = ranges[:n]
+	}
+	return ranges
+}
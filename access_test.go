@@ -0,0 +1,52 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotRanges(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, 0, len(tr.HotRanges(1)))
+	tr.SampleAccess(3)
+	for i := 0; i < 6400; i++ {
+		tr.Set(int64(i), i)
+	}
+	for i := 0; i < 30000; i++ {
+		tr.Get(int64(1000 + i%100))
+	}
+	// only the most recent samples are kept, so the sets are forgotten
+	ranges := tr.HotRanges(2)
+	assert.Equal(t, []HotRange{{Min: 1000, Max: 1099, Hits: 4096}}, ranges)
+
+	for i := 0; i < 3000; i++ {
+		tr.Get(int64(6000 + i%400))
+	}
+	ranges = tr.HotRanges(10)
+	assert.Equal(t, 5, len(ranges))
+	assert.Equal(t, HotRange{Min: 1000, Max: 1099, Hits: 3096}, ranges[0])
+	var hits int
+	for _, r := range ranges[1:] {
+		if r.Min < 6000 || r.Max > 6399 {
+			t.Fatalf("unexpected range %v", r)
+		}
+		hits += r.Hits
+	}
+	assert.Equal(t, 1000, hits)
+
+	tr.SampleAccess(0)
+	tr.Get(1)
+	assert.Equal(t, 0, len(tr.HotRanges(1)))
+}
+
+func TestHotRangesFirstSample(t *testing.T) {
+	var tr BTree
+	for i := -100000; i <= 99000; i += 1000 {
+		tr.Set(int64(i), i)
+	}
+	tr.SampleAccess(1)
+	tr.Get(99000)
+	assert.Equal(t, []HotRange{{Min: 96000, Max: 99000, Hits: 1}},
+		tr.HotRanges(10))
+}
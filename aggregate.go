@@ -0,0 +1,101 @@
+package tinybtree
+
+// Aggregator maintains a summary of the items in each subtree, such as the
+// sum, min or max of the values. A nil aggregate stands for no items.
+type Aggregator interface {
+	// Lift returns the aggregate of a single item.
+	Lift(key int64, value interface{}) interface{}
+	// Merge combines the aggregates of two adjacent ranges, where a covers
+	// the smaller keys.
+	Merge(a, b interface{}) interface{}
+}
+
+// aggregate recomputes the aggregate of the node from its items and the
+// aggregates of its children.
+func (n *node) aggregate(tr *BTree, height int) {
+	if tr.agg == nil {
+		return
+	}
+	var a interface{}
+	for i := 0; i < n.numItems; i++ {
+		if height > 0 {
+			a = merge(tr.agg, a, n.children[i].agg)
+		}
+		a = merge(tr.agg, a, tr.agg.Lift(n.keys[i], n.vals[i].val()))
+	}
+	if height > 0 {
+		a = merge(tr.agg, a, n.children[n.numItems].agg)
+	}
+	n.agg = a
+}
+
+func merge(agg Aggregator, a, b interface{}) interface{} {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return agg.Merge(a, b)
+}
+
+// Aggregate returns the aggregate of all items in the tree, or nil when the
+// tree is empty or has no Aggregator.
+func (tr *BTree) Aggregate() interface{} {
+	if tr.root == nil {
+		return nil
+	}
+	return tr.root.agg
+}
+
+// AggregateRange returns the aggregate of the items within the range
+// [lo, hi], or nil when the range is empty or the tree has no Aggregator.
+func (tr *BTree) AggregateRange(lo, hi int64) interface{} {
+	if tr.root == nil || tr.agg == nil || lo > hi {
+		return nil
+	}
+	return tr.root.aggregateRange(tr.agg, lo, hi, true, true, tr.height)
+}
+
+func (n *node) aggregateRange(
+	agg Aggregator, lo, hi int64, checkLo, checkHi bool, height int,
+) (a interface{}) {
+	if !checkLo && !checkHi {
+		// the whole subtree is within the range
+		return n.agg
+	}
+	i, j := 0, n.numItems
+	if checkLo {
+		i, _ = n.findFirst(lo)
+	}
+	if checkHi {
+		var found bool
+		j, found = n.find(hi)
+		if found {
+			j++
+		}
+	}
+	if height > 0 {
+		a = n.children[i].aggregateRange(agg, lo, hi,
+			checkLo, checkHi && i == j, height-1)
+	}
+	for ; i < j; i++ {
+		a = merge(agg, a, agg.Lift(n.keys[i], n.vals[i].val()))
+		if height > 0 {
+			a = merge(agg, a, n.children[i+1].aggregateRange(agg, lo, hi,
+				false, checkHi && i+1 == j, height-1))
+		}
+	}
+	return a
+}
+
+// reaggregate recomputes the aggregates of all nodes in the subtree, which
+// must be writable
+func (n *node) reaggregate(tr *BTree, height int) {
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			n.mutChild(tr, i).reaggregate(tr, height-1)
+		}
+	}
+	n.aggregate(tr, height)
+}
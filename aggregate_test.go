@@ -0,0 +1,61 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sumAggregator struct{}
+
+func (sumAggregator) Lift(key int64, value interface{}) interface{} {
+	return value.(int)
+}
+
+func (sumAggregator) Merge(a, b interface{}) interface{} {
+	return a.(int) + b.(int)
+}
+
+func TestAggregateRange(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Multi: multi, Aggregator: sumAggregator{}})
+		assert.Equal(t, nil, tr.AggregateRange(0, 100))
+		const N = 2000
+		for _, i := range rand.Perm(N) {
+			tr.Set(int64(i%(N/2)), i)
+		}
+		for _, i := range rand.Perm(N / 2)[:N/4] {
+			tr.Delete(int64(i))
+		}
+		for _, i := range rand.Perm(N / 2)[:N/4] {
+			tr.Set(int64(i), i)
+		}
+		if err := tr.sane(); err != nil {
+			t.Fatal(err)
+		}
+		sum := func(lo, hi int64) interface{} {
+			var total int
+			var any bool
+			tr.Ascend(lo, func(key int64, value interface{}) bool {
+				if key > hi {
+					return false
+				}
+				total += value.(int)
+				any = true
+				return true
+			})
+			if !any {
+				return nil
+			}
+			return total
+		}
+		assert.Equal(t, sum(-1, N), tr.Aggregate())
+		for i := 0; i < 1000; i++ {
+			lo := int64(rand.Intn(N/2+20) - 10)
+			hi := lo + int64(rand.Intn(N/4))
+			assert.Equal(t, sum(lo, hi), tr.AggregateRange(lo, hi))
+		}
+		assert.Equal(t, nil, tr.AggregateRange(10, 9))
+	}
+}
@@ -0,0 +1,65 @@
+package tinybtree
+
+import (
+	"sync"
+	"unsafe"
+)
+
+const (
+	cacheLine = 64
+	// headPad moves the keys of a node to the start of a cache line
+	headPad = (cacheLine - unsafe.Offsetof(node{}.keys)%cacheLine) % cacheLine
+	// tailPad rounds the size of an alignedNode up to whole cache lines
+	tailPad = (cacheLine - (headPad+unsafe.Sizeof(node{}))%cacheLine) %
+		cacheLine
+)
+
+// alignedNode pads a node so that its keys start at a cache line when the
+// alignedNode itself does, and so that the next alignedNode in a slice
+// starts at a cache line too
+type alignedNode struct {
+	_ [headPad]byte
+	n node
+	_ [tailPad]byte
+}
+
+// slabNodes is the number of nodes allocated at a time, enough for the
+// slab to be larger than 32 KiB. Go places such large objects at the start
+// of a page, so every node of the slab is aligned.
+const slabNodes = 32<<10/unsafe.Sizeof(alignedNode{}) + 1
+
+// AlignedAllocator is an Allocator that provides nodes whose keys start at
+// a 64 byte cache line, so that searching a node touches as few cache lines
+// as possible. The nodes are carved out of slabs of many nodes, and freed
+// nodes are reused. A slab is kept in memory as long as any of its nodes
+// is. It's safe for concurrent use. See Options.CacheAligned.
+type AlignedAllocator struct {
+	mu   sync.Mutex
+	slab []alignedNode
+	free []*Node
+}
+
+// NewNode returns a zeroed node
+func (a *AlignedAllocator) NewNode() *Node {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.free) > 0 {
+		n := a.free[len(a.free)-1]
+		a.free[len(a.free)-1] = nil
+		a.free = a.free[:len(a.free)-1]
+		return n
+	}
+	if len(a.slab) == 0 {
+		a.slab = make([]alignedNode, slabNodes)
+	}
+	n := &a.slab[0].n
+	a.slab = a.slab[1:]
+	return n
+}
+
+// FreeNode keeps the node for reuse
+func (a *AlignedAllocator) FreeNode(n *Node) {
+	a.mu.Lock()
+	a.free = append(a.free, n)
+	a.mu.Unlock()
+}
@@ -0,0 +1,41 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignedAllocator(t *testing.T) {
+	assert.Equal(t, uintptr(0), unsafe.Sizeof(alignedNode{})%cacheLine)
+	tr := New(&Options{CacheAligned: true})
+	for i := 0; i < 20000; i++ {
+		tr.Set(int64(rand.Intn(10000)), i)
+		if i%3 == 0 {
+			tr.Delete(int64(rand.Intn(10000)))
+		}
+	}
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+	var walk func(n *node, height int)
+	walk = func(n *node, height int) {
+		if addr := uintptr(unsafe.Pointer(&n.keys)); addr%cacheLine != 0 {
+			t.Fatalf("keys at %x are not aligned", addr)
+		}
+		if height > 0 {
+			for i := 0; i <= n.numItems; i++ {
+				walk(n.children[i], height-1)
+			}
+		}
+	}
+	walk(tr.root, tr.height)
+
+	var a AlignedAllocator
+	n := a.NewNode()
+	n.numItems = 0
+	a.FreeNode(n)
+	assert.Equal(t, n, a.NewNode())
+}
@@ -0,0 +1,48 @@
+package tinybtree
+
+// Node is a node of a BTree. Its fields are only used by the tree, it's
+// exported for implementing an Allocator.
+type Node = node
+
+// Allocator provides the nodes of a tree, such as from a sync.Pool or an
+// arena. NewNode must return a zeroed node. The tree passes each node that
+// it no longer uses to FreeNode after zeroing it, so it may be handed out
+// by NewNode again. Trees that exchange nodes through MoveRange should use
+// the same Allocator.
+type Allocator interface {
+	NewNode() *Node
+	FreeNode(n *Node)
+}
+
+func (tr *BTree) newNode() *node {
+	var n *node
+	if tr.alloc != nil {
+		n = tr.alloc.NewNode()
+	} else {
+		n = new(node)
+	}
+	n.gen = tr.generation()
+	return n
+}
+
+// freeNode passes the node to the Allocator unless it's shared with a clone
+func (tr *BTree) freeNode(n *node) {
+	if tr.alloc != nil && tr.owned(n) {
+		*n = node{}
+		tr.alloc.FreeNode(n)
+	}
+}
+
+// free passes all nodes of the subtree to freeNode
+func (n *node) free(tr *BTree, height int) {
+	if !tr.owned(n) {
+		// the children of a shared node are shared as well
+		return
+	}
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			n.children[i].free(tr, height-1)
+		}
+	}
+	tr.freeNode(n)
+}
@@ -0,0 +1,83 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trackingAllocator keeps track of the nodes that are in use
+type trackingAllocator struct {
+	live map[*Node]bool
+	free []*Node
+}
+
+func (a *trackingAllocator) NewNode() *Node {
+	var n *Node
+	if len(a.free) > 0 {
+		n = a.free[len(a.free)-1]
+		a.free = a.free[:len(a.free)-1]
+	} else {
+		n = new(Node)
+	}
+	a.live[n] = true
+	return n
+}
+
+func (a *trackingAllocator) FreeNode(n *Node) {
+	if !a.live[n] {
+		panic("node freed twice")
+	}
+	if *n != (Node{}) {
+		panic("node not zeroed")
+	}
+	delete(a.live, n)
+	a.free = append(a.free, n)
+}
+
+func (n *node) countNodes(height int) int {
+	count := 1
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			count += n.children[i].countNodes(height - 1)
+		}
+	}
+	return count
+}
+
+func (tr *BTree) countNodes() int {
+	if tr.root == nil {
+		return 0
+	}
+	return tr.root.countNodes(tr.height)
+}
+
+func TestAllocator(t *testing.T) {
+	alloc := &trackingAllocator{live: map[*Node]bool{}}
+	tr := New(&Options{Allocator: alloc})
+	other := New(&Options{Allocator: alloc})
+	for i := 0; i < 20000; i++ {
+		key := int64(rand.Intn(5000))
+		switch rand.Intn(3) {
+		case 0:
+			tr.Delete(key)
+		default:
+			tr.Set(key, i)
+		}
+		if i%1000 == 0 {
+			tr.MoveRange(other, key, key+500)
+			other.MoveRange(tr, key+200, key+1000)
+		}
+	}
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, tr.countNodes()+other.countNodes(), len(alloc.live))
+	for _, key := range rand.Perm(5000) {
+		tr.Delete(int64(key))
+	}
+	assert.Equal(t, other.countNodes(), len(alloc.live))
+	other.Clear()
+	assert.Equal(t, 0, len(alloc.live))
+}
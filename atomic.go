@@ -0,0 +1,113 @@
+package tinybtree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicTree publishes a tree to readers on other goroutines, such as a
+// tree of reference data that is reloaded from time to time. The readers
+// Load the current tree and the writer builds a replacement and stores it,
+// after which new Loads return the replacement while earlier readers keep
+// using the previous tree. The zero value holds no tree.
+type AtomicTree struct {
+	mu sync.Mutex // serializes Store and Swap
+	v  atomic.Value
+}
+
+// NewAtomicTree returns an AtomicTree holding tr, see Store
+func NewAtomicTree(tr *BTree) *AtomicTree {
+	a := new(AtomicTree)
+	a.Store(tr)
+	return a
+}
+
+// Load returns the current tree, or nil when none was stored
+func (a *AtomicTree) Load() *BTree {
+	tr, _ := a.v.Load().(*BTree)
+	return tr
+}
+
+// Store publishes tr. The tree is frozen, because it may be read from
+// multiple goroutines from now on.
+func (a *AtomicTree) Store(tr *BTree) {
+	a.Swap(tr)
+}
+
+// Publish stores a clone of tr, see Clone, and leaves tr to the writer. A
+// single writer that keeps changing tr and publishes it after every batch
+// of writes lets any number of readers run alongside it without locking.
+// Publishing takes O(1) time, the writes that follow copy the nodes they
+// touch instead of changing them in place, unless tr has a BloomFilter,
+// whose copy makes every publish O(n). Panics when tr has a Retainer,
+// since the clones would retain every value again, and nothing knows when
+// the readers are done with a clone to release them, see
+// ErrRetainerPublish.
+func (a *AtomicTree) Publish(tr *BTree) {
+	if tr.ret != nil {
+		panic(ErrRetainerPublish)
+	}
+	a.Store(tr.Clone())
+}
+
+// Swap publishes tr like Store and returns the previous tree. The previous
+// tree may still be in use by readers that loaded it earlier.
+func (a *AtomicTree) Swap(tr *BTree) (old *BTree) {
+	if tr != nil {
+		tr.Freeze()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	old = a.Load()
+	a.v.Store(tr)
+	return old
+}
+
+// SPMCTree is a tree with a single writer and any number of readers, which
+// never block. The writer calls Set, Delete and Update from one goroutine
+// at a time, and every write is published to the readers like Publish
+// does, so the readers Load a frozen tree that doesn't change under them.
+// A write copies the O(log n) nodes on its path instead of changing them in
+// place, which is all the overhead the writer pays, as long as the tree has
+// no BloomFilter, which makes every publish O(n). A Retainer isn't
+// supported, see Publish.
+type SPMCTree struct {
+	w   *BTree // the tree of the writer
+	pub AtomicTree
+}
+
+// NewSPMCTree returns an empty SPMCTree using opts. Panics when opts has a
+// Retainer.
+func NewSPMCTree(opts *Options) *SPMCTree {
+	s := &SPMCTree{w: New(opts)}
+	s.pub.Publish(s.w)
+	return s
+}
+
+// Load returns the tree as of the last write, for reading only
+func (s *SPMCTree) Load() *BTree {
+	return s.pub.Load()
+}
+
+// Set or replace a value for a key and publish the change
+func (s *SPMCTree) Set(key int64, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	prev, replaced = s.w.Set(key, value)
+	s.pub.Publish(s.w)
+	return prev, replaced
+}
+
+// Delete a value for a key and publish the change
+func (s *SPMCTree) Delete(key int64) (prev interface{}, deleted bool) {
+	prev, deleted = s.w.Delete(key)
+	s.pub.Publish(s.w)
+	return prev, deleted
+}
+
+// Update calls fn with the tree of the writer and publishes all changes
+// that fn made at once. The tree must not be used after fn returns.
+func (s *SPMCTree) Update(fn func(tr *BTree)) {
+	fn(s.w)
+	s.pub.Publish(s.w)
+}
@@ -0,0 +1,173 @@
+package tinybtree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicTree(t *testing.T) {
+	var a AtomicTree
+	if a.Load() != nil {
+		t.Fatal("expected nil")
+	}
+	load := func(version int) *BTree {
+		tr := new(BTree)
+		for i := 0; i < 1000; i++ {
+			tr.Set(int64(i), version)
+		}
+		return tr
+	}
+	first := load(0)
+	a.Store(first)
+	if !first.Frozen() {
+		t.Fatal("expected a frozen tree")
+	}
+
+	// readers always see a complete tree of a single version
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tr := a.Load()
+				v, _ := tr.Get(0)
+				tr.Scan(func(key int64, value interface{}) bool {
+					if value != v {
+						t.Errorf("expected %v, got %v", v, value)
+						return false
+					}
+					return true
+				})
+			}
+		}()
+	}
+	for version := 1; version <= 10; version++ {
+		old := a.Swap(load(version))
+		v, _ := old.Get(0)
+		assert.Equal(t, version-1, v)
+	}
+	wg.Wait()
+	assert.Equal(t, first, NewAtomicTree(first).Load())
+	a.Swap(nil)
+	if a.Load() != nil {
+		t.Fatal("expected nil")
+	}
+}
+
+func TestAtomicTreePublish(t *testing.T) {
+	tr := new(BTree)
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i), 0)
+	}
+	var a AtomicTree
+	a.Publish(tr)
+	if tr.Frozen() {
+		t.Fatal("expected the tree to stay writable")
+	}
+
+	// the writer keeps writing to tr while readers scan what it published
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				snap := a.Load()
+				v, _ := snap.Get(0)
+				n := 0
+				snap.Scan(func(key int64, value interface{}) bool {
+					n++
+					if value != v {
+						t.Errorf("expected %v, got %v", v, value)
+						return false
+					}
+					return true
+				})
+				assert.Equal(t, 1000, n)
+			}
+		}()
+	}
+	for version := 1; version <= 50; version++ {
+		for i := 0; i < 1000; i++ {
+			tr.Set(int64(i), version)
+		}
+		a.Publish(tr)
+	}
+	wg.Wait()
+	v, _ := a.Load().Get(999)
+	assert.Equal(t, 50, v)
+}
+
+func TestSPMCTree(t *testing.T) {
+	s := NewSPMCTree(nil)
+	assert.Equal(t, 0, s.Load().Len())
+	if !s.Load().Frozen() {
+		t.Fatal("expected a frozen tree")
+	}
+
+	// the writer keeps every tree a sequence 0..n-1 of keys with values
+	// that sum up to n, so readers can check what they see
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				tr := s.Load()
+				n, sum := 0, 0
+				tr.Scan(func(key int64, value interface{}) bool {
+					if key != int64(n) {
+						t.Errorf("expected key %d, got %d", n, key)
+						return false
+					}
+					n++
+					sum += value.(int)
+					return true
+				})
+				if sum != tr.Len() || n != tr.Len() {
+					t.Errorf("torn tree of %d items with sum %d", n, sum)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 2000; i++ {
+		n := int64(s.Load().Len())
+		if i%10 == 9 {
+			// move a value from one key to another within a batch
+			s.Update(func(tr *BTree) {
+				tr.Set(0, 0)
+				tr.Set(n, 2)
+			})
+			s.Update(func(tr *BTree) {
+				tr.Set(0, 1)
+				tr.Set(n, 1)
+			})
+			continue
+		}
+		s.Set(n, 1)
+	}
+	prev, deleted := s.Delete(1999)
+	assert.Equal(t, 1, prev)
+	assert.Equal(t, true, deleted)
+	close(done)
+	wg.Wait()
+	assert.Equal(t, 1999, s.Load().Len())
+}
+
+func TestPublishRetainer(t *testing.T) {
+	defer func() {
+		assert.Equal(t, ErrRetainerPublish, recover())
+	}()
+	NewSPMCTree(&Options{Retainer: refCounter{}})
+	t.Fatal("expected a panic")
+}
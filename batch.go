@@ -0,0 +1,82 @@
+package tinybtree
+
+import "sort"
+
+// WriteBatch buffers changes to a tree and applies them together in key
+// order. Writes to the same key are coalesced, so only the last change of
+// each key reaches the tree.
+type WriteBatch struct {
+	tr  *BTree
+	ops []batchOp
+}
+
+type batchOp struct {
+	key   int64
+	value interface{}
+	del   bool
+}
+
+// NewWriteBatch returns an empty batch for the tree
+func (tr *BTree) NewWriteBatch() *WriteBatch {
+	return &WriteBatch{tr: tr}
+}
+
+// Set buffers setting a value for a key
+func (b *WriteBatch) Set(key int64, value interface{}) {
+	b.ops = append(b.ops, batchOp{key: key, value: value})
+}
+
+// Delete buffers deleting a key
+func (b *WriteBatch) Delete(key int64) {
+	b.ops = append(b.ops, batchOp{key: key, del: true})
+}
+
+// Len returns the number of buffered changes
+func (b *WriteBatch) Len() int {
+	return len(b.ops)
+}
+
+// Reset drops the buffered changes
+func (b *WriteBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Commit applies the buffered changes in key order and resets the batch. It
+// returns the keys, in order, whose existing values were replaced by a Set.
+// In multi mode all values that were set for a key since its last Delete
+// are added, in the order they were set.
+func (b *WriteBatch) Commit() (replaced []int64) {
+	sort.SliceStable(b.ops, func(i, j int) bool {
+		return b.ops[i].key < b.ops[j].key
+	})
+	for i := 0; i < len(b.ops); {
+		j := i + 1
+		for j < len(b.ops) && b.ops[j].key == b.ops[i].key {
+			j++
+		}
+		// only the ops after the last delete of the key matter
+		start := i
+		for k := j - 1; k >= i; k-- {
+			if b.ops[k].del {
+				start = k
+				break
+			}
+		}
+		if !b.tr.multi {
+			start = j - 1
+		}
+		for _, op := range b.ops[start:j] {
+			if op.del {
+				b.tr.Delete(op.key)
+			} else if _, ok := b.tr.Set(op.key, op.value); ok {
+				replaced = append(replaced, op.key)
+			}
+		}
+		i = j
+	}
+	for i := range b.ops {
+		b.ops[i] = batchOp{}
+	}
+	b.ops = b.ops[:0]
+	return replaced
+}
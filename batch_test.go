@@ -0,0 +1,57 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBatch(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 10; i++ {
+		tr.Set(int64(i), i)
+	}
+	var n int
+	tr.Subscribe(func(e Event) {
+		n++
+	})
+	b := tr.NewWriteBatch()
+	b.Set(20, "a")
+	b.Set(5, "b")
+	b.Set(20, "c")
+	b.Delete(3)
+	b.Set(3, "d")
+	b.Delete(7)
+	b.Set(1, "e")
+	b.Delete(1)
+	b.Delete(30)
+	assert.Equal(t, 9, b.Len())
+	assert.Equal(t, []int64{3, 5}, b.Commit())
+	assert.Equal(t, 0, b.Len())
+	// one change for each of the keys 1, 3, 5, 7 and 20
+	assert.Equal(t, 5, n)
+
+	for key, want := range map[int64]interface{}{
+		1: nil, 3: "d", 5: "b", 7: nil, 20: "c", 30: nil, 9: 9,
+	} {
+		value, _ := tr.Get(key)
+		assert.Equal(t, want, value)
+	}
+	assert.Equal(t, 9, tr.Len())
+	assert.Equal(t, []int64(nil), b.Commit())
+}
+
+func TestWriteBatchMulti(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	tr.Set(1, "x")
+	tr.Set(2, "y")
+	b := tr.NewWriteBatch()
+	b.Set(1, "a")
+	b.Set(2, "b")
+	b.Delete(2)
+	b.Set(2, "c")
+	b.Set(2, "d")
+	assert.Equal(t, []int64(nil), b.Commit())
+	assert.Equal(t, []interface{}{"x", "a"}, tr.GetAll(1))
+	assert.Equal(t, []interface{}{"c", "d"}, tr.GetAll(2))
+}
@@ -0,0 +1,64 @@
+package tinybtree
+
+import "math"
+
+// Bitmap is a set of uint64 values, such as a *roaring64.Bitmap from
+// github.com/RoaringBitmap/roaring, which this package doesn't depend on.
+// Keys are converted to and from the values with uint64(key) and
+// int64(value), so negative keys map to the upper half of the values.
+type Bitmap interface {
+	Add(x uint64)
+	Contains(x uint64) bool
+}
+
+// KeysAsBitmap adds the keys of the tree to bm and returns it. The keys are
+// added in order, which is the fastest way to fill a roaring bitmap.
+func (tr *BTree) KeysAsBitmap(bm Bitmap) Bitmap {
+	if tr.root != nil {
+		tr.root.each(func(key int64, value interface{}) {
+			bm.Add(uint64(key))
+		}, tr.height)
+	}
+	return bm
+}
+
+// IntersectBitmap visits the items in key order whose keys are in bm
+func (tr *BTree) IntersectBitmap(
+	bm Bitmap,
+	iter func(key int64, value interface{}) bool,
+) {
+	tr.Scan(func(key int64, value interface{}) bool {
+		if !bm.Contains(uint64(key)) {
+			return true
+		}
+		return iter(key, value)
+	})
+}
+
+// ExportKeyRanges returns the keys of the tree as the ranges of
+// consecutive keys, in order, for sending the membership of a tree to a
+// peer that doesn't need the values. Each range is found with FirstGap,
+// so dense runs of keys take O(log n) time no matter how long they are.
+// Duplicate keys are listed once.
+func (tr *BTree) ExportKeyRanges() []KeyRange {
+	if tr.root == nil {
+		return nil
+	}
+	var ranges []KeyRange
+	lo := tr.min
+	for {
+		gap, ok := tr.FirstGap(lo)
+		if !ok {
+			return append(ranges, KeyRange{lo, math.MaxInt64})
+		}
+		ranges = append(ranges, KeyRange{lo, gap - 1})
+		found := false
+		tr.Ascend(gap, func(key int64, _ interface{}) bool {
+			lo, found = key, true
+			return false
+		})
+		if !found {
+			return ranges
+		}
+	}
+}
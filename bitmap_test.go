@@ -0,0 +1,64 @@
+package tinybtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapBitmap is a Bitmap for testing
+type mapBitmap map[uint64]bool
+
+func (bm mapBitmap) Add(x uint64)           { bm[x] = true }
+func (bm mapBitmap) Contains(x uint64) bool { return bm[x] }
+
+func TestBitmap(t *testing.T) {
+	var tr BTree
+	keys := []int64{math.MinInt64, -1, 0, 1, 2, 3, 100, math.MaxInt64}
+	for _, key := range keys {
+		tr.Set(key, key)
+	}
+	bm := tr.KeysAsBitmap(mapBitmap{}).(mapBitmap)
+	assert.Equal(t, len(keys), len(bm))
+	for _, key := range keys {
+		assert.Equal(t, true, bm.Contains(uint64(key)))
+	}
+
+	filter := mapBitmap{}
+	for _, key := range []int64{-1, 2, 50, 100, math.MaxInt64} {
+		filter.Add(uint64(key))
+	}
+	var got []int64
+	tr.IntersectBitmap(filter, func(key int64, value interface{}) bool {
+		assert.Equal(t, key, value)
+		got = append(got, key)
+		return len(got) < 3
+	})
+	assert.Equal(t, []int64{-1, 2, 100}, got)
+}
+
+func TestExportKeyRanges(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, 0, len(tr.ExportKeyRanges()))
+	for i := int64(0); i < 1000; i++ {
+		if i%100 < 90 {
+			tr.Set(i, nil)
+		}
+	}
+	tr.Set(-5, nil)
+	tr.Set(math.MaxInt64, nil)
+	tr.Set(math.MaxInt64-1, nil)
+	ranges := tr.ExportKeyRanges()
+	assert.Equal(t, 12, len(ranges))
+	assert.Equal(t, KeyRange{-5, -5}, ranges[0])
+	assert.Equal(t, KeyRange{0, 89}, ranges[1])
+	assert.Equal(t, KeyRange{900, 989}, ranges[10])
+	assert.Equal(t, KeyRange{math.MaxInt64 - 1, math.MaxInt64}, ranges[11])
+
+	multi := New(&Options{Multi: true})
+	for _, key := range []int64{1, 1, 2, 4, 4} {
+		multi.Set(key, nil)
+	}
+	assert.Equal(t, []KeyRange{{1, 2}, {4, 4}}, multi.ExportKeyRanges())
+}
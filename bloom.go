@@ -0,0 +1,91 @@
+package tinybtree
+
+// bloom is a counting bloom filter of the keys of a tree. A counter that
+// overflows sticks at its maximum, so keys are never lost from the filter,
+// at the cost of some false positives.
+type bloom struct {
+	counts []uint8 // the length is a power of two
+	max    int     // number of keys the filter is sized for
+}
+
+const (
+	bloomHashes  = 4
+	bloomPerKey  = 10 // counters per key, about 1% false positives
+	bloomMinKeys = 1024
+)
+
+// newBloom returns a filter sized for n keys
+func newBloom(n int) *bloom {
+	if n < bloomMinKeys {
+		n = bloomMinKeys
+	}
+	size := 1
+	for size < n*bloomPerKey {
+		size <<= 1
+	}
+	return &bloom{counts: make([]uint8, size), max: n}
+}
+
+// bloomHash returns the two hashes from which the positions of key are
+// derived
+func bloomHash(key int64) (h1, h2 uint64) {
+	// splitmix64
+	h := uint64(key) + 0x9e3779b97f4a7c15
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	h ^= h >> 31
+	return h, h>>32 | 1
+}
+
+func (b *bloom) add(key int64) {
+	h1, h2 := bloomHash(key)
+	mask := uint64(len(b.counts) - 1)
+	for i := uint64(0); i < bloomHashes; i++ {
+		if c := &b.counts[(h1+i*h2)&mask]; *c < 255 {
+			*c++
+		}
+	}
+}
+
+func (b *bloom) remove(key int64) {
+	h1, h2 := bloomHash(key)
+	mask := uint64(len(b.counts) - 1)
+	for i := uint64(0); i < bloomHashes; i++ {
+		if c := &b.counts[(h1+i*h2)&mask]; *c > 0 && *c < 255 {
+			*c--
+		}
+	}
+}
+
+// has returns false when key is definitely not in the tree
+func (b *bloom) has(key int64) bool {
+	h1, h2 := bloomHash(key)
+	mask := uint64(len(b.counts) - 1)
+	for i := uint64(0); i < bloomHashes; i++ {
+		if b.counts[(h1+i*h2)&mask] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomAdd adds key to the filter of the tree after it was inserted
+func (tr *BTree) bloomAdd(key int64) {
+	if tr.length <= tr.bloom.max {
+		tr.bloom.add(key)
+		return
+	}
+	tr.rebuildBloom()
+}
+
+// rebuildBloom replaces the filter of the tree with one that is sized for
+// twice its number of keys, which keeps the cost of growing the filter
+// constant per insert
+func (tr *BTree) rebuildBloom() {
+	tr.bloom = newBloom(tr.length * 2)
+	if tr.root != nil {
+		tr.root.each(func(key int64, value interface{}) {
+			tr.bloom.add(key)
+		}, tr.height)
+	}
+}
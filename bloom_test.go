@@ -0,0 +1,87 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{BloomFilter: true, Multi: multi})
+		m := make(map[int64]int)
+		for i := 0; i < 100000; i++ {
+			key := int64(rand.Intn(20000))
+			switch rand.Intn(3) {
+			case 0, 1:
+				tr.Set(key, key)
+				m[key]++
+				if !multi {
+					m[key] = 1
+				}
+			case 2:
+				tr.Delete(key)
+				delete(m, key)
+			}
+			if i%1000 == 0 {
+				for key := int64(0); key < 20000; key += 7 {
+					_, ok := tr.Get(key)
+					assert.Equal(t, m[key] > 0, ok)
+				}
+			}
+		}
+
+		// move half of the keys to another tree and back
+		other := New(&Options{BloomFilter: true, Multi: multi})
+		tr.MoveRange(other, 0, 9999)
+		for key := int64(0); key < 20000; key++ {
+			_, ok := tr.Get(key)
+			assert.Equal(t, key >= 10000 && m[key] > 0, ok)
+			_, ok = other.Get(key)
+			assert.Equal(t, key < 10000 && m[key] > 0, ok)
+		}
+		other.MoveRange(tr, 0, 9999)
+		for key := int64(0); key < 20000; key++ {
+			_, ok := tr.Get(key)
+			assert.Equal(t, m[key] > 0, ok)
+		}
+
+		tr.Clear()
+		for key := int64(0); key < 20000; key++ {
+			if _, ok := tr.Get(key); ok {
+				t.Fatal("expected false")
+			}
+		}
+	}
+}
+
+func TestBloomFilterFalsePositives(t *testing.T) {
+	const N = 100000
+	tr := New(&Options{BloomFilter: true})
+	for i := 0; i < N; i++ {
+		tr.Set(int64(i*2), nil)
+	}
+	var positives int
+	for i := 0; i < N; i++ {
+		if tr.bloom.has(int64(i*2 + 1)) {
+			positives++
+		}
+	}
+	if positives > N/20 {
+		t.Fatalf("expected at most %d false positives, got %d", N/20,
+			positives)
+	}
+}
+
+func BenchmarkBTreeGetMissBloom(b *testing.B) {
+	tr := New(&Options{BloomFilter: true})
+	for i := 0; i < 1000000; i++ {
+		tr.Set(int64(i*2), i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr.Get(int64((n%1000000)*2 + 1))
+	}
+}
@@ -0,0 +1,61 @@
+package tinybtree
+
+import "math"
+
+// Bounds is a range of keys for Iterate. Use math.MinInt64 and
+// math.MaxInt64 with the inclusive flags set for a range that is open on
+// that side.
+type Bounds struct {
+	Lo, Hi      int64
+	LoInclusive bool // Lo itself is within the range
+	HiInclusive bool // Hi itself is within the range
+	Reverse     bool // iterate from Hi down to Lo
+}
+
+// All returns the bounds of all keys
+func All() Bounds {
+	return Bounds{
+		Lo: math.MinInt64, Hi: math.MaxInt64,
+		LoInclusive: true, HiInclusive: true,
+	}
+}
+
+// inclusive returns the range as [lo, hi], or false when it's empty
+func (b Bounds) inclusive() (lo, hi int64, ok bool) {
+	lo, hi = b.Lo, b.Hi
+	if !b.LoInclusive {
+		if lo == math.MaxInt64 {
+			return 0, 0, false
+		}
+		lo++
+	}
+	if !b.HiInclusive {
+		if hi == math.MinInt64 {
+			return 0, 0, false
+		}
+		hi--
+	}
+	return lo, hi, lo <= hi
+}
+
+// Iterate visits the items within the bounds in key order, or in reverse
+// key order when b.Reverse is set. Like Ascend and Descend, the iter
+// function may modify the tree.
+func (tr *BTree) Iterate(
+	b Bounds,
+	iter func(key int64, value interface{}) bool,
+) {
+	lo, hi, ok := b.inclusive()
+	if !ok {
+		return
+	}
+	if b.Reverse {
+		tr.Descend(hi, func(key int64, value interface{}) bool {
+			return key >= lo && iter(key, value)
+		})
+		return
+	}
+	tr.Ascend(lo, func(key int64, value interface{}) bool {
+		return key <= hi && iter(key, value)
+	})
+}
@@ -0,0 +1,53 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterate(t *testing.T) {
+	var tr BTree
+	for _, i := range rand.Perm(100) {
+		tr.Set(int64(i*2), i)
+	}
+	keys := func(b Bounds) []int64 {
+		var keys []int64
+		tr.Iterate(b, func(key int64, value interface{}) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return keys
+	}
+	assert.Equal(t, []int64{10, 12, 14}, keys(Bounds{
+		Lo: 10, Hi: 14, LoInclusive: true, HiInclusive: true}))
+	assert.Equal(t, []int64{12}, keys(Bounds{Lo: 10, Hi: 14}))
+	assert.Equal(t, []int64{12, 14}, keys(Bounds{Lo: 10, Hi: 14,
+		HiInclusive: true}))
+	assert.Equal(t, []int64{14, 12, 10}, keys(Bounds{Lo: 10, Hi: 14,
+		LoInclusive: true, HiInclusive: true, Reverse: true}))
+	assert.Equal(t, []int64{12, 10}, keys(Bounds{Lo: 10, Hi: 14,
+		LoInclusive: true, Reverse: true}))
+	assert.Equal(t, []int64{10, 12}, keys(Bounds{Lo: 9, Hi: 13}))
+	assert.Equal(t, []int64(nil), keys(Bounds{Lo: 10, Hi: 10}))
+	assert.Equal(t, []int64(nil), keys(Bounds{Lo: 14, Hi: 10,
+		LoInclusive: true, HiInclusive: true}))
+	assert.Equal(t, []int64(nil), keys(Bounds{Lo: math.MaxInt64,
+		Hi: math.MaxInt64, HiInclusive: true}))
+	assert.Equal(t, []int64(nil), keys(Bounds{Lo: math.MinInt64,
+		Hi: math.MinInt64, LoInclusive: true}))
+	assert.Equal(t, 100, len(keys(All())))
+	all := All()
+	all.Reverse = true
+	assert.Equal(t, int64(198), keys(all)[0])
+
+	// stop early
+	var n int
+	tr.Iterate(All(), func(key int64, value interface{}) bool {
+		n++
+		return n < 3
+	})
+	assert.Equal(t, 3, n)
+}
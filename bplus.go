@@ -0,0 +1,388 @@
+package tinybtree
+
+// BPlusTree is an ordered set of key/value pairs where the key is an int64
+// and the value is an interface{}. Unlike BTree all items are stored in the
+// leaves, which are linked to each other, so scans walk from leaf to leaf
+// without going back through the branches.
+type BPlusTree struct {
+	height int
+	root   *bpNode
+	length int
+	mods   uint64 // incremented when items are inserted or removed
+}
+
+type bpNode struct {
+	numItems int
+	// leaves hold the items, branches hold only the keys which separate
+	// their children. All keys in children[i] are less than items[i].key
+	// and all keys in children[i+1] are greater or equal to it.
+	items      [maxItems]item
+	children   [maxItems + 1]*bpNode
+	prev, next *bpNode // leaves only
+}
+
+// leaf returns the leaf where key belongs
+func (tr *BPlusTree) leaf(key int64) *bpNode {
+	n := tr.root
+	for h := tr.height; h > 0; h-- {
+		n = n.children[n.upper(key)]
+	}
+	return n
+}
+
+// first returns the leftmost leaf
+func (tr *BPlusTree) first() *bpNode {
+	n := tr.root
+	for h := tr.height; h > 0; h-- {
+		n = n.children[0]
+	}
+	return n
+}
+
+// last returns the rightmost leaf
+func (tr *BPlusTree) last() *bpNode {
+	n := tr.root
+	for h := tr.height; h > 0; h-- {
+		n = n.children[n.numItems]
+	}
+	return n
+}
+
+// Len returns the number of items in the tree
+func (tr *BPlusTree) Len() int {
+	return tr.length
+}
+
+// Set or replace a value for a key
+func (tr *BPlusTree) Set(key int64, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	if tr.root == nil {
+		tr.root = new(bpNode)
+		tr.root.items[0] = item{key: key, slot: slot{value: value}}
+		tr.root.numItems = 1
+		tr.length = 1
+		tr.mods++
+		return
+	}
+	prev, replaced = tr.root.set(key, value, tr.height)
+	if replaced {
+		return
+	}
+	if tr.root.numItems == maxItems {
+		n := tr.root
+		right, sep := n.split(tr.height)
+		tr.root = new(bpNode)
+		tr.root.children[0] = n
+		tr.root.items[0] = item{key: sep}
+		tr.root.children[1] = right
+		tr.root.numItems = 1
+		tr.height++
+	}
+	tr.length++
+	tr.mods++
+	return
+}
+
+func (n *bpNode) split(height int) (right *bpNode, sep int64) {
+	right = new(bpNode)
+	if height == 0 {
+		// the right leaf takes the upper half, its first key separates the
+		// two leaves
+		copy(right.items[:], n.items[maxItems/2:])
+		right.numItems = maxItems - maxItems/2
+		for i := maxItems / 2; i < maxItems; i++ {
+			n.items[i] = item{}
+		}
+		n.numItems = maxItems / 2
+		right.prev = n
+		right.next = n.next
+		if n.next != nil {
+			n.next.prev = right
+		}
+		n.next = right
+		return right, right.items[0].key
+	}
+	sep = n.items[maxItems/2].key
+	copy(right.items[:], n.items[maxItems/2+1:])
+	copy(right.children[:], n.children[maxItems/2+1:])
+	right.numItems = maxItems - maxItems/2 - 1
+	for i := maxItems / 2; i < maxItems; i++ {
+		n.items[i] = item{}
+	}
+	for i := maxItems/2 + 1; i < maxItems+1; i++ {
+		n.children[i] = nil
+	}
+	n.numItems = maxItems / 2
+	return right, sep
+}
+
+func (n *bpNode) set(key int64, value interface{}, height int) (
+	prev interface{}, replaced bool,
+) {
+	i := n.upper(key)
+	if height == 0 {
+		if i > 0 && n.items[i-1].key == key {
+			prev = n.items[i-1].value
+			n.items[i-1].value = value
+			return prev, true
+		}
+		copy(n.items[i+1:n.numItems+1], n.items[i:n.numItems])
+		n.items[i] = item{key: key, slot: slot{value: value}}
+		n.numItems++
+		return nil, false
+	}
+	prev, replaced = n.children[i].set(key, value, height-1)
+	if replaced {
+		return
+	}
+	if n.children[i].numItems == maxItems {
+		right, sep := n.children[i].split(height - 1)
+		copy(n.children[i+2:n.numItems+2], n.children[i+1:n.numItems+1])
+		copy(n.items[i+1:n.numItems+1], n.items[i:n.numItems])
+		n.items[i] = item{key: sep}
+		n.children[i+1] = right
+		n.numItems++
+	}
+	return
+}
+
+// Get a value for key
+func (tr *BPlusTree) Get(key int64) (value interface{}, gotten bool) {
+	if tr.root == nil {
+		return
+	}
+	n := tr.leaf(key)
+	i := n.upper(key)
+	if i > 0 && n.items[i-1].key == key {
+		return n.items[i-1].value, true
+	}
+	return nil, false
+}
+
+// Delete a value for a key
+func (tr *BPlusTree) Delete(key int64) (prev interface{}, deleted bool) {
+	if tr.root == nil {
+		return
+	}
+	prev, deleted = tr.root.delete(key, tr.height)
+	if !deleted {
+		return
+	}
+	if tr.height > 0 && tr.root.numItems == 0 {
+		tr.root = tr.root.children[0]
+		tr.height--
+	}
+	tr.length--
+	tr.mods++
+	if tr.length == 0 {
+		tr.root = nil
+		tr.height = 0
+	}
+	return
+}
+
+func (n *bpNode) delete(key int64, height int) (
+	prev interface{}, deleted bool,
+) {
+	i := n.upper(key)
+	if height == 0 {
+		if i == 0 || n.items[i-1].key != key {
+			return nil, false
+		}
+		prev = n.items[i-1].value
+		copy(n.items[i-1:], n.items[i:n.numItems])
+		n.items[n.numItems-1] = item{}
+		n.numItems--
+		return prev, true
+	}
+	prev, deleted = n.children[i].delete(key, height-1)
+	if deleted && n.children[i].numItems < minItems {
+		n.rebalance(i, height)
+	}
+	return
+}
+
+// rebalance fixes the child at index i when it has too few items
+func (n *bpNode) rebalance(i, height int) {
+	if i == n.numItems {
+		i--
+	}
+	left, right := n.children[i], n.children[i+1]
+	if height == 1 {
+		// the children are leaves, the separator is not an item
+		if left.numItems+right.numItems < maxItems {
+			// merge left + right
+			copy(left.items[left.numItems:], right.items[:right.numItems])
+			left.numItems += right.numItems
+			left.next = right.next
+			if right.next != nil {
+				right.next.prev = left
+			}
+			n.remove(i)
+		} else if left.numItems > right.numItems {
+			// move left -> right
+			copy(right.items[1:right.numItems+1], right.items[:right.numItems])
+			right.items[0] = left.items[left.numItems-1]
+			right.numItems++
+			left.items[left.numItems-1] = item{}
+			left.numItems--
+			n.items[i].key = right.items[0].key
+		} else {
+			// move right -> left
+			left.items[left.numItems] = right.items[0]
+			left.numItems++
+			copy(right.items[:], right.items[1:right.numItems])
+			right.items[right.numItems-1] = item{}
+			right.numItems--
+			n.items[i].key = right.items[0].key
+		}
+		return
+	}
+	if left.numItems+right.numItems+1 < maxItems {
+		// merge left + separator + right
+		left.items[left.numItems] = n.items[i]
+		copy(left.items[left.numItems+1:], right.items[:right.numItems])
+		copy(left.children[left.numItems+1:],
+			right.children[:right.numItems+1])
+		left.numItems += right.numItems + 1
+		n.remove(i)
+	} else if left.numItems > right.numItems {
+		// move left -> right
+		copy(right.items[1:right.numItems+1], right.items[:right.numItems])
+		copy(right.children[1:right.numItems+2],
+			right.children[:right.numItems+1])
+		right.items[0] = n.items[i]
+		right.children[0] = left.children[left.numItems]
+		right.numItems++
+		n.items[i] = left.items[left.numItems-1]
+		left.items[left.numItems-1] = item{}
+		left.children[left.numItems] = nil
+		left.numItems--
+	} else {
+		// move right -> left
+		left.items[left.numItems] = n.items[i]
+		left.children[left.numItems+1] = right.children[0]
+		left.numItems++
+		n.items[i] = right.items[0]
+		copy(right.items[:], right.items[1:right.numItems])
+		copy(right.children[:], right.children[1:right.numItems+1])
+		right.items[right.numItems-1] = item{}
+		right.children[right.numItems] = nil
+		right.numItems--
+	}
+}
+
+// remove drops the separator at index i and the child right of it
+func (n *bpNode) remove(i int) {
+	copy(n.items[i:], n.items[i+1:n.numItems])
+	copy(n.children[i+1:], n.children[i+2:n.numItems+1])
+	n.items[n.numItems-1] = item{}
+	n.children[n.numItems] = nil
+	n.numItems--
+}
+
+// Scan all items in tree. The iter function may modify the tree, in which
+// case the scan continues after the last visited key.
+func (tr *BPlusTree) Scan(iter func(key int64, value interface{}) bool) {
+	it := iterState{cur: &tr.mods, iter: iter, mods: tr.mods}
+	defer it.catch()
+	if tr.root != nil && !tr.first().ascend(0, &it) && it.stale {
+		tr.ascend(it.resume(), &it)
+	}
+}
+
+// Ascend the tree within the range [pivot, last]
+func (tr *BPlusTree) Ascend(
+	pivot int64,
+	iter func(key int64, value interface{}) bool,
+) {
+	tr.ascend(pivot, &iterState{cur: &tr.mods, iter: iter})
+}
+
+func (tr *BPlusTree) ascend(pivot int64, it *iterState) {
+	defer it.catch()
+	for tr.root != nil {
+		it.mods = tr.mods
+		n := tr.leaf(pivot)
+		if n.ascend(n.lower(pivot), it) || !it.stale {
+			return
+		}
+		pivot = it.resume()
+	}
+}
+
+// ascend visits the items of the leaf starting at index i and then the
+// items of the following leaves
+func (n *bpNode) ascend(i int, it *iterState) bool {
+	for ; n != nil; n, i = n.next, 0 {
+		it.prefetch(n.next)
+		for ; i < n.numItems; i++ {
+			if !it.visit(n.items[i].key, n.items[i].value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Reverse all items in tree. The iter function may modify the tree, in which
+// case the scan continues before the last visited key.
+func (tr *BPlusTree) Reverse(iter func(key int64, value interface{}) bool) {
+	it := iterState{cur: &tr.mods, iter: iter, mods: tr.mods}
+	defer it.catch()
+	if tr.root != nil {
+		n := tr.last()
+		if !n.descend(n.numItems-1, &it) && it.stale {
+			tr.descend(it.resume(), &it)
+		}
+	}
+}
+
+// Descend the tree within the range [pivot, first]
+func (tr *BPlusTree) Descend(
+	pivot int64,
+	iter func(key int64, value interface{}) bool,
+) {
+	tr.descend(pivot, &iterState{cur: &tr.mods, iter: iter})
+}
+
+func (tr *BPlusTree) descend(pivot int64, it *iterState) {
+	defer it.catch()
+	for tr.root != nil {
+		it.mods = tr.mods
+		n := tr.leaf(pivot)
+		if n.descend(n.upper(pivot)-1, it) || !it.stale {
+			return
+		}
+		pivot = it.resume()
+	}
+}
+
+// descend visits the items of the leaf starting at index i going down and
+// then the items of the preceding leaves
+func (n *bpNode) descend(i int, it *iterState) bool {
+	for n != nil {
+		it.prefetch(n.prev)
+		for ; i >= 0; i-- {
+			if !it.visit(n.items[i].key, n.items[i].value) {
+				return false
+			}
+		}
+		n = n.prev
+		if n != nil {
+			i = n.numItems - 1
+		}
+	}
+	return true
+}
+
+// prefetch touches the first cache line of the leaf that is visited next,
+// so that its cache miss overlaps with visiting the current leaf. Go has no
+// prefetch instruction, a load whose result is kept in the iterState does
+// the same for the CPU.
+func (it *iterState) prefetch(n *bpNode) {
+	if n != nil {
+		it.touched += n.numItems
+	}
+}
@@ -0,0 +1,275 @@
+package tinybtree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sane checks the ordering of keys, the fill of nodes and the leaf links
+func (tr *BPlusTree) sane() error {
+	if tr.root == nil {
+		if tr.length != 0 || tr.height != 0 {
+			return fmt.Errorf("empty tree with length %d and height %d",
+				tr.length, tr.height)
+		}
+		return nil
+	}
+	var leaves []*bpNode
+	count, err := tr.root.sane(tr.height, true, &leaves)
+	if err != nil {
+		return err
+	}
+	if count != tr.length {
+		return fmt.Errorf("expected length %d, got %d", count, tr.length)
+	}
+	for i, n := range leaves {
+		if (i == 0 && n.prev != nil) || (i > 0 && n.prev != leaves[i-1]) {
+			return fmt.Errorf("bad prev link at leaf %d", i)
+		}
+		if (i == len(leaves)-1 && n.next != nil) ||
+			(i < len(leaves)-1 && n.next != leaves[i+1]) {
+			return fmt.Errorf("bad next link at leaf %d", i)
+		}
+	}
+	var last int64
+	var i int
+	tr.Scan(func(key int64, value interface{}) bool {
+		if i > 0 && key <= last {
+			err = fmt.Errorf("out of order %d after %d", key, last)
+			return false
+		}
+		last = key
+		i++
+		return true
+	})
+	return err
+}
+
+func (n *bpNode) sane(height int, root bool, leaves *[]*bpNode) (
+	count int, err error,
+) {
+	if n.numItems >= maxItems || (!root && n.numItems < minItems) ||
+		(height > 0 && n.numItems == 0) {
+		return 0, fmt.Errorf("node has %d items", n.numItems)
+	}
+	if height == 0 {
+		*leaves = append(*leaves, n)
+		return n.numItems, nil
+	}
+	for i := 0; i <= n.numItems; i++ {
+		c := n.children[i]
+		if i > 0 && c.min(height-1) < n.items[i-1].key {
+			return 0, fmt.Errorf("key below separator %d", n.items[i-1].key)
+		}
+		if i < n.numItems && c.max(height-1) >= n.items[i].key {
+			return 0, fmt.Errorf("key above separator %d", n.items[i].key)
+		}
+		cnt, err := c.sane(height-1, false, leaves)
+		if err != nil {
+			return 0, err
+		}
+		count += cnt
+	}
+	return count, nil
+}
+
+func (n *bpNode) min(height int) int64 {
+	for ; height > 0; height-- {
+		n = n.children[0]
+	}
+	return n.items[0].key
+}
+
+func (n *bpNode) max(height int) int64 {
+	for ; height > 0; height-- {
+		n = n.children[n.numItems]
+	}
+	return n.items[n.numItems-1].key
+}
+
+func TestBPlusTree(t *testing.T) {
+	var tr BPlusTree
+	const N = 10000
+	keys := rand.Perm(N)
+	for _, key := range keys {
+		prev, replaced := tr.Set(int64(key), key)
+		if replaced || prev != nil {
+			t.Fatal("expected nil")
+		}
+	}
+	assert.Equal(t, N, tr.Len())
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		value, ok := tr.Get(int64(key))
+		if !ok || value != key {
+			t.Fatalf("expected '%v', got '%v'", key, value)
+		}
+		prev, replaced := tr.Set(int64(key), key)
+		if !replaced || prev != key {
+			t.Fatalf("expected '%v', got '%v'", key, prev)
+		}
+	}
+	if _, ok := tr.Get(N); ok {
+		t.Fatal("expected false")
+	}
+
+	// delete half of the items
+	for _, key := range keys[:N/2] {
+		prev, deleted := tr.Delete(int64(key))
+		if !deleted || prev != key {
+			t.Fatalf("expected '%v', got '%v'", key, prev)
+		}
+		if _, deleted := tr.Delete(int64(key)); deleted {
+			t.Fatal("expected false")
+		}
+	}
+	assert.Equal(t, N/2, tr.Len())
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+
+	rest := append([]int(nil), keys[N/2:]...)
+	sort.Ints(rest)
+	var all []int
+	tr.Scan(func(key int64, value interface{}) bool {
+		all = append(all, int(key))
+		return true
+	})
+	assert.Equal(t, rest, all)
+
+	// ascend and descend from every possible pivot
+	for i := -1; i <= N; i += 7 {
+		j := sort.SearchInts(rest, i)
+		var count int
+		tr.Ascend(int64(i), func(key int64, value interface{}) bool {
+			if int(key) != rest[j+count] {
+				t.Fatalf("expected %d, got %d", rest[j+count], key)
+			}
+			count++
+			return count < 50
+		})
+		if count != len(rest)-j && count != 50 {
+			t.Fatalf("unexpected count %d", count)
+		}
+		j = sort.SearchInts(rest, i+1) - 1
+		count = 0
+		tr.Descend(int64(i), func(key int64, value interface{}) bool {
+			if int(key) != rest[j-count] {
+				t.Fatalf("expected %d, got %d", rest[j-count], key)
+			}
+			count++
+			return count < 50
+		})
+		if count != j+1 && count != 50 {
+			t.Fatalf("unexpected count %d", count)
+		}
+	}
+	all = all[:0]
+	tr.Reverse(func(key int64, value interface{}) bool {
+		all = append(all, int(key))
+		return true
+	})
+	assert.Equal(t, len(rest), len(all))
+	assert.Equal(t, rest[len(rest)-1], all[0])
+
+	// delete everything while scanning
+	tr.Scan(func(key int64, value interface{}) bool {
+		tr.Delete(key)
+		return true
+	})
+	assert.Equal(t, 0, tr.Len())
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBPlusTreeRandom(t *testing.T) {
+	var tr BPlusTree
+	m := make(map[int64]int)
+	for i := 0; i < 50000; i++ {
+		key := int64(rand.Intn(2000))
+		if rand.Intn(2) == 0 {
+			tr.Set(key, i)
+			m[key] = i
+		} else {
+			prev, deleted := tr.Delete(key)
+			if v, ok := m[key]; ok != deleted || (ok && v != prev) {
+				t.Fatalf("expected '%v', got '%v'", v, prev)
+			}
+			delete(m, key)
+		}
+		if i%1000 == 0 {
+			if err := tr.sane(); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, len(m), tr.Len())
+		}
+	}
+}
+
+func BenchmarkBPlusTreeIterate(b *testing.B) {
+	var tr BPlusTree
+	for i := 1; i <= 1000000; i++ {
+		tr.Set(int64(i), i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr.Scan(func(key int64, value interface{}) bool {
+			return true
+		})
+	}
+}
+
+func BenchmarkBPlusTreeAscend(b *testing.B) {
+	var tr BPlusTree
+	for i := 1; i <= 1000000; i++ {
+		tr.Set(int64(i), i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var count int
+		tr.Ascend(int64(n%1000000), func(key int64, value interface{}) bool {
+			count++
+			return count < 100
+		})
+	}
+}
+
+func BenchmarkBPlusTreeDescend(b *testing.B) {
+	var tr BPlusTree
+	for i := 1; i <= 1000000; i++ {
+		tr.Set(int64(i), i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var count int
+		tr.Descend(int64(n%1000000), func(key int64, value interface{}) bool {
+			count++
+			return count < 100
+		})
+	}
+}
+
+func BenchmarkBPlusTreeIterateRandom(b *testing.B) {
+	// leaves that were split in random order are scattered in memory
+	var tr BPlusTree
+	for _, i := range rand.Perm(1000000) {
+		tr.Set(int64(i), i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr.Scan(func(key int64, value interface{}) bool {
+			return true
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package tinybtree
+
+// BPlusIter is a position in a BPlusTree that moves in both directions
+// along the linked leaves, so changing direction never searches the tree
+// again. It must not be used after items were inserted into or removed
+// from the tree, which makes it panic with ErrConcurrentModification.
+type BPlusIter struct {
+	n    *bpNode // nil when the tree is empty
+	i    int     // -1 before the first item, n.numItems after the last one
+	cur  *uint64 // modification counter of the tree
+	mods uint64  // the counter at the start
+}
+
+// IterAt returns an iterator at the first item with a key greater or equal
+// to key. It's past the last item when there is none.
+func (tr *BPlusTree) IterAt(key int64) *BPlusIter {
+	if tr.root == nil {
+		return &BPlusIter{cur: &tr.mods, mods: tr.mods}
+	}
+	n := tr.leaf(key)
+	i := n.lower(key)
+	if i == n.numItems && n.next != nil {
+		n, i = n.next, 0
+	}
+	return &BPlusIter{n, i, &tr.mods, tr.mods}
+}
+
+// check panics when the tree was modified
+func (it *BPlusIter) check() {
+	if *it.cur != it.mods {
+		panic(ErrConcurrentModification)
+	}
+}
+
+// Valid returns true when the iterator is at an item
+func (it *BPlusIter) Valid() bool {
+	return it.n != nil && it.i >= 0 && it.i < it.n.numItems
+}
+
+// Next moves to the following item. Returns false when it moved past the
+// last item, from where Prev returns to the last item.
+func (it *BPlusIter) Next() bool {
+	it.check()
+	if it.n == nil || it.i == it.n.numItems {
+		return false
+	}
+	it.i++
+	if it.i < it.n.numItems {
+		return true
+	}
+	if it.n.next == nil {
+		return false
+	}
+	it.n, it.i = it.n.next, 0
+	return true
+}
+
+// Prev moves to the preceding item. Returns false when it moved before the
+// first item, from where Next returns to the first item.
+func (it *BPlusIter) Prev() bool {
+	it.check()
+	if it.n == nil || it.i < 0 {
+		return false
+	}
+	it.i--
+	if it.i >= 0 {
+		return true
+	}
+	if it.n.prev == nil {
+		return false
+	}
+	it.n, it.i = it.n.prev, it.n.prev.numItems-1
+	return true
+}
+
+// Key returns the key of the current item
+func (it *BPlusIter) Key() int64 {
+	it.check()
+	return it.n.items[it.i].key
+}
+
+// Value returns the value of the current item
+func (it *BPlusIter) Value() interface{} {
+	it.check()
+	return it.n.items[it.i].value
+}
@@ -0,0 +1,86 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBPlusIter(t *testing.T) {
+	var tr BPlusTree
+	it := tr.IterAt(0)
+	assert.Equal(t, false, it.Valid())
+	assert.Equal(t, false, it.Next())
+	assert.Equal(t, false, it.Prev())
+
+	const N = 10000
+	for _, i := range rand.Perm(N) {
+		tr.Set(int64(i*2), i)
+	}
+	for _, pivot := range []int64{math.MinInt64, 0, 1, 999, 1000, N*2 - 2} {
+		it := tr.IterAt(pivot)
+		expect := (pivot + 1) / 2 * 2
+		if pivot < 0 {
+			expect = 0
+		}
+		if !it.Valid() || it.Key() != expect {
+			t.Fatalf("%d: expected %d", pivot, expect)
+		}
+		assert.Equal(t, int(expect/2), it.Value())
+	}
+	it = tr.IterAt(N * 2)
+	assert.Equal(t, false, it.Valid())
+	assert.Equal(t, true, it.Prev())
+	assert.Equal(t, int64(N*2-2), it.Key())
+
+	// walk a random path, changing direction along the way
+	it = tr.IterAt(N)
+	pos := N / 2
+	for i := 0; i < 100000; i++ {
+		if rand.Intn(2) == 0 {
+			ok := it.Next()
+			if pos < N {
+				pos++
+			}
+			assert.Equal(t, pos < N, ok)
+		} else {
+			ok := it.Prev()
+			if pos >= 0 {
+				pos--
+			}
+			assert.Equal(t, pos >= 0, ok)
+		}
+		if pos >= 0 && pos < N {
+			if !it.Valid() || it.Key() != int64(pos*2) {
+				t.Fatalf("expected %d", pos*2)
+			}
+		} else {
+			assert.Equal(t, false, it.Valid())
+		}
+	}
+
+	// the ends
+	it = tr.IterAt(math.MinInt64)
+	assert.Equal(t, false, it.Prev())
+	assert.Equal(t, false, it.Prev())
+	assert.Equal(t, true, it.Next())
+	assert.Equal(t, int64(0), it.Key())
+}
+
+func TestBPlusIterConcurrentModification(t *testing.T) {
+	var tr BPlusTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	it := tr.IterAt(50)
+	tr.Set(50, "replaced")
+	assert.Equal(t, int64(50), it.Key())
+	tr.Set(1000, nil)
+	defer func() {
+		assert.Equal(t, ErrConcurrentModification, recover())
+	}()
+	it.Prev()
+	t.Fatal("expected a panic")
+}
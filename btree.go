@@ -1,202 +1,663 @@
 package tinybtree
 
+import (
+	"context"
+	"sync"
+)
+
 const freeKey = -int64(^uint64(0) >> 1)
-const maxItems = 31 // use an odd number
 const minItems = maxItems * 40 / 100
 
+const (
+	// MaxItems is the capacity of a node of a BTree, a node splits when it
+	// fills up. It can be changed with build tags, see the README.
+	MaxItems = maxItems
+	// MinItems is the fewest items that a node other than the root holds
+	MinItems = minItems
+)
+
 type item struct {
-	key   int64
+	key int64
+	slot
+}
+
+// slot is the value of an item
+type slot struct {
 	value interface{}
+	num   int64 // the value when value is inlineInt, see SetInt, or the stamp
 }
 
 type node struct {
 	numItems int
-	items    [maxItems]item
+	count    int         // number of items in the subtree
+	agg      interface{} // aggregate of the subtree, see Aggregator
+	gen      uint64      // generation of the tree owning the node, see Clone
+	// the keys are stored apart from the values so that searching a node
+	// only touches the cache lines of the keys
+	keys     [maxItems]int64
+	vals     [maxItems]slot
 	children [maxItems + 1]*node
 }
 
-// BTree is an ordered set of key/value pairs where the key is a string
+// item returns the item at index i
+func (n *node) item(i int) item {
+	return item{n.keys[i], n.vals[i]}
+}
+
+// setItem sets the item at index i
+func (n *node) setItem(i int, it item) {
+	n.keys[i], n.vals[i] = it.key, it.slot
+}
+
+// copyItems copies the items from index j up to k of src to index i
+func (n *node) copyItems(i int, src *node, j, k int) {
+	copy(n.keys[i:], src.keys[j:k])
+	copy(n.vals[i:], src.vals[j:k])
+}
+
+// clearItems clears the items from index i up to j
+func (n *node) clearItems(i, j int) {
+	for ; i < j; i++ {
+		n.keys[i], n.vals[i] = 0, slot{}
+	}
+}
+
+// BTree is an ordered set of key/value pairs where the key is an int64
 // and the value is an interface{}
 type BTree struct {
 	height int
 	root   *node
 	length int
+	multi  bool
+	agg    Aggregator
+	codec  KeyCodec
+	ret    Retainer
+	alloc  Allocator
+	subs   []*subscription
+	txnMu  sync.Mutex // serializes Update
+	access *accessStats
+	frozen bool
+	mods   uint64 // incremented when items are inserted or removed
+
+	stampMode StampMode
+	version   int64  // the last StampVersion
+	bloom     *bloom // see Options.BloomFilter
+	yield     int
+	gen       uint64 // nodes of another generation are shared, see Clone
+
+	valueHash func(value interface{}) uint64 // see Checksum
+	nodeHook  func(NodeEvent)
+	maxLen    int // see SetMaxLen
+	evict     EvictPolicy
+	gate      func(ctx context.Context) error // see SetContext
+	pool      *sync.Pool                      // see SetPooled
+	recycling bool                            // see recycle
+	tracer    Tracer
+
+	// the smallest and the largest key when the tree isn't empty, so that
+	// Ascend and Descend from beyond them go straight to Scan and Reverse
+	min, max int64
 }
 
-func (n *node) find(key int64) (index int, found bool) {
-	i, j := 0, n.numItems
-	for i < j {
-		h := i + (j-i)/2
-		if key >= n.items[h].key {
-			i = h + 1
-		} else {
-			j = h
+// Options for a new BTree. The zero value of a BTree uses the defaults.
+type Options struct {
+	// Multi allows duplicate keys. Set appends the value instead of replacing
+	// it, Get returns the first value that was set for a key and Delete
+	// removes all values for a key. Use GetAll and DeleteOne to manage
+	// the individual values.
+	Multi bool
+	// Aggregator, when set, maintains an aggregate of the values in each
+	// subtree which is used to answer AggregateRange in O(log n).
+	Aggregator Aggregator
+	// KeyCodec converts the byte slice keys of SetBytes, GetBytes and
+	// DeleteBytes. Defaults to BigEndianCodec.
+	KeyCodec KeyCodec
+	// Retainer, when set, is told about every value that is stored in or
+	// removed from the tree.
+	Retainer Retainer
+	// Allocator, when set, provides the nodes of the tree.
+	Allocator Allocator
+	// CacheAligned makes the tree use an AlignedAllocator when it has no
+	// Allocator, so that the keys of every node start at a cache line.
+	CacheAligned bool
+	// Stamp records a stamp for each value that is set, which is returned
+	// by GetWithMeta. Values stored with SetInt are boxed when stamping.
+	Stamp StampMode
+	// BloomFilter maintains a counting bloom filter of the keys, which
+	// answers most lookups of missing keys without searching the tree. It
+	// takes about 10 bytes per key.
+	BloomFilter bool
+	// YieldEvery, when set, makes iterations call runtime.Gosched after
+	// every so many visited items, so that a scan over a huge tree doesn't
+	// hold up other goroutines that are waiting for the processor.
+	YieldEvery int
+	// ValueHash hashes the values for Checksum. Defaults to HashValue.
+	ValueHash func(value interface{}) uint64
+	// NodeHook, when set, is called when nodes are split or rebalanced,
+	// such as for finding the key ranges that cause bursts of them. It must
+	// not access the tree.
+	NodeHook func(NodeEvent)
+	// Gate, when set, is called by SetContext and DeleteContext before
+	// they write, such as for rate limiting a bulk load. When it returns
+	// an error the write is not made and the error is returned.
+	Gate func(ctx context.Context) error
+	// Pool, when set, is the New function of the pool of SetPooled. It
+	// should return pointers. Replaced and deleted values are put back into
+	// the pool, so the tree must only hold values of SetPooled.
+	Pool func() interface{}
+	// Tracer, when set, starts a span around each bulk operation.
+	Tracer Tracer
+}
+
+// New returns a new BTree using the provided options.
+func New(opts *Options) *BTree {
+	tr := new(BTree)
+	if opts != nil {
+		tr.multi = opts.Multi
+		tr.agg = opts.Aggregator
+		tr.codec = opts.KeyCodec
+		tr.ret = opts.Retainer
+		tr.alloc = opts.Allocator
+		if tr.alloc == nil && opts.CacheAligned {
+			tr.alloc = new(AlignedAllocator)
+		}
+		tr.stampMode = opts.Stamp
+		if opts.BloomFilter {
+			tr.bloom = newBloom(0)
+		}
+		tr.yield = opts.YieldEvery
+		tr.valueHash = opts.ValueHash
+		tr.nodeHook = opts.NodeHook
+		tr.gate = opts.Gate
+		tr.tracer = opts.Tracer
+		if opts.Pool != nil {
+			tr.pool = &sync.Pool{New: opts.Pool}
+			tr.recycling = true
 		}
 	}
-	if i > 0 && n.items[i-1].key >= key {
-		return i - 1, true
-	}
-	return i, false
+	return tr
 }
 
 // Set or replace a value for a key
 func (tr *BTree) Set(key int64, value interface{}) (
 	prev interface{}, replaced bool,
 ) {
+	var prevItem item
+	prevItem, replaced = tr.set(item{key: key, slot: slot{value: value}})
+	return prevItem.val(), replaced
+}
+
+func (tr *BTree) set(it item) (prev item, replaced bool) {
+	tr.checkWrite()
+	if tr.access != nil {
+		tr.access.record(it.key)
+	}
+	if tr.stampMode != NoStamp {
+		it.slot = tr.stamp(it.slot)
+	}
+	if tr.ret != nil {
+		tr.ret.Retain(it.val())
+	}
 	if tr.root == nil {
-		tr.root = new(node)
-		tr.root.items[0] = item{key, value}
+		tr.root = tr.newNode()
+		tr.root.setItem(0, it)
 		tr.root.numItems = 1
+		tr.root.count = 1
+		tr.root.aggregate(tr, 0)
 		tr.length = 1
+		tr.min, tr.max = it.key, it.key
+		tr.mods++
+		if tr.bloom != nil {
+			tr.bloomAdd(it.key)
+		}
+		tr.emitSet(it, prev, false)
 		return
 	}
-	prev, replaced = tr.root.set(key, value, tr.height)
+	tr.root = tr.mut(tr.root)
+	prev, replaced = tr.root.set(tr, it, tr.height)
 	if replaced {
+		if tr.ret != nil {
+			tr.ret.Release(prev.val())
+		}
+		tr.emitSet(it, prev, true)
+		tr.recycle(prev.slot)
 		return
 	}
 	if tr.root.numItems == maxItems {
 		n := tr.root
-		right, median := n.split(tr.height)
-		tr.root = new(node)
+		right, median := n.split(tr, tr.height)
+		tr.root = tr.newNode()
 		tr.root.children[0] = n
-		tr.root.items[0] = median
+		tr.root.setItem(0, median)
 		tr.root.children[1] = right
 		tr.root.numItems = 1
+		tr.root.count = n.count + right.count + 1
 		tr.height++
+		tr.root.aggregate(tr, tr.height)
 	}
 	tr.length++
+	tr.mods++
+	if it.key < tr.min {
+		tr.min = it.key
+	} else if it.key > tr.max {
+		tr.max = it.key
+	}
+	if tr.bloom != nil {
+		tr.bloomAdd(it.key)
+	}
+	tr.emitSet(it, prev, false)
+	if tr.maxLen > 0 && tr.length > tr.maxLen {
+		tr.evictOne()
+	}
 	return
 }
 
-func (n *node) split(height int) (right *node, median item) {
-	right = new(node)
-	median = n.items[maxItems/2]
-	copy(right.items[:maxItems/2], n.items[maxItems/2+1:])
+func (n *node) split(tr *BTree, height int) (right *node, median item) {
+	right = tr.newNode()
+	median = n.item(maxItems / 2)
+	right.copyItems(0, n, maxItems/2+1, maxItems)
 	if height > 0 {
-		copy(right.children[:maxItems/2+1], n.children[maxItems/2+1:])
+		copy(right.children[:], n.children[maxItems/2+1:])
 	}
-	right.numItems = maxItems / 2
+	right.numItems = maxItems - maxItems/2 - 1
+	right.count = right.numItems
 	if height > 0 {
 		for i := maxItems/2 + 1; i < maxItems+1; i++ {
+			right.count += n.children[i].count
 			n.children[i] = nil
 		}
 	}
-	for i := maxItems / 2; i < maxItems; i++ {
-		n.items[i] = item{}
-	}
+	n.count -= right.count + 1
+	n.clearItems(maxItems/2, maxItems)
 	n.numItems = maxItems / 2
+	n.aggregate(tr, height)
+	right.aggregate(tr, height)
+	if tr.nodeHook != nil {
+		tr.nodeHook(NodeEvent{Kind: NodeSplit, Key: median.key,
+			Min: n.keys[0], Max: right.keys[right.numItems-1],
+			Height: height})
+	}
 	return
 }
 
-func (n *node) set(key int64, value interface{}, height int) (
-	prev interface{}, replaced bool,
+func (n *node) set(tr *BTree, it item, height int) (
+	prev item, replaced bool,
 ) {
-	i, found := n.find(key)
+	i, found := n.find(it.key)
 	if found {
-		prev = n.items[i].value
-		n.items[i].value = value
-		return prev, true
+		if !tr.multi {
+			prev = n.item(i)
+			n.setItem(i, it)
+			n.aggregate(tr, height)
+			return prev, true
+		}
+		// duplicates go after the existing ones
+		i++
 	}
 	if height == 0 {
-		for j := n.numItems; j > i; j-- {
-			n.items[j] = n.items[j-1]
-		}
-		n.items[i] = item{key, value}
+		n.copyItems(i+1, n, i, n.numItems)
+		n.setItem(i, it)
 		n.numItems++
-		return nil, false
+		n.count++
+		n.aggregate(tr, height)
+		return item{}, false
 	}
-	prev, replaced = n.children[i].set(key, value, height-1)
+	prev, replaced = n.mutChild(tr, i).set(tr, it, height-1)
 	if replaced {
+		n.aggregate(tr, height)
 		return
 	}
+	n.count++
 	if n.children[i].numItems == maxItems {
-		right, median := n.children[i].split(height - 1)
-		copy(n.children[i+1:], n.children[i:])
-		copy(n.items[i+1:], n.items[i:])
-		n.items[i] = median
-		n.children[i+1] = right
-		n.numItems++
+		n.splitChild(tr, i, height)
 	}
+	n.aggregate(tr, height)
 	return
 }
 
-// Scan all items in tree
+// splitChild splits the full child at index i in two
+func (n *node) splitChild(tr *BTree, i, height int) {
+	right, median := n.children[i].split(tr, height-1)
+	copy(n.children[i+1:], n.children[i:])
+	n.copyItems(i+1, n, i, n.numItems)
+	n.setItem(i, median)
+	n.children[i+1] = right
+	n.numItems++
+}
+
+// Scan all items in tree. The iter function may modify the tree, in which
+// case the scan continues after the last visited key.
 func (tr *BTree) Scan(iter func(key int64, value interface{}) bool) {
-	if tr.root != nil {
-		tr.root.scan(iter, tr.height)
+	it := iterState{cur: &tr.mods, iter: iter, mods: tr.mods,
+		yield: tr.yield}
+	defer it.catch()
+	if tr.root != nil && !tr.root.scan(&it, tr.height) && it.stale {
+		tr.ascend(it.resume(), &it)
 	}
 }
 
 func (n *node) scan(
-	iter func(key int64, value interface{}) bool, height int,
+	it *iterState, height int,
 ) bool {
 	if height == 0 {
 		for i := 0; i < n.numItems; i++ {
-			if !iter(n.items[i].key, n.items[i].value) {
+			if !it.visit(n.keys[i], n.vals[i].val()) {
 				return false
 			}
 		}
 		return true
 	}
 	for i := 0; i < n.numItems; i++ {
-		if !n.children[i].scan(iter, height-1) {
+		if !n.children[i].scan(it, height-1) {
+			return false
+		}
+		if !it.visit(n.keys[i], n.vals[i].val()) {
+			return false
+		}
+	}
+	return n.children[n.numItems].scan(it, height-1)
+}
+
+// ScanFrom scans the items in tree starting with the item at index offset.
+// Items are always in key order, and duplicate keys in the order they were
+// set, so an offset names the same item as long as the tree is unchanged.
+// Seeking to the offset takes O(log n) time.
+func (tr *BTree) ScanFrom(
+	offset int,
+	iter func(key int64, value interface{}) bool,
+) {
+	if offset < 0 {
+		offset = 0
+	}
+	it := iterState{cur: &tr.mods, iter: iter, mods: tr.mods,
+		yield: tr.yield}
+	defer it.catch()
+	if tr.root != nil && offset < tr.length &&
+		!tr.root.scanFrom(offset, &it, tr.height) && it.stale {
+		tr.ascend(it.resume(), &it)
+	}
+}
+
+func (n *node) scanFrom(offset int, it *iterState, height int) bool {
+	if height == 0 {
+		for i := offset; i < n.numItems; i++ {
+			if !it.visit(n.keys[i], n.vals[i].val()) {
+				return false
+			}
+		}
+		return true
+	}
+	i := 0
+	for ; i < n.numItems && offset > n.children[i].count; i++ {
+		offset -= n.children[i].count + 1
+	}
+	if offset < n.children[i].count &&
+		!n.children[i].scanFrom(offset, it, height-1) {
+		return false
+	}
+	for ; i < n.numItems; i++ {
+		if !it.visit(n.keys[i], n.vals[i].val()) {
 			return false
 		}
-		if !iter(n.items[i].key, n.items[i].value) {
+		if !n.children[i+1].scan(it, height-1) {
 			return false
 		}
 	}
-	return n.children[n.numItems].scan(iter, height-1)
+	return true
 }
 
 // Get a value for key
 func (tr *BTree) Get(key int64) (value interface{}, gotten bool) {
-	if tr.root == nil {
-		return
+	if it := tr.get(key); it != nil {
+		return it.val(), true
+	}
+	return nil, false
+}
+
+// GetErr returns the value for key, or ErrKeyNotFound when the key is not in
+// the tree.
+func (tr *BTree) GetErr(key int64) (value interface{}, err error) {
+	if it := tr.get(key); it != nil {
+		return it.val(), nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (tr *BTree) get(key int64) *slot {
+	if tr.access != nil {
+		tr.access.record(key)
+	}
+	if tr.root == nil || (tr.bloom != nil && !tr.bloom.has(key)) {
+		return nil
+	}
+	if tr.multi {
+		return tr.root.getFirst(key, tr.height)
 	}
 	return tr.root.get(key, tr.height)
 }
 
-func (n *node) get(key int64, height int) (value interface{}, gotten bool) {
+// at returns the item at index, counting from the smallest key
+func (n *node) at(index, height int) item {
+	if height == 0 {
+		return n.item(index)
+	}
+	for i := 0; i < n.numItems; i++ {
+		count := n.children[i].count
+		if index < count {
+			return n.children[i].at(index, height-1)
+		}
+		if index == count {
+			return n.item(i)
+		}
+		index -= count + 1
+	}
+	return n.children[n.numItems].at(index, height-1)
+}
+
+// rank returns the number of items with a key less than key
+func (n *node) rank(key int64, height int) int {
+	i, _ := n.findFirst(key)
+	if height == 0 {
+		return i
+	}
+	rank := i
+	for j := 0; j < i; j++ {
+		rank += n.children[j].count
+	}
+	return rank + n.children[i].rank(key, height-1)
+}
+
+// GetAll returns all values for key in the order they were set
+func (tr *BTree) GetAll(key int64) (values []interface{}) {
+	tr.Ascend(key, func(k int64, v interface{}) bool {
+		if k != key {
+			return false
+		}
+		values = append(values, v)
+		return true
+	})
+	return values
+}
+
+func (n *node) get(key int64, height int) *slot {
 	i, found := n.find(key)
 	if found {
-		return n.items[i].value, true
+		return &n.vals[i]
 	}
 	if height == 0 {
-		return nil, false
+		return nil
 	}
 	return n.children[i].get(key, height-1)
 }
 
+func (n *node) getFirst(key int64, height int) *slot {
+	i, found := n.findFirst(key)
+	if height > 0 {
+		// the left child may hold earlier duplicates of the key
+		if it := n.children[i].getFirst(key, height-1); it != nil {
+			return it
+		}
+	}
+	if found {
+		return &n.vals[i]
+	}
+	return nil
+}
+
 // Len returns the number of items in the tree
 func (tr *BTree) Len() int {
 	return tr.length
 }
 
-// Delete a value for a key
+// Delete a value for a key. In multi mode all values for the key are
+// deleted and the first one is returned.
 func (tr *BTree) Delete(key int64) (prev interface{}, deleted bool) {
+	tr.checkWrite()
+	if tr.multi {
+		prev, deleted = tr.DeleteOne(key)
+		if deleted {
+			for _, ok := tr.DeleteOne(key); ok; _, ok = tr.DeleteOne(key) {
+			}
+		}
+		return prev, deleted
+	}
 	if tr.root == nil {
 		return
 	}
 	var prevItem item
-	prevItem, deleted = tr.root.delete(false, key, tr.height)
+	tr.root = tr.mut(tr.root)
+	prevItem, deleted = tr.root.delete(tr, false, key, tr.height)
 	if !deleted {
 		return
 	}
-	prev = prevItem.value
+	prev = prevItem.val()
+	tr.shrink(key)
+	if tr.bloom != nil {
+		tr.bloom.remove(key)
+	}
+	if tr.ret != nil {
+		tr.ret.Release(prev)
+	}
+	tr.emit(Event{Kind: EventDelete, Key: key, Value: prev})
+	tr.recycle(prevItem.slot)
+	return
+}
+
+// DeleteFn deletes a value for a key and passes the deleted value to
+// onDelete. In multi mode onDelete is called for each deleted value. A
+// value of SetPooled goes back into the pool once onDelete returns.
+func (tr *BTree) DeleteFn(key int64, onDelete func(value interface{})) (
+	deleted bool,
+) {
+	for {
+		prev, ok := tr.removeFirst(key)
+		if !ok {
+			return deleted
+		}
+		onDelete(prev.val())
+		tr.recycle(prev.slot)
+		deleted = true
+		if !tr.multi {
+			return deleted
+		}
+	}
+}
+
+// DeleteOne deletes the first value for a key. Outside of multi mode it's
+// the same as Delete.
+func (tr *BTree) DeleteOne(key int64) (prev interface{}, deleted bool) {
+	prevItem, deleted := tr.removeFirst(key)
+	if !deleted {
+		return nil, false
+	}
+	tr.recycle(prevItem.slot)
+	return prevItem.val(), true
+}
+
+// removeFirst deletes the first value for a key without putting it back
+// into the pool, which is left to the caller
+func (tr *BTree) removeFirst(key int64) (prev item, deleted bool) {
+	tr.checkWrite()
+	if tr.root == nil {
+		return
+	}
+	tr.root = tr.mut(tr.root)
+	prev, deleted = tr.root.deleteFirst(tr, key, tr.height)
+	if !deleted {
+		return
+	}
+	tr.shrink(key)
+	if tr.bloom != nil {
+		tr.bloom.remove(key)
+	}
+	if tr.ret != nil || tr.subs != nil {
+		value := prev.val()
+		if tr.ret != nil {
+			tr.ret.Release(value)
+		}
+		tr.emit(Event{Kind: EventDelete, Key: key, Value: value})
+	}
+	return
+}
+
+// DeleteItem is like DeleteOne but returns the deleted item, with its key.
+// The value is handed to the caller, so a value of SetPooled doesn't go
+// back into the pool.
+func (tr *BTree) DeleteItem(key int64) (removed Item, deleted bool) {
+	prev, deleted := tr.removeFirst(key)
+	if deleted {
+		removed = Item{key, prev.val()}
+	}
+	return removed, deleted
+}
+
+// shrink updates the tree after an item with key was removed from the root
+func (tr *BTree) shrink(key int64) {
 	if tr.root.numItems == 0 {
+		old := tr.root
 		tr.root = tr.root.children[0]
 		tr.height--
+		tr.freeNode(old)
 	}
 	tr.length--
+	tr.mods++
 	if tr.length == 0 {
 		tr.root = nil
 		tr.height = 0
+	} else if key == tr.min || key == tr.max {
+		tr.updateMinMax()
 	}
-	return
 }
 
-func (n *node) delete(max bool, key int64, height int) (
+// updateMinMax finds the smallest and the largest key by following the
+// edges of the tree
+func (tr *BTree) updateMinMax() {
+	if tr.root == nil {
+		return
+	}
+	n := tr.root
+	for h := tr.height; h > 0; h-- {
+		n = n.children[0]
+	}
+	tr.min = n.keys[0]
+	n = tr.root
+	for h := tr.height; h > 0; h-- {
+		n = n.children[n.numItems]
+	}
+	tr.max = n.keys[n.numItems-1]
+}
+
+// Min returns the smallest key, or false when the tree is empty
+func (tr *BTree) Min() (key int64, ok bool) {
+	return tr.min, tr.root != nil
+}
+
+// Max returns the largest key, or false when the tree is empty
+func (tr *BTree) Max() (key int64, ok bool) {
+	return tr.max, tr.root != nil
+}
+
+func (n *node) delete(tr *BTree, max bool, key int64, height int) (
 	prev item, deleted bool,
 ) {
 	i, found := 0, false
@@ -207,12 +668,13 @@ func (n *node) delete(max bool, key int64, height int) (
 	}
 	if height == 0 {
 		if found {
-			prev = n.items[i]
+			prev = n.item(i)
 			// found the items at the leaf, remove it and return.
-			copy(n.items[i:], n.items[i+1:n.numItems])
-			n.items[n.numItems-1] = item{}
-			n.children[n.numItems] = nil
+			n.copyItems(i, n, i+1, n.numItems)
+			n.clearItems(n.numItems-1, n.numItems)
 			n.numItems--
+			n.count--
+			n.aggregate(tr, height)
 			return prev, true
 		}
 		return item{}, false
@@ -221,108 +683,200 @@ func (n *node) delete(max bool, key int64, height int) (
 	if found {
 		if max {
 			i++
-			prev, deleted = n.children[i].delete(true, freeKey, height-1)
+			prev, deleted = n.mutChild(tr, i).delete(tr, true, freeKey,
+				height-1)
 		} else {
-			prev = n.items[i]
-			maxItem, _ := n.children[i].delete(true, freeKey, height-1)
-			n.items[i] = maxItem
+			prev = n.item(i)
+			maxItem, _ := n.mutChild(tr, i).delete(tr, true, freeKey,
+				height-1)
+			n.setItem(i, maxItem)
 			deleted = true
 		}
 	} else {
-		prev, deleted = n.children[i].delete(max, key, height-1)
+		prev, deleted = n.mutChild(tr, i).delete(tr, max, key, height-1)
 	}
 	if !deleted {
 		return
 	}
-	if n.children[i].numItems < minItems {
-		if i == n.numItems {
-			i--
-		}
-		if n.children[i].numItems+n.children[i+1].numItems+1 < maxItems {
-			// merge left + item + right
-			n.children[i].items[n.children[i].numItems] = n.items[i]
-			copy(n.children[i].items[n.children[i].numItems+1:],
-				n.children[i+1].items[:n.children[i+1].numItems])
-			if height > 1 {
-				copy(n.children[i].children[n.children[i].numItems+1:],
-					n.children[i+1].children[:n.children[i+1].numItems+1])
-			}
-			n.children[i].numItems += n.children[i+1].numItems + 1
-			copy(n.items[i:], n.items[i+1:n.numItems])
-			copy(n.children[i+1:], n.children[i+2:n.numItems+1])
-			n.items[n.numItems] = item{}
-			n.children[n.numItems+1] = nil
-			n.numItems--
-		} else if n.children[i].numItems > n.children[i+1].numItems {
-			// move left -> right
-			copy(n.children[i+1].items[1:],
-				n.children[i+1].items[:n.children[i+1].numItems])
-			if height > 1 {
-				copy(n.children[i+1].children[1:],
-					n.children[i+1].children[:n.children[i+1].numItems+1])
-			}
-			n.children[i+1].items[0] = n.items[i]
-			if height > 1 {
-				n.children[i+1].children[0] =
-					n.children[i].children[n.children[i].numItems]
-			}
-			n.children[i+1].numItems++
-			n.items[i] = n.children[i].items[n.children[i].numItems-1]
-			n.children[i].items[n.children[i].numItems-1] = item{}
-			if height > 1 {
-				n.children[i].children[n.children[i].numItems] = nil
-			}
-			n.children[i].numItems--
-		} else {
-			// move right -> left
-			n.children[i].items[n.children[i].numItems] = n.items[i]
-			if height > 1 {
-				n.children[i].children[n.children[i].numItems+1] =
-					n.children[i+1].children[0]
-			}
-			n.children[i].numItems++
-			n.items[i] = n.children[i+1].items[0]
-			copy(n.children[i+1].items[:],
-				n.children[i+1].items[1:n.children[i+1].numItems])
-			if height > 1 {
-				copy(n.children[i+1].children[:],
-					n.children[i+1].children[1:n.children[i+1].numItems+1])
-			}
-			n.children[i+1].numItems--
+	n.count--
+	n.rebalance(tr, i, height)
+	n.aggregate(tr, height)
+	return
+}
+
+func (n *node) deleteFirst(tr *BTree, key int64, height int) (
+	prev item, deleted bool,
+) {
+	i, found := n.findFirst(key)
+	if height == 0 {
+		if !found {
+			return item{}, false
+		}
+		prev = n.item(i)
+		n.copyItems(i, n, i+1, n.numItems)
+		n.clearItems(n.numItems-1, n.numItems)
+		n.numItems--
+		n.count--
+		n.aggregate(tr, height)
+		return prev, true
+	}
+	// earlier duplicates of the key may be in the left child
+	prev, deleted = n.mutChild(tr, i).deleteFirst(tr, key, height-1)
+	if !deleted {
+		if !found {
+			return item{}, false
 		}
+		prev = n.item(i)
+		maxItem, _ := n.children[i].delete(tr, true, freeKey, height-1)
+		n.setItem(i, maxItem)
+		deleted = true
 	}
+	n.count--
+	n.rebalance(tr, i, height)
+	n.aggregate(tr, height)
 	return
 }
 
+// rebalance fixes the child at index i when it has too few items
+func (n *node) rebalance(tr *BTree, i, height int) {
+	if n.children[i].numItems >= minItems {
+		return
+	}
+	if i == n.numItems {
+		i--
+	}
+	left, right := n.mutChild(tr, i), n.children[i+1]
+	if tr.nodeHook != nil {
+		tr.nodeHook(rebalanceEvent(n, i, height))
+	}
+	if left.numItems+right.numItems+1 < maxItems {
+		// merge left + item + right
+		left.setItem(left.numItems, n.item(i))
+		left.copyItems(left.numItems+1, right, 0, right.numItems)
+		if height > 1 {
+			copy(left.children[left.numItems+1:],
+				right.children[:right.numItems+1])
+		}
+		left.numItems += right.numItems + 1
+		left.count += right.count + 1
+		n.copyItems(i, n, i+1, n.numItems)
+		copy(n.children[i+1:], n.children[i+2:n.numItems+1])
+		n.clearItems(n.numItems-1, n.numItems)
+		n.children[n.numItems] = nil
+		n.numItems--
+		left.aggregate(tr, height-1)
+		tr.freeNode(right)
+	} else if left.numItems > right.numItems {
+		// move left -> right
+		right = n.mutChild(tr, i+1)
+		right.copyItems(1, right, 0, right.numItems)
+		if height > 1 {
+			copy(right.children[1:], right.children[:right.numItems+1])
+		}
+		right.setItem(0, n.item(i))
+		moved := 1
+		if height > 1 {
+			right.children[0] = left.children[left.numItems]
+			moved += right.children[0].count
+		}
+		right.numItems++
+		right.count += moved
+		left.count -= moved
+		n.setItem(i, left.item(left.numItems-1))
+		left.clearItems(left.numItems-1, left.numItems)
+		if height > 1 {
+			left.children[left.numItems] = nil
+		}
+		left.numItems--
+		left.aggregate(tr, height-1)
+		right.aggregate(tr, height-1)
+	} else {
+		// move right -> left
+		right = n.mutChild(tr, i+1)
+		left.setItem(left.numItems, n.item(i))
+		moved := 1
+		if height > 1 {
+			left.children[left.numItems+1] = right.children[0]
+			moved += right.children[0].count
+		}
+		left.numItems++
+		left.count += moved
+		right.count -= moved
+		n.setItem(i, right.item(0))
+		right.copyItems(0, right, 1, right.numItems)
+		if height > 1 {
+			copy(right.children[:], right.children[1:right.numItems+1])
+		}
+		right.numItems--
+		right.clearItems(right.numItems, right.numItems+1)
+		if height > 1 {
+			right.children[right.numItems+1] = nil
+		}
+		left.aggregate(tr, height-1)
+		right.aggregate(tr, height-1)
+	}
+}
+
+// rebalanceEvent returns the NodeEvent for rebalancing the children of the
+// node at index i and i+1
+func rebalanceEvent(n *node, i, height int) NodeEvent {
+	left, right := n.children[i], n.children[i+1]
+	e := NodeEvent{Kind: NodeBorrow, Key: n.keys[i], Min: n.keys[i],
+		Max: n.keys[i], Height: height - 1}
+	if left.numItems+right.numItems+1 < maxItems {
+		e.Kind = NodeMerge
+	}
+	if left.numItems > 0 {
+		e.Min = left.keys[0]
+	}
+	if right.numItems > 0 {
+		e.Max = right.keys[right.numItems-1]
+	}
+	return e
+}
+
 // Ascend the tree within the range [pivot, last]
 func (tr *BTree) Ascend(
 	pivot int64,
 	iter func(key int64, value interface{}) bool,
 ) {
-	if tr.root != nil {
-		tr.root.ascend(pivot, iter, tr.height)
+	if tr.root != nil && pivot <= tr.min {
+		tr.Scan(iter)
+		return
+	}
+	tr.ascend(pivot, &iterState{cur: &tr.mods, iter: iter,
+		yield: tr.yield})
+}
+
+func (tr *BTree) ascend(pivot int64, it *iterState) {
+	defer it.catch()
+	it.dups = tr.multi
+	for tr.root != nil {
+		it.mods = tr.mods
+		if tr.root.ascend(pivot, it, tr.height) || !it.stale {
+			return
+		}
+		pivot = it.resume()
 	}
 }
 
 func (n *node) ascend(
-	pivot int64,
-	iter func(key int64, value interface{}) bool,
-	height int,
+	pivot int64, it *iterState, height int,
 ) bool {
-	i, found := n.find(pivot)
-	if !found {
-		if height > 0 {
-			if !n.children[i].ascend(pivot, iter, height-1) {
-				return false
-			}
+	// in multi mode the child left of the first match may still hold
+	// duplicates of the pivot
+	i, found := n.findFirst(pivot)
+	if height > 0 && (!found || it.dups) {
+		if !n.children[i].ascend(pivot, it, height-1) {
+			return false
 		}
 	}
 	for ; i < n.numItems; i++ {
-		if !iter(n.items[i].key, n.items[i].value) {
+		if !it.visit(n.keys[i], n.vals[i].val()) {
 			return false
 		}
 		if height > 0 {
-			if !n.children[i+1].scan(iter, height-1) {
+			if !n.children[i+1].scan(it, height-1) {
 				return false
 			}
 		}
@@ -330,32 +884,36 @@ func (n *node) ascend(
 	return true
 }
 
-// Reverse all items in tree
+// Reverse all items in tree. The iter function may modify the tree, in which
+// case the scan continues before the last visited key.
 func (tr *BTree) Reverse(iter func(key int64, value interface{}) bool) {
-	if tr.root != nil {
-		tr.root.reverse(iter, tr.height)
+	it := iterState{cur: &tr.mods, iter: iter, mods: tr.mods,
+		yield: tr.yield}
+	defer it.catch()
+	if tr.root != nil && !tr.root.reverse(&it, tr.height) && it.stale {
+		tr.descend(it.resume(), &it)
 	}
 }
 
 func (n *node) reverse(
-	iter func(key int64, value interface{}) bool, height int,
+	it *iterState, height int,
 ) bool {
 	if height == 0 {
 		for i := n.numItems - 1; i >= 0; i-- {
-			if !iter(n.items[i].key, n.items[i].value) {
+			if !it.visit(n.keys[i], n.vals[i].val()) {
 				return false
 			}
 		}
 		return true
 	}
-	if !n.children[n.numItems].reverse(iter, height-1) {
+	if !n.children[n.numItems].reverse(it, height-1) {
 		return false
 	}
 	for i := n.numItems - 1; i >= 0; i-- {
-		if !iter(n.items[i].key, n.items[i].value) {
+		if !it.visit(n.keys[i], n.vals[i].val()) {
 			return false
 		}
-		if !n.children[i].reverse(iter, height-1) {
+		if !n.children[i].reverse(it, height-1) {
 			return false
 		}
 	}
@@ -367,31 +925,46 @@ func (tr *BTree) Descend(
 	pivot int64,
 	iter func(key int64, value interface{}) bool,
 ) {
-	if tr.root != nil {
-		tr.root.descend(pivot, iter, tr.height)
+	if tr.root != nil && pivot >= tr.max {
+		tr.Reverse(iter)
+		return
+	}
+	tr.descend(pivot, &iterState{cur: &tr.mods, iter: iter,
+		yield: tr.yield})
+}
+
+func (tr *BTree) descend(pivot int64, it *iterState) {
+	defer it.catch()
+	it.dups = tr.multi
+	for tr.root != nil {
+		it.mods = tr.mods
+		if tr.root.descend(pivot, it, tr.height) || !it.stale {
+			return
+		}
+		pivot = it.resume()
 	}
 }
 
 func (n *node) descend(
-	pivot int64,
-	iter func(key int64, value interface{}) bool,
-	height int,
+	pivot int64, it *iterState, height int,
 ) bool {
+	// in multi mode the child right of the last match may still hold
+	// duplicates of the pivot
 	i, found := n.find(pivot)
-	if !found {
-		if height > 0 {
-			if !n.children[i].descend(pivot, iter, height-1) {
-				return false
-			}
+	if found {
+		i++
+	}
+	if height > 0 && (!found || it.dups) {
+		if !n.children[i].descend(pivot, it, height-1) {
+			return false
 		}
-		i--
 	}
-	for ; i >= 0; i-- {
-		if !iter(n.items[i].key, n.items[i].value) {
+	for i--; i >= 0; i-- {
+		if !it.visit(n.keys[i], n.vals[i].val()) {
 			return false
 		}
 		if height > 0 {
-			if !n.children[i].reverse(iter, height-1) {
+			if !n.children[i].reverse(it, height-1) {
 				return false
 			}
 		}
@@ -399,22 +972,32 @@ func (n *node) descend(
 	return true
 }
 
+// GreaterOrEqual is the same as Ascend. Use Iterate for ranges with
+// explicit bounds.
 func (tr *BTree) GreaterOrEqual(
 	pivot int64,
 	iter func(key int64, value interface{}) bool,
 ) {
-	if tr.root != nil {
-		tr.root.ascend(pivot, iter, tr.height)
+	if tr.root != nil && pivot <= tr.min {
+		tr.Scan(iter)
+		return
 	}
+	tr.ascend(pivot, &iterState{cur: &tr.mods, iter: iter,
+		yield: tr.yield})
 }
 
+// LessOrEqual is the same as Descend. Use Iterate for ranges with explicit
+// bounds.
 func (tr *BTree) LessOrEqual(
 	pivot int64,
 	iter func(key int64, value interface{}) bool,
 ) {
-	if tr.root != nil {
-		tr.root.descend(pivot, iter, tr.height)
+	if tr.root != nil && pivot >= tr.max {
+		tr.Reverse(iter)
+		return
 	}
+	tr.descend(pivot, &iterState{cur: &tr.mods, iter: iter,
+		yield: tr.yield})
 }
 
 func (tr *BTree) Next(pivot int64) (key int64, value interface{}) {
@@ -445,49 +1028,33 @@ func (tr *BTree) Prev(pivot int64) (key int64, value interface{}) {
 	return
 }
 
-func (tr *BTree) GetOrNearest(key int64) (nKey int64, nValue interface{}) {
+// GetOrNearest returns the item with key, or else the item with the largest
+// key less than key. Returns false when there is no such item.
+func (tr *BTree) GetOrNearest(key int64) (
+	nKey int64, nValue interface{}, ok bool,
+) {
 	if tr.root == nil {
-		return
+		return 0, nil, false
 	}
 	return tr.root.getOrNearest(key, tr.height)
 }
 
-func (n *node) getOrNearest(key int64, height int) (nKey int64, nValue interface{}) {
+func (n *node) getOrNearest(key int64, height int) (
+	nKey int64, nValue interface{}, ok bool,
+) {
 	i, found := n.find(key)
 	if found {
-		return n.items[i].key, n.items[i].value
+		return n.keys[i], n.vals[i].val(), true
 	}
-
-	if height == 0 {
-		//fmt.Printf("index: %d, items: %v\n", i, n.items)
-		if i > 0 {
-			return n.items[i-1].key, n.items[i-1].value
+	if height > 0 {
+		nKey, nValue, ok = n.children[i].getOrNearest(key, height-1)
+		if ok {
+			return nKey, nValue, true
 		}
 	}
-
-	// если дошли до последней родительской ноды, то:
-	// 1. проверим, есть ли элемент в последней дочерней ноде, если есть, то вернем его
-	// 2. если в дочерней ноде элемента нет, но c.find вернул ci > 0, это значит что ближайший меньший элемент все таки находится в этой дочерней ноде и равен c.items[ci-1]
-	// 3. иначе ближайший меньший элемент находится в родительской ноде и равен n.items[i-1]
-	if height == 1 {
-		c := n.children[i]
-		ci, found := c.find(key)
-		if found {
-			return c.items[ci].key, c.items[ci].value
-		}
-
-		//fmt.Printf("child index: %d, child items: %v\n", ci, c.items)
-		if ci > 0 {
-			return c.items[ci-1].key, c.items[ci-1].value
-		}
-
-		//fmt.Printf("index: %d, items: %v\n", i, n.items)
-		if i > 0 {
-			return n.items[i-1].key, n.items[i-1].value
-		}
-
-		return
+	// all keys of the child are greater, the nearest is left of it
+	if i > 0 {
+		return n.keys[i-1], n.vals[i-1].val(), true
 	}
-
-	return n.children[i].getOrNearest(key, height-1)
+	return 0, nil, false
 }
@@ -45,12 +45,12 @@ func (n *node) print(level, height int) {
 			n.children[i].print(level+1, height-1)
 		}
 		if height > 0 || (height == 0 && !flatLeaf) {
-			fmt.Printf("%s%v\n", strings.Repeat("  ", level), n.items[i].key)
+			fmt.Printf("%s%v\n", strings.Repeat("  ", level), n.keys[i])
 		} else {
 			if i > 0 {
 				fmt.Printf(",")
 			}
-			fmt.Printf("%d", n.items[i].key)
+			fmt.Printf("%d", n.keys[i])
 		}
 	}
 	if height == 0 && flatLeaf {
@@ -72,7 +72,7 @@ func (n *node) deepPrint(level, height int) {
 		return
 	}
 	fmt.Printf("%s count: %v\n", strings.Repeat("  ", level), n.numItems)
-	fmt.Printf("%s items: %v\n", strings.Repeat("  ", level), n.items)
+	fmt.Printf("%s keys: %v\n", strings.Repeat("  ", level), n.keys)
 	if height > 0 {
 		fmt.Printf("%s child: %v\n", strings.Repeat("  ", level), n.children)
 	}
@@ -84,6 +84,67 @@ func (n *node) deepPrint(level, height int) {
 	}
 }
 
+// sane checks the ordering of keys, the fill of nodes and the subtree counts
+func (tr *BTree) sane() error {
+	if tr.root == nil {
+		if tr.length != 0 || tr.height != 0 {
+			return fmt.Errorf("empty tree with length %d and height %d",
+				tr.length, tr.height)
+		}
+		return nil
+	}
+	count, err := tr.root.sane(tr.height, true)
+	if err != nil {
+		return err
+	}
+	if count != tr.length {
+		return fmt.Errorf("expected length %d, got %d", count, tr.length)
+	}
+	if min := tr.root.at(0, tr.height).key; min != tr.min {
+		return fmt.Errorf("expected min %d, got %d", min, tr.min)
+	}
+	if max := tr.root.at(tr.length-1, tr.height).key; max != tr.max {
+		return fmt.Errorf("expected max %d, got %d", max, tr.max)
+	}
+	var last int64
+	var i int
+	tr.Scan(func(key int64, value interface{}) bool {
+		if i > 0 && key < last {
+			err = fmt.Errorf("out of order %d after %d", key, last)
+			return false
+		}
+		if tr.root.at(i, tr.height).key != key {
+			err = fmt.Errorf("wrong item at index %d", i)
+			return false
+		}
+		last = key
+		i++
+		return true
+	})
+	return err
+}
+
+func (n *node) sane(height int, root bool) (count int, err error) {
+	if n.numItems == 0 || n.numItems >= maxItems ||
+		(!root && n.numItems < minItems) {
+		return 0, fmt.Errorf("node has %d items", n.numItems)
+	}
+	count = n.numItems
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			c, err := n.children[i].sane(height-1, false)
+			if err != nil {
+				return 0, err
+			}
+			count += c
+		}
+	}
+	if count != n.count {
+		return 0, fmt.Errorf("expected count %d, got %d", count, n.count)
+	}
+	return count, nil
+}
+
 func intsEquals(a, b []int64) bool {
 	if len(a) != len(b) {
 		return false
@@ -611,6 +672,9 @@ func testBTreeRandom(t *testing.T, r *rand.Rand, keys []int, count *uint32) {
 			t.Fatalf("expected nil")
 		}
 	}
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
 	shuffle(r, keys)
 	for i := 0; i < len(keys); i++ {
 		prev, ok := tr.Get(int64(keys[i]))
@@ -628,6 +692,11 @@ func testBTreeRandom(t *testing.T, r *rand.Rand, keys []int, count *uint32) {
 		if ok || prev != nil {
 			t.Fatalf("expected nil")
 		}
+		if i%100 == 0 {
+			if err := tr.sane(); err != nil {
+				t.Fatal(err)
+			}
+		}
 	}
 	atomic.AddUint32(count, 1)
 }
@@ -682,6 +751,51 @@ func TestBTreeScan(t *testing.T) {
 	assert.Equal(t, er, a)
 }
 
+func TestBTreeScanFrom(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Multi: multi})
+		const N = 3000
+		for _, i := range rand.Perm(N) {
+			tr.Set(int64(i/3), i)
+		}
+		var all []interface{}
+		tr.Scan(func(key int64, value interface{}) bool {
+			all = append(all, value)
+			return true
+		})
+		for _, offset := range []int{-5, 0, 1, 30, 31, 32, 500,
+			len(all) - 1, len(all), len(all) + 10} {
+			var values []interface{}
+			tr.ScanFrom(offset, func(key int64, value interface{}) bool {
+				values = append(values, value)
+				return true
+			})
+			from := offset
+			if from < 0 {
+				from = 0
+			}
+			if from > len(all) {
+				from = len(all)
+			}
+			if len(values) != len(all)-from {
+				t.Fatalf("offset %d: expected %d items, got %d",
+					offset, len(all)-from, len(values))
+			}
+			for i, value := range values {
+				if value != all[from+i] {
+					t.Fatalf("offset %d: wrong item at %d", offset, i)
+				}
+			}
+		}
+		var n int
+		tr.ScanFrom(100, func(key int64, value interface{}) bool {
+			n++
+			return n < 10
+		})
+		assert.Equal(t, 10, n)
+	}
+}
+
 func TestBTreeNearest(t *testing.T) {
 
 	var tree BTree
@@ -700,10 +814,28 @@ func TestBTreeNearest(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run("", func(t *testing.T) {
-			k, _ := tree.GetOrNearest(tt.key)
+			k, _, ok := tree.GetOrNearest(tt.key)
+			assert.Equal(t, true, ok)
 			assert.Equal(t, tt.near, k)
 		})
 	}
+	_, _, ok := tree.GetOrNearest(-1)
+	assert.Equal(t, false, ok)
+	var empty BTree
+	_, _, ok = empty.GetOrNearest(0)
+	assert.Equal(t, false, ok)
+
+	// a nearest key in a branch above the leaf of the key
+	tree = BTree{}
+	for i := int64(0); i < 100000; i += 2 {
+		tree.Set(i, nil)
+	}
+	for i := int64(1); i < 100000; i += 2 {
+		k, _, ok := tree.GetOrNearest(i)
+		if !ok || k != i-1 {
+			t.Fatalf("%d: expected %d, got %d", i, i-1, k)
+		}
+	}
 }
 
 func TestBTreeNearest2(t *testing.T) {
@@ -716,7 +848,7 @@ func TestBTreeNearest2(t *testing.T) {
 	tree.Set(40, "x")
 	tree.Set(50, "x")
 
-	key, value := tree.GetOrNearest(25)
+	key, value, _ := tree.GetOrNearest(25)
 	fmt.Printf("near: %v = %v\n", key, value)
 
 	nKey, nValue := tree.Next(key)
@@ -734,7 +866,7 @@ func TestBTreePrev(t *testing.T) {
 	assert.Equal(t, 10000000, tree.Len())
 
 	var i int64 = 5000000
-	key, _ := tree.GetOrNearest(i)
+	key, _, _ := tree.GetOrNearest(i)
 	tree.LessOrEqual(key, func(k int64, v interface{}) bool {
 		if k != i {
 			t.Fatalf("mismatch, key: %d, i: %d", k, i)
@@ -752,7 +884,7 @@ func TestBTreeNext(t *testing.T) {
 	assert.Equal(t, 10000000, tree.Len())
 
 	var i int64 = 5000000
-	key, _ := tree.GetOrNearest(i)
+	key, _, _ := tree.GetOrNearest(i)
 	tree.GreaterOrEqual(key, func(k int64, v interface{}) bool {
 		if k != i {
 			t.Fatalf("mismatch, key: %d, i: %d", k, i)
@@ -827,3 +959,209 @@ func BenchmarkBTreeIterate(b *testing.B) {
 		})
 	}
 }
+
+func TestBTreeMulti(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	const N, D = 1000, 5
+	set := make(map[int64][]interface{}) // the values in insertion order
+	for _, i := range rand.Perm(N * D) {
+		prev, replaced := tr.Set(int64(i%N), i)
+		if replaced || prev != nil {
+			t.Fatal("expected nil")
+		}
+		set[int64(i%N)] = append(set[int64(i%N)], i)
+	}
+	assert.Equal(t, N*D, tr.Len())
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+
+	// duplicates keep the order they were set in
+	scanned := make(map[int64][]interface{})
+	var lastKey int64 = -1
+	tr.Scan(func(key int64, value interface{}) bool {
+		if key < lastKey {
+			t.Fatal("out of order", key, lastKey)
+		}
+		lastKey = key
+		scanned[key] = append(scanned[key], value)
+		return true
+	})
+	assert.Equal(t, set, scanned)
+
+	for i := int64(0); i < N; i++ {
+		all := tr.GetAll(i)
+		assert.Equal(t, set[i], all)
+		first, ok := tr.Get(i)
+		if !ok || first != all[0] {
+			t.Fatalf("expected '%v', got '%v'", all[0], first)
+		}
+		var asc, desc int
+		tr.Ascend(i, func(key int64, value interface{}) bool {
+			if key == i {
+				if value != set[i][asc] {
+					t.Fatalf("ascend %d: expected '%v', got '%v'", i,
+						set[i][asc], value)
+				}
+				asc++
+			}
+			return key == i
+		})
+		tr.Descend(i, func(key int64, value interface{}) bool {
+			if key == i {
+				desc++
+			}
+			return key == i
+		})
+		if asc != D || desc != D {
+			t.Fatalf("expected %d, got %d and %d", D, asc, desc)
+		}
+	}
+
+	// DeleteOne removes the values in the order they were set
+	for i := int64(0); i < N; i += 2 {
+		all := tr.GetAll(i)
+		for j := 0; j < 2; j++ {
+			prev, deleted := tr.DeleteOne(i)
+			if !deleted || prev != all[j] {
+				t.Fatalf("expected '%v', got '%v'", all[j], prev)
+			}
+		}
+		assert.Equal(t, all[2:], tr.GetAll(i))
+	}
+	assert.Equal(t, N*D-N, tr.Len())
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete removes all remaining values
+	for i := int64(0); i < N; i++ {
+		first, _ := tr.Get(i)
+		prev, deleted := tr.Delete(i)
+		if !deleted || prev != first {
+			t.Fatalf("expected '%v', got '%v'", first, prev)
+		}
+		if _, ok := tr.Get(i); ok {
+			t.Fatal("expected false")
+		}
+	}
+	assert.Equal(t, 0, tr.Len())
+}
+
+func TestBTreeDeleteFn(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Multi: multi})
+		for i := 0; i < 100; i++ {
+			tr.Set(int64(i%50), i)
+		}
+		var deleted []interface{}
+		onDelete := func(value interface{}) {
+			deleted = append(deleted, value)
+		}
+		if !tr.DeleteFn(10, onDelete) {
+			t.Fatal("expected true")
+		}
+		if tr.DeleteFn(10, onDelete) {
+			t.Fatal("expected false")
+		}
+		if multi {
+			assert.Equal(t, []interface{}{10, 60}, deleted)
+			assert.Equal(t, 98, tr.Len())
+		} else {
+			assert.Equal(t, []interface{}{60}, deleted)
+			assert.Equal(t, 49, tr.Len())
+		}
+	}
+}
+
+func TestBTreeDeleteItem(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	tr.Set(5, "a")
+	tr.Set(5, "b")
+	removed, ok := tr.DeleteItem(5)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, Item{5, "a"}, removed)
+	removed, ok = tr.DeleteItem(5)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, Item{5, "b"}, removed)
+	removed, ok = tr.DeleteItem(5)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, Item{}, removed)
+}
+
+func BenchmarkBTreeAscend(b *testing.B) {
+
+	var tree BTree
+	for i := 1; i <= 10000000; i++ {
+		tree.Set(int64(i), i)
+	}
+	assert.Equal(b, 10000000, tree.Len())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var count int
+		tree.Ascend(int64(n%10000000), func(key int64, value interface{}) bool {
+			count++
+			return count < 100
+		})
+	}
+}
+
+func BenchmarkBTreeDescend(b *testing.B) {
+
+	var tree BTree
+	for i := 1; i <= 10000000; i++ {
+		tree.Set(int64(i), i)
+	}
+	assert.Equal(b, 10000000, tree.Len())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var count int
+		tree.Descend(int64(n%10000000), func(key int64, value interface{}) bool {
+			count++
+			return count < 100
+		})
+	}
+}
+
+func TestBTreeMinMax(t *testing.T) {
+	var tr BTree
+	_, ok := tr.Min()
+	assert.Equal(t, false, ok)
+	for _, i := range rand.Perm(1000) {
+		tr.Set(int64(i), i)
+	}
+	min, ok := tr.Min()
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(0), min)
+	max, _ := tr.Max()
+	assert.Equal(t, int64(999), max)
+	tr.Delete(0)
+	tr.Delete(999)
+	min, _ = tr.Min()
+	max, _ = tr.Max()
+	assert.Equal(t, int64(1), min)
+	assert.Equal(t, int64(998), max)
+	tr.TrimBelow(500)
+	min, _ = tr.Min()
+	assert.Equal(t, int64(500), min)
+
+	// Ascend and Descend from beyond the bounds visit all items
+	var keys []int64
+	tr.Ascend(-10, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, 499, len(keys))
+	keys = keys[:0]
+	tr.Descend(2000, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	assert.Equal(t, []int64{998, 997}, keys)
+}
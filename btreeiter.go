@@ -0,0 +1,192 @@
+package tinybtree
+
+import "math"
+
+// Iter is a position in a BTree that moves in both directions. It keeps
+// the path from the root to the current item, so a step only goes up or
+// down as many levels as it needs to, which is one level on average, and
+// never searches the tree again. It must not be used after items were
+// inserted into or removed from the tree, which makes it panic with
+// ErrConcurrentModification. Values that were replaced may or may not be
+// seen.
+type Iter struct {
+	tr *BTree
+	// the path to the current item, empty when the iterator is before the
+	// first or past the last item. For the node on top, index is the
+	// position of the current item, for the others it's the child that
+	// was taken.
+	stack []cursorFrame
+	past  bool   // past the last item rather than before the first one
+	mods  uint64 // modification counter of the tree at the start
+}
+
+// IterAt returns an iterator at the first item with a key greater or equal
+// to key. It's past the last item when there is none.
+func (tr *BTree) IterAt(key int64) *Iter {
+	it := &Iter{tr: tr, stack: make([]cursorFrame, 0, tr.height+1),
+		mods: tr.mods}
+	if tr.root == nil {
+		it.past = true
+		return it
+	}
+	n := tr.root
+	for h := tr.height; ; h-- {
+		i, _ := n.findFirst(key)
+		it.stack = append(it.stack, cursorFrame{n, i})
+		if h == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	it.ascend()
+	return it
+}
+
+// check panics when the tree was modified
+func (it *Iter) check() {
+	if it.mods != it.tr.mods {
+		panic(ErrConcurrentModification)
+	}
+}
+
+// height returns the height of the node on top of the stack
+func (it *Iter) height() int {
+	return it.tr.height - (len(it.stack) - 1)
+}
+
+// ascend pops the nodes that have no items left after the taken child or
+// the current position, stopping at the following item
+func (it *Iter) ascend() bool {
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.index < top.n.numItems {
+			return true
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	it.past = true
+	return false
+}
+
+// descend pops the nodes that have no items left before the taken child or
+// the current position, stopping at the preceding item
+func (it *Iter) descend() bool {
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.index > 0 {
+			top.index--
+			return true
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	it.past = false
+	return false
+}
+
+// leftmost pushes the path to the first item of the subtree
+func (it *Iter) leftmost(n *node, height int) {
+	for ; height > 0; height-- {
+		it.stack = append(it.stack, cursorFrame{n, 0})
+		n = n.children[0]
+	}
+	it.stack = append(it.stack, cursorFrame{n, 0})
+}
+
+// rightmost pushes the path to the last item of the subtree
+func (it *Iter) rightmost(n *node, height int) {
+	for ; height > 0; height-- {
+		it.stack = append(it.stack, cursorFrame{n, n.numItems})
+		n = n.children[n.numItems]
+	}
+	it.stack = append(it.stack, cursorFrame{n, n.numItems - 1})
+}
+
+// Valid returns true when the iterator is at an item
+func (it *Iter) Valid() bool {
+	return len(it.stack) > 0
+}
+
+// Next moves to the following item. Returns false when it moved past the
+// last item, from where Prev returns to the last item.
+func (it *Iter) Next() bool {
+	it.check()
+	if len(it.stack) == 0 {
+		if it.past || it.tr.root == nil {
+			return false
+		}
+		it.leftmost(it.tr.root, it.tr.height)
+		return true
+	}
+	top := &it.stack[len(it.stack)-1]
+	top.index++
+	if height := it.height(); height > 0 {
+		// move from the branch item into the child right of it
+		it.leftmost(top.n.children[top.index], height-1)
+		return true
+	}
+	return it.ascend()
+}
+
+// Prev moves to the preceding item. Returns false when it moved before the
+// first item, from where Next returns to the first item.
+func (it *Iter) Prev() bool {
+	it.check()
+	if len(it.stack) == 0 {
+		if !it.past || it.tr.root == nil {
+			return false
+		}
+		it.rightmost(it.tr.root, it.tr.height)
+		return true
+	}
+	top := &it.stack[len(it.stack)-1]
+	if height := it.height(); height > 0 {
+		// move from the branch item into the child left of it
+		it.rightmost(top.n.children[top.index], height-1)
+		return true
+	}
+	return it.descend()
+}
+
+// Key returns the key of the current item
+func (it *Iter) Key() int64 {
+	it.check()
+	top := it.stack[len(it.stack)-1]
+	return top.n.keys[top.index]
+}
+
+// Value returns the value of the current item
+func (it *Iter) Value() interface{} {
+	it.check()
+	top := it.stack[len(it.stack)-1]
+	return top.n.vals[top.index].val()
+}
+
+// ScanBidirectional visits the items from both ends at once, alternating
+// between fromLow, which gets the items in ascending order, and fromHigh,
+// which gets them in descending order, until the two meet. Every item is
+// visited by one of them. When one returns false the other carries on
+// alone, and the scan stops when both did. The tree must not be modified
+// during the scan.
+func (tr *BTree) ScanBidirectional(
+	fromLow, fromHigh func(key int64, value interface{}) bool,
+) {
+	lo := tr.IterAt(math.MinInt64)
+	hi := &Iter{tr: tr, stack: make([]cursorFrame, 0, tr.height+1),
+		past: true, mods: tr.mods}
+	hi.Prev()
+	lowOK, highOK := true, true
+	for left := tr.length; left > 0 && (lowOK || highOK); {
+		if lowOK {
+			lowOK = fromLow(lo.Key(), lo.Value())
+			lo.Next()
+			if left--; left == 0 {
+				break
+			}
+		}
+		if highOK {
+			highOK = fromHigh(hi.Key(), hi.Value())
+			hi.Prev()
+			left--
+		}
+	}
+}
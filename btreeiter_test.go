@@ -0,0 +1,142 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIter(t *testing.T) {
+	var tr BTree
+	it := tr.IterAt(0)
+	assert.Equal(t, false, it.Valid())
+	assert.Equal(t, false, it.Next())
+	assert.Equal(t, false, it.Prev())
+
+	const N = 10000
+	for _, i := range rand.Perm(N) {
+		tr.Set(int64(i*2), i)
+	}
+	for _, pivot := range []int64{math.MinInt64, 0, 1, 999, 1000, N*2 - 2} {
+		it := tr.IterAt(pivot)
+		expect := (pivot + 1) / 2 * 2
+		if pivot < 0 {
+			expect = 0
+		}
+		if !it.Valid() || it.Key() != expect {
+			t.Fatalf("%d: expected %d", pivot, expect)
+		}
+		assert.Equal(t, int(expect/2), it.Value())
+	}
+	it = tr.IterAt(N * 2)
+	assert.Equal(t, false, it.Valid())
+	assert.Equal(t, true, it.Prev())
+	assert.Equal(t, int64(N*2-2), it.Key())
+
+	// walk a random path, changing direction along the way
+	it = tr.IterAt(N)
+	pos := N / 2
+	for i := 0; i < 100000; i++ {
+		if rand.Intn(2) == 0 {
+			ok := it.Next()
+			if pos < N {
+				pos++
+			}
+			assert.Equal(t, pos < N, ok)
+		} else {
+			ok := it.Prev()
+			if pos >= 0 {
+				pos--
+			}
+			assert.Equal(t, pos >= 0, ok)
+		}
+		if pos >= 0 && pos < N {
+			if !it.Valid() || it.Key() != int64(pos*2) {
+				t.Fatalf("expected %d", pos*2)
+			}
+		} else {
+			assert.Equal(t, false, it.Valid())
+		}
+	}
+
+	// the ends
+	it = tr.IterAt(math.MinInt64)
+	assert.Equal(t, false, it.Prev())
+	assert.Equal(t, false, it.Prev())
+	assert.Equal(t, true, it.Next())
+	assert.Equal(t, int64(0), it.Key())
+}
+
+func TestIterMulti(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i%10), i)
+	}
+	it := tr.IterAt(5)
+	var n int
+	for ; it.Valid() && it.Key() == 5; it.Next() {
+		n++
+	}
+	assert.Equal(t, 100, n)
+	it = tr.IterAt(5)
+	assert.Equal(t, true, it.Prev())
+	assert.Equal(t, int64(4), it.Key())
+}
+
+func TestScanBidirectional(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 7, 1000} {
+		tr := New(&Options{Multi: true})
+		for i := 0; i < n; i++ {
+			tr.Set(int64(i/2), i)
+		}
+		var low, high []int
+		tr.ScanBidirectional(func(key int64, value interface{}) bool {
+			low = append(low, value.(int))
+			return true
+		}, func(key int64, value interface{}) bool {
+			high = append(high, value.(int))
+			return true
+		})
+		assert.Equal(t, (n+1)/2, len(low))
+		assert.Equal(t, n/2, len(high))
+		for i, v := range low {
+			assert.Equal(t, i, v)
+		}
+		for i, v := range high {
+			assert.Equal(t, n-1-i, v)
+		}
+	}
+
+	// the high side carries on alone once the low side stopped
+	var tr BTree
+	for i := 0; i < 10; i++ {
+		tr.Set(int64(i), i)
+	}
+	var keys []int64
+	tr.ScanBidirectional(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return key < 1
+	}, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return key > 3
+	})
+	assert.Equal(t, []int64{0, 9, 1, 8, 7, 6, 5, 4, 3}, keys)
+}
+
+func TestIterConcurrentModification(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	it := tr.IterAt(50)
+	tr.Set(50, "replaced")
+	assert.Equal(t, true, it.Next())
+	tr.Delete(10)
+	defer func() {
+		assert.Equal(t, ErrConcurrentModification, recover())
+	}()
+	it.Next()
+	t.Fatal("expected a panic")
+}
@@ -0,0 +1,124 @@
+package tinybtree
+
+import "fmt"
+
+// Builder constructs a tree from items that are added in key order, which
+// is much faster than setting them one by one. The items are packed into
+// full nodes that are linked bottom up once all items are there, without
+// searching or rebalancing. Add doesn't check anything, Build verifies the
+// tree at the end.
+type Builder struct {
+	opts  *Options
+	items []item
+}
+
+// NewBuilder returns a Builder of a tree with the provided options
+func NewBuilder(opts *Options) *Builder {
+	return &Builder{opts: opts}
+}
+
+// Add appends an item. The keys must be added in ascending order, in multi
+// mode they may repeat.
+func (b *Builder) Add(key int64, value interface{}) {
+	b.items = append(b.items, item{key: key, slot: slot{value: value}})
+}
+
+// Build returns the tree of the added items and resets the builder. It
+// panics with the error of Verify when the keys were not added in order.
+func (b *Builder) Build() *BTree {
+	tr := New(b.opts)
+	items := b.items
+	b.items = nil
+	span := tr.span("Build")
+	span.SetAttribute("tinybtree.items", int64(len(items)))
+	defer span.End(nil)
+	if tr.stampMode != NoStamp {
+		for i := range items {
+			items[i].slot = tr.stamp(items[i].slot)
+		}
+	}
+	tr.load(items)
+	if err := tr.Verify(); err != nil {
+		panic(err)
+	}
+	if tr.ret != nil {
+		for _, it := range items {
+			tr.ret.Retain(it.val())
+		}
+	}
+	if tr.bloom != nil {
+		tr.rebuildBloom()
+	}
+	return tr
+}
+
+// Verify checks the structure of the tree: the keys are in order, the nodes
+// hold as many items as they should and the counts of the subtrees and the
+// bounds of the tree add up.
+func (tr *BTree) Verify() error {
+	if tr.root == nil {
+		if tr.length != 0 || tr.height != 0 {
+			return fmt.Errorf("tinybtree: empty tree with length %d and "+
+				"height %d", tr.length, tr.height)
+		}
+		return nil
+	}
+	v := verifier{multi: tr.multi}
+	count, err := tr.root.verify(&v, tr.height, true)
+	if err != nil {
+		return err
+	}
+	if count != tr.length {
+		return fmt.Errorf("tinybtree: length %d, but %d items", tr.length,
+			count)
+	}
+	if tr.root.at(0, tr.height).key != tr.min ||
+		tr.root.at(count-1, tr.height).key != tr.max {
+		return fmt.Errorf("tinybtree: wrong min %d or max %d", tr.min,
+			tr.max)
+	}
+	return nil
+}
+
+// verifier carries the last key through Verify
+type verifier struct {
+	multi bool
+	last  int64
+	seen  bool // an item was checked
+}
+
+func (v *verifier) check(key int64) error {
+	if v.seen && (key < v.last || key == v.last && !v.multi) {
+		return fmt.Errorf("tinybtree: key %d out of order after %d", key,
+			v.last)
+	}
+	v.last, v.seen = key, true
+	return nil
+}
+
+func (n *node) verify(v *verifier, height int, root bool) (int, error) {
+	if n.numItems == 0 || n.numItems >= maxItems ||
+		(!root && n.numItems < minItems) {
+		return 0, fmt.Errorf("tinybtree: node with %d items", n.numItems)
+	}
+	count := n.numItems
+	for i := 0; i <= n.numItems; i++ {
+		if height > 0 {
+			c, err := n.children[i].verify(v, height-1, false)
+			if err != nil {
+				return 0, err
+			}
+			count += c
+		}
+		if i < n.numItems {
+			if err := v.check(n.keys[i]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if count != n.count {
+		return 0, fmt.Errorf("tinybtree: node count %d, but %d items",
+			n.count, count)
+	}
+	return count, nil
+}
@@ -0,0 +1,72 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	for _, n := range []int{0, 1, maxItems - 1, maxItems, 1000, 100000} {
+		b := NewBuilder(&Options{Aggregator: sumAggregator{}})
+		for i := 0; i < n; i++ {
+			b.Add(int64(i*2), i)
+		}
+		tr := b.Build()
+		if err := tr.sane(); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, n, tr.Len())
+		v, ok := tr.Get(int64(n / 2 * 2))
+		assert.Equal(t, n > 0, ok)
+		if ok {
+			assert.Equal(t, n/2, v)
+			assert.Equal(t, n*(n-1)/2, tr.Aggregate())
+		}
+		// the tree takes writes as usual
+		tr.Set(1, -1)
+		tr.Delete(0)
+		if err := tr.Verify(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := NewBuilder(&Options{Multi: true})
+	b.Add(1, "a")
+	b.Add(1, "b")
+	assert.Equal(t, []interface{}{"a", "b"}, b.Build().GetAll(1))
+
+	// out of order, and duplicates outside of multi mode
+	for _, keys := range [][]int64{{2, 1}, {1, 1}} {
+		b = NewBuilder(nil)
+		for _, key := range keys {
+			b.Add(key, nil)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%v: expected a panic", keys)
+				}
+			}()
+			b.Build()
+		}()
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, nil, tr.Verify())
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i), i)
+	}
+	assert.Equal(t, nil, tr.Verify())
+	tr.root.count++
+	if tr.Verify() == nil {
+		t.Fatal("expected a wrong count")
+	}
+	tr.root.count--
+	tr.root.keys[0], tr.root.keys[1] = tr.root.keys[1], tr.root.keys[0]
+	if tr.Verify() == nil {
+		t.Fatal("expected keys out of order")
+	}
+}
@@ -0,0 +1,68 @@
+package tinybtree
+
+// Backing is a slower store behind a tree that's used as a cache, see
+// NewCache
+type Backing interface {
+	// Load returns the value of a key that's not in the tree, ok is false
+	// when the store doesn't have it either
+	Load(key int64) (value interface{}, ok bool, err error)
+	// Store writes the value of a key before it's set in the tree
+	Store(key int64, value interface{}) error
+	// Remove deletes a key before it's deleted from the tree
+	Remove(key int64) error
+}
+
+// Cache is an ordered cache in front of a Backing. Get loads the keys that
+// are missing from the tree, Set and Delete write through to the backing
+// store. The tree changes only after the store succeeded.
+type Cache struct {
+	tr       *BTree
+	b        Backing
+	populate bool
+}
+
+// NewCache returns a cache of b using the tree. When populate is set the
+// values loaded by Get are set in the tree, otherwise the tree only holds
+// the values that were written through the cache.
+func NewCache(tr *BTree, b Backing, populate bool) *Cache {
+	return &Cache{tr: tr, b: b, populate: populate}
+}
+
+// Tree returns the tree of the cache, such as for scanning the cached keys
+func (c *Cache) Tree() *BTree {
+	return c.tr
+}
+
+// Get a value for key from the tree, or from the backing store when the
+// key is not in the tree
+func (c *Cache) Get(key int64) (value interface{}, gotten bool, err error) {
+	if value, ok := c.tr.Get(key); ok {
+		return value, true, nil
+	}
+	value, gotten, err = c.b.Load(key)
+	if err != nil || !gotten {
+		return nil, false, err
+	}
+	if c.populate {
+		c.tr.Set(key, value)
+	}
+	return value, true, nil
+}
+
+// Set a value for key in the backing store and then in the tree
+func (c *Cache) Set(key int64, value interface{}) error {
+	if err := c.b.Store(key, value); err != nil {
+		return err
+	}
+	c.tr.Set(key, value)
+	return nil
+}
+
+// Delete a key from the backing store and then from the tree
+func (c *Cache) Delete(key int64) error {
+	if err := c.b.Remove(key); err != nil {
+		return err
+	}
+	c.tr.Delete(key)
+	return nil
+}
@@ -0,0 +1,82 @@
+package tinybtree
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapBacking is a Backing that fails for negative keys
+type mapBacking struct {
+	m     map[int64]interface{}
+	loads int
+}
+
+var errNegative = errors.New("negative key")
+
+func (b *mapBacking) Load(key int64) (interface{}, bool, error) {
+	if key < 0 {
+		return nil, false, errNegative
+	}
+	b.loads++
+	value, ok := b.m[key]
+	return value, ok, nil
+}
+
+func (b *mapBacking) Store(key int64, value interface{}) error {
+	if key < 0 {
+		return errNegative
+	}
+	b.m[key] = value
+	return nil
+}
+
+func (b *mapBacking) Remove(key int64) error {
+	if key < 0 {
+		return errNegative
+	}
+	delete(b.m, key)
+	return nil
+}
+
+func TestCache(t *testing.T) {
+	for _, populate := range []bool{false, true} {
+		b := &mapBacking{m: map[int64]interface{}{1: "a", 2: "b"}}
+		c := NewCache(new(BTree), b, populate)
+		for i := 0; i < 2; i++ {
+			value, ok, err := c.Get(1)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, true, ok)
+			assert.Equal(t, "a", value)
+		}
+		if populate {
+			assert.Equal(t, 1, b.loads)
+			assert.Equal(t, 1, c.Tree().Len())
+		} else {
+			assert.Equal(t, 2, b.loads)
+			assert.Equal(t, 0, c.Tree().Len())
+		}
+		_, ok, err := c.Get(3)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, false, ok)
+		_, _, err = c.Get(-1)
+		assert.Equal(t, errNegative, err)
+
+		// writes go through to the backing store
+		assert.Equal(t, nil, c.Set(3, "c"))
+		assert.Equal(t, "c", b.m[3])
+		value, _ := c.Tree().Get(3)
+		assert.Equal(t, "c", value)
+		assert.Equal(t, nil, c.Delete(1))
+		_, ok = b.m[1]
+		assert.Equal(t, false, ok)
+		_, ok = c.Tree().Get(1)
+		assert.Equal(t, false, ok)
+
+		// the tree is unchanged when the store fails
+		assert.Equal(t, errNegative, c.Set(-1, "x"))
+		_, ok = c.Tree().Get(-1)
+		assert.Equal(t, false, ok)
+	}
+}
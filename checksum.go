@@ -0,0 +1,82 @@
+package tinybtree
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// fnvUint64 adds the bytes of x to the FNV-1a hash h
+func fnvUint64(h, x uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h = (h ^ (x & 0xff)) * fnvPrime
+		x >>= 8
+	}
+	return h
+}
+
+func fnvString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h = (h ^ uint64(s[i])) * fnvPrime
+	}
+	return h
+}
+
+// Checksum returns a hash of the keys and values of the tree in order, so
+// that two trees holding the same items, such as two replicas, have the
+// same checksum. The values are hashed with Options.ValueHash, or else by
+// HashValue. It takes O(n) time.
+func (tr *BTree) Checksum() uint64 {
+	hash := tr.valueHash
+	if hash == nil {
+		hash = HashValue
+	}
+	h := uint64(fnvOffset)
+	tr.Scan(func(key int64, value interface{}) bool {
+		h = fnvUint64(fnvUint64(h, uint64(key)), hash(value))
+		return true
+	})
+	return h
+}
+
+// HashValue is the default hash of the values for Checksum. Strings, byte
+// slices, booleans and numbers are hashed by their contents and type, which
+// is the same on every machine, other values by their %v formatting.
+func HashValue(value interface{}) uint64 {
+	h := uint64(fnvOffset)
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return fnvString(fnvUint64(h, 1), v)
+	case []byte:
+		return fnvString(fnvUint64(h, 2), string(v))
+	case bool:
+		if v {
+			return fnvUint64(h, 3)
+		}
+		return fnvUint64(h, 4)
+	case int:
+		return fnvUint64(fnvUint64(h, 5), uint64(v))
+	case int64:
+		return fnvUint64(fnvUint64(h, 6), uint64(v))
+	case int32:
+		return fnvUint64(fnvUint64(h, 7), uint64(v))
+	case uint:
+		return fnvUint64(fnvUint64(h, 8), uint64(v))
+	case uint64:
+		return fnvUint64(fnvUint64(h, 9), v)
+	case uint32:
+		return fnvUint64(fnvUint64(h, 10), uint64(v))
+	case float64:
+		return fnvUint64(fnvUint64(h, 11), math.Float64bits(v))
+	case float32:
+		return fnvUint64(fnvUint64(h, 12), uint64(math.Float32bits(v)))
+	default:
+		return fnvString(fnvUint64(h, 13), fmt.Sprintf("%T %v", v, v))
+	}
+}
@@ -0,0 +1,67 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	var a, b BTree
+	assert.Equal(t, a.Checksum(), b.Checksum())
+	for _, i := range rand.Perm(1000) {
+		a.Set(int64(i), i)
+	}
+	for i := 999; i >= 0; i-- {
+		b.Set(int64(i), i)
+	}
+	assert.Equal(t, a.Checksum(), b.Checksum())
+
+	sum := a.Checksum()
+	b.Set(500, 501)
+	if b.Checksum() == sum {
+		t.Fatal("expected the value to change the checksum")
+	}
+	b.Set(500, int64(500))
+	if b.Checksum() == sum {
+		t.Fatal("expected the type to change the checksum")
+	}
+	b.Set(500, 500)
+	assert.Equal(t, sum, b.Checksum())
+	b.Delete(500)
+	b.Set(1000, 500)
+	if b.Checksum() == sum {
+		t.Fatal("expected the key to change the checksum")
+	}
+
+	// the values are hashed by ValueHash
+	c := New(&Options{ValueHash: func(value interface{}) uint64 {
+		return 0
+	}})
+	d := New(&Options{ValueHash: func(value interface{}) uint64 {
+		return 0
+	}})
+	c.Set(1, "a")
+	d.Set(1, "b")
+	assert.Equal(t, c.Checksum(), d.Checksum())
+}
+
+func TestHashValue(t *testing.T) {
+	values := []interface{}{nil, "a", []byte("a"), true, false, 1, int64(1),
+		int32(1), uint(1), uint64(1), uint32(1), 1.0, float32(1),
+		struct{ A int }{1}}
+	seen := map[uint64]interface{}{}
+	for _, v := range values {
+		h := HashValue(v)
+		if prev, ok := seen[h]; ok {
+			t.Fatalf("%#v and %#v have the same hash", prev, v)
+		}
+		seen[h] = v
+		assert.Equal(t, h, HashValue(v))
+	}
+	// the hashes don't change between versions, so that replicas agree
+	assert.Equal(t, uint64(0), HashValue(nil))
+	assert.Equal(t, uint64(5952155467226901439), HashValue("a"))
+	assert.Equal(t, uint64(2027879709643453761), HashValue(1))
+}
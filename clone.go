@@ -0,0 +1,167 @@
+package tinybtree
+
+import "sync/atomic"
+
+// lastGen is the last generation handed out to a tree, see BTree.gen
+var lastGen uint64
+
+// Clone returns a copy of the tree in O(1) time. The copy shares its nodes
+// with the tree until either of them writes to a node, at which point the
+// writer copies the node and the nodes on the path to it.
+//
+// The two trees are isolated from each other: nothing written to one of
+// them is ever observed through the other, including by an iteration that
+// is in progress. The tree and its clone may be used from different
+// goroutines without locking between them, such as a report that iterates
+// over a clone while the tree keeps taking writes. Each of them on its own
+// still needs the usual synchronization.
+//
+// The clone has the options of the tree but no subscribers or access
+// statistics, and it isn't frozen. When the tree has a Retainer, Clone
+// retains every value once more for the clone, which takes O(n) time. The
+// shared nodes are never passed to an Allocator, as neither tree knows when
+// the other one is done with them.
+func (tr *BTree) Clone() *BTree {
+	c := &BTree{
+		height:    tr.height,
+		root:      tr.root,
+		length:    tr.length,
+		min:       tr.min,
+		max:       tr.max,
+		multi:     tr.multi,
+		agg:       tr.agg,
+		codec:     tr.codec,
+		ret:       tr.ret,
+		alloc:     tr.alloc,
+		stampMode: tr.stampMode,
+		version:   tr.version,
+		yield:     tr.yield,
+		valueHash: tr.valueHash,
+		nodeHook:  tr.nodeHook,
+		maxLen:    tr.maxLen,
+		evict:     tr.evict,
+		gate:      tr.gate,
+		pool:      tr.pool,
+		tracer:    tr.tracer,
+	}
+	if tr.bloom != nil {
+		c.bloom = &bloom{counts: append([]uint8(nil), tr.bloom.counts...),
+			max: tr.bloom.max}
+	}
+	// the nodes of either tree now belong to neither of them. A frozen tree
+	// never writes to its nodes again, so it's left unchanged, which keeps
+	// Clone safe to call from concurrent readers.
+	c.gen = nextGen()
+	if !tr.frozen {
+		tr.gen = nextGen()
+		// the values are shared too, so they must not go back into the pool
+		tr.recycling = false
+	}
+	if tr.ret != nil && tr.root != nil {
+		tr.root.each(func(key int64, value interface{}) {
+			tr.ret.Retain(value)
+		}, tr.height)
+	}
+	return c
+}
+
+func nextGen() uint64 {
+	return atomic.AddUint64(&lastGen, 1)
+}
+
+// owned returns true if the node belongs to the tree alone, so that it may
+// be written to in place
+func (tr *BTree) owned(n *node) bool {
+	return tr.gen != 0 && n.gen == tr.gen
+}
+
+// mut returns the node if the tree owns it, or else a copy of it that the
+// tree owns
+func (tr *BTree) mut(n *node) *node {
+	if tr.owned(n) {
+		return n
+	}
+	c := tr.newNode()
+	gen := c.gen
+	*c = *n
+	c.gen = gen
+	return c
+}
+
+// mutChild makes the child at index i writable, see mut
+func (n *node) mutChild(tr *BTree, i int) *node {
+	n.children[i] = tr.mut(n.children[i])
+	return n.children[i]
+}
+
+// adopt hands the nodes of the subtree that belong to tr over to dst
+func (n *node) adopt(tr, dst *BTree, height int) {
+	if !tr.owned(n) {
+		return
+	}
+	n.gen = dst.generation()
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			n.children[i].adopt(tr, dst, height-1)
+		}
+	}
+}
+
+// generation returns the generation of the tree, assigning one the first
+// time
+func (tr *BTree) generation() uint64 {
+	if tr.gen == 0 {
+		tr.gen = nextGen()
+	}
+	return tr.gen
+}
+
+// CopyRange returns a new tree with the items within the range [lo, hi],
+// such as for extracting the slice of one tenant from a shared index. The
+// new tree is built bottom up from the items in O(k) time after finding the
+// range in O(log n), and shares no nodes with the tree. It has the Multi,
+// Aggregator, KeyCodec and Tracer options of the tree, like Compact, and
+// the values are shared with the tree, so they no longer go back into its
+// Pool.
+func (tr *BTree) CopyRange(lo, hi int64) *BTree {
+	c := &BTree{multi: tr.multi, agg: tr.agg, codec: tr.codec,
+		tracer: tr.tracer}
+	if tr.root == nil || lo > hi {
+		return c
+	}
+	span := tr.span("CopyRange")
+	defer span.End(nil)
+	items := make([]item, 0, tr.countRange(lo, hi))
+	items, _ = tr.root.appendRange(items, lo, hi, tr.height)
+	if len(items) > 0 {
+		tr.recycling = false
+	}
+	c.load(items)
+	span.SetAttribute("tinybtree.items", int64(len(items)))
+	return c
+}
+
+// appendRange appends the items of the subtree within the range [lo, hi]
+// to items, returning false once it has seen a key past hi.
+func (n *node) appendRange(
+	items []item, lo, hi int64, height int,
+) ([]item, bool) {
+	i, _ := n.findFirst(lo)
+	for ; i <= n.numItems; i++ {
+		if height > 0 {
+			var ok bool
+			if items, ok = n.children[i].appendRange(
+				items, lo, hi, height-1); !ok {
+				return items, false
+			}
+		}
+		if i == n.numItems {
+			break
+		}
+		if n.keys[i] > hi {
+			return items, false
+		}
+		items = append(items, n.item(i))
+	}
+	return items, true
+}
@@ -0,0 +1,186 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// contents returns the items of the tree in order
+func (tr *BTree) contents() (items []item) {
+	tr.Scan(func(key int64, value interface{}) bool {
+		items = append(items, item{key, slot{value: value}})
+		return true
+	})
+	return items
+}
+
+func TestClone(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tr := New(&Options{Aggregator: sumAggregator{}})
+	for i := 0; i < 5000; i++ {
+		tr.Set(int64(r.Intn(10000)), i)
+	}
+	trees := []*BTree{tr}
+	want := [][]item{tr.contents()}
+	for round := 0; round < 20; round++ {
+		// write to a random tree and clone it now and then, the trees that
+		// aren't written to must stay as they were
+		i := r.Intn(len(trees))
+		for j := 0; j < 300; j++ {
+			key := int64(r.Intn(10000))
+			switch r.Intn(4) {
+			case 0:
+				trees[i].Delete(key)
+			case 1:
+				d := r.Intn(len(trees))
+				trees[i].MoveRange(trees[d], key, key+20)
+				want[d] = trees[d].contents()
+			default:
+				trees[i].Set(key, j)
+			}
+		}
+		want[i] = trees[i].contents()
+		if round%3 == 0 {
+			trees = append(trees, trees[i].Clone())
+			want = append(want, want[i])
+		}
+		for k, c := range trees {
+			if err := c.sane(); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, want[k], c.contents())
+			assert.Equal(t, len(want[k]), c.Len())
+			sum := 0
+			for _, it := range want[k] {
+				sum += it.val().(int)
+			}
+			if len(want[k]) > 0 {
+				assert.Equal(t, sum, c.Aggregate())
+			}
+		}
+	}
+}
+
+func TestCloneIsolation(t *testing.T) {
+	tr := New(nil)
+	for i := 0; i < 10000; i++ {
+		tr.Set(int64(i), i)
+	}
+	c := tr.Clone()
+	want := c.contents()
+
+	// iterate over the clone while the tree takes writes
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10000; i += 2 {
+			tr.Delete(int64(i))
+			tr.Set(int64(i+10000), i)
+		}
+		tr.TransformValues(func(key int64, value interface{}) interface{} {
+			return -1
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			var got []item
+			c.Ascend(0, func(key int64, value interface{}) bool {
+				got = append(got, item{key, slot{value: value}})
+				return true
+			})
+			assert.Equal(t, want, got)
+			got = got[:0]
+			c.Descend(10000, func(key int64, value interface{}) bool {
+				got = append(got, item{key, slot{value: value}})
+				return true
+			})
+			assert.Equal(t, len(want), len(got))
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, want, c.contents())
+	assert.Equal(t, 10000, tr.Len())
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+
+	// writes to the clone don't show in the tree
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+	v, _ := tr.Get(10000)
+	assert.Equal(t, -1, v)
+}
+
+func TestCloneRetainer(t *testing.T) {
+	refs := refCounter{}
+	tr := New(&Options{Retainer: refs})
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	c := tr.Clone()
+	assert.Equal(t, 2, refs[5])
+	tr.Delete(5)
+	assert.Equal(t, 1, refs[5])
+	c.Clear()
+	assert.Equal(t, 0, refs[5])
+	assert.Equal(t, 99, len(refs))
+}
+
+func TestCloneAllocator(t *testing.T) {
+	alloc := &trackingAllocator{live: map[*Node]bool{}}
+	tr := New(&Options{Allocator: alloc})
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i), i)
+	}
+	shared := tr.countNodes()
+	c := tr.Clone()
+	for i := 0; i < 1000; i += 2 {
+		tr.Delete(int64(i))
+		c.Set(int64(i), -i)
+	}
+	// the shared nodes are left to the garbage collector, all others are
+	// freed
+	tr.Clear()
+	c.Clear()
+	assert.Equal(t, shared, len(alloc.live))
+}
+
+func TestCopyRange(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i/2), i)
+	}
+	c := tr.CopyRange(100, 299)
+	assert.Equal(t, nil, c.Verify())
+	assert.Equal(t, 400, c.Len())
+	var want, got []interface{}
+	tr.Ascend(100, func(key int64, value interface{}) bool {
+		if key > 299 {
+			return false
+		}
+		want = append(want, value)
+		return true
+	})
+	c.Scan(func(key int64, value interface{}) bool {
+		got = append(got, value)
+		return true
+	})
+	assert.Equal(t, want, got)
+
+	// the copy and the tree are independent
+	c.Set(100, -1)
+	tr.Set(200, -1)
+	assert.Equal(t, 1000+1, tr.Len())
+	assert.Equal(t, 400+1, c.Len())
+	assert.Equal(t, 2, tr.CopyRange(-10, 0).Len())
+	assert.Equal(t, 0, tr.CopyRange(2000, 3000).Len())
+	assert.Equal(t, 0, tr.CopyRange(300, 200).Len())
+	assert.Equal(t, 0, new(BTree).CopyRange(0, 1).Len())
+	assert.Equal(t, tr.Len(), tr.CopyRange(math.MinInt64, math.MaxInt64).Len())
+}
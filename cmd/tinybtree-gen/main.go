@@ -0,0 +1,94 @@
+// Command tinybtree-gen writes a B-tree that is specialized for one key and
+// value type, so that keys are compared without interfaces or callbacks.
+// It's meant to be run by go generate:
+//
+//	//go:generate tinybtree-gen -name PointTree -key Point -less lessPoint
+//
+// which writes a PointTree type to pointtree_gen.go in the package of the
+// file. The less function, or any expression of type func(a, b Key) bool,
+// must report whether a sorts before b. The tree has the methods Set, Get,
+// Delete, Len, Scan, Ascend, Reverse and Descend of tinybtree.BTree. The
+// -doc flag adds a paragraph to the doc comment of the tree type.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+)
+
+type params struct {
+	Package string
+	Name    string // name of the tree type
+	Key     string // key type
+	Value   string // value type
+	Less    string // less function
+	Degree  int    // capacity of a node
+	Doc     string // more documentation of the tree type
+}
+
+func main() {
+	var p params
+	var out string
+	flag.StringVar(&p.Package, "pkg", os.Getenv("GOPACKAGE"), "package name")
+	flag.StringVar(&p.Name, "name", "", "name of the tree type")
+	flag.StringVar(&p.Key, "key", "", "key type")
+	flag.StringVar(&p.Value, "value", "interface{}", "value type")
+	flag.StringVar(&p.Less, "less", "", "function that reports whether a < b")
+	flag.IntVar(&p.Degree, "degree", 31, "number of items per node")
+	flag.StringVar(&p.Doc, "doc", "", "paragraph added to the doc comment")
+	flag.StringVar(&out, "o", "", "output file, defaults to <name>_gen.go")
+	flag.Parse()
+	if p.Package == "" || p.Name == "" || p.Key == "" || p.Less == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if out == "" {
+		out = strings.ToLower(p.Name) + "_gen.go"
+	}
+	src, err := generate(p)
+	if err == nil {
+		err = os.WriteFile(out, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tinybtree-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the formatted source of the tree
+func generate(p params) ([]byte, error) {
+	if p.Degree < 3 {
+		return nil, fmt.Errorf("degree must be at least 3")
+	}
+	var buf bytes.Buffer
+	t := template.Must(template.New("tree").Funcs(template.FuncMap{
+		"lower": func(s string) string {
+			return strings.ToLower(s[:1]) + s[1:]
+		},
+		"comment": comment,
+	}).Parse(treeTemplate))
+	if err := t.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// comment wraps the text into // comment lines of up to 76 columns
+func comment(text string) string {
+	var b strings.Builder
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line) > 2 && len(line)+1+len(word) > 76 {
+			b.WriteString(line + "\n")
+			line = "//"
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\n")
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// treeTest is run against a generated tree with string keys
+const treeTest = `package gen
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func lessString(a, b string) bool { return a < b }
+
+func TestStringTree(t *testing.T) {
+	var tr StringTree
+	m := map[string]int{}
+	for i := 0; i < 20000; i++ {
+		key := strconv.Itoa(rand.Intn(5000))
+		if rand.Intn(3) == 0 {
+			_, ok := tr.Delete(key)
+			_, want := m[key]
+			if ok != want {
+				t.Fatalf("delete %s: expected %v", key, want)
+			}
+			delete(m, key)
+		} else {
+			tr.Set(key, i)
+			m[key] = i
+		}
+	}
+	if tr.Len() != len(m) {
+		t.Fatalf("expected %d items, got %d", len(m), tr.Len())
+	}
+	var keys []string
+	for key, value := range m {
+		keys = append(keys, key)
+		if v, ok := tr.Get(key); !ok || v != value {
+			t.Fatalf("get %s: expected %d, got %d", key, value, v)
+		}
+	}
+	sort.Strings(keys)
+	var i int
+	tr.Ascend(keys[len(keys)/2], func(key string, value int) bool {
+		if key != keys[len(keys)/2+i] {
+			t.Fatalf("ascend: expected %s, got %s", keys[len(keys)/2+i], key)
+		}
+		i++
+		return true
+	})
+	if i != len(keys)-len(keys)/2 {
+		t.Fatalf("ascend: expected %d items, got %d", len(keys)-len(keys)/2, i)
+	}
+	i = len(keys) / 2
+	tr.Descend(keys[i]+"\x00", func(key string, value int) bool {
+		if key != keys[i] {
+			t.Fatalf("descend: expected %s, got %s", keys[i], key)
+		}
+		i--
+		return true
+	})
+	if i != -1 {
+		t.Fatalf("descend stopped at %d", i)
+	}
+	for _, key := range keys {
+		tr.Delete(key)
+	}
+	if tr.Len() != 0 {
+		t.Fatalf("expected an empty tree")
+	}
+}
+`
+
+func TestGenerate(t *testing.T) {
+	src, err := generate(params{Package: "gen", Name: "StringTree",
+		Key: "string", Value: "int", Less: "lessString", Degree: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "// string and values of type int\ntype") {
+		t.Fatalf("expected the doc comment on the type:\n%s", src)
+	}
+	doc, err := generate(params{Package: "gen", Name: "T", Key: "int",
+		Value: "int", Less: "less", Degree: 8,
+		Doc: "It's documented. " + strings.Repeat("word ", 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// int and values of type int\n//\n// It's documented." +
+		strings.Repeat(" word", 11) + "\n//" + strings.Repeat(" word", 9) +
+		"\ntype T struct"
+	if !strings.Contains(string(doc), want) {
+		t.Fatalf("expected the doc paragraph on the type:\n%s", doc)
+	}
+	if _, err := generate(params{Package: "gen", Name: "T", Key: "int",
+		Less: "less", Degree: 2}); err == nil {
+		t.Fatal("expected an error for a too small degree")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":            "module gen\n\ngo 1.13\n",
+		"stringtree_gen.go": string(src),
+		"tree_test.go":      treeTest,
+	}
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gobin, "test", "-count=1", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
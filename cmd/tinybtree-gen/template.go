@@ -0,0 +1,363 @@
+package main
+
+// treeTemplate is the source of a tree, all of its unexported names start
+// with the name of the tree so that several trees fit into one package.
+const treeTemplate = `// Code generated by tinybtree-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+{{$n := print (lower .Name) "Node"}}{{$i := print (lower .Name) "Item"}}
+{{- $max := print (lower .Name) "MaxItems"}}{{$min := print (lower .Name) "MinItems"}}
+const (
+	{{$max}} = {{.Degree}}
+	{{$min}} = {{$max}} * 40 / 100
+)
+
+type {{$i}} struct {
+	key   {{.Key}}
+	value {{.Value}}
+}
+
+type {{$n}} struct {
+	numItems int
+	items    [{{$max}}]{{$i}}
+	children [{{$max}} + 1]*{{$n}}
+}
+
+// {{.Name}} is an ordered set of key/value pairs with keys of type
+// {{.Key}} and values of type {{.Value}}
+{{- with .Doc}}
+//
+{{comment .}}{{else}}
+{{end -}}
+type {{.Name}} struct {
+	height int
+	root   *{{$n}}
+	length int
+}
+
+func (n *{{$n}}) find(key {{.Key}}) (index int, found bool) {
+	i, j := 0, n.numItems
+	for i < j {
+		h := i + (j-i)/2
+		if !{{.Less}}(key, n.items[h].key) {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i > 0 && !{{.Less}}(n.items[i-1].key, key) {
+		return i - 1, true
+	}
+	return i, false
+}
+
+// Set or replace a value for a key
+func (tr *{{.Name}}) Set(key {{.Key}}, value {{.Value}}) (
+	prev {{.Value}}, replaced bool,
+) {
+	if tr.root == nil {
+		tr.root = new({{$n}})
+		tr.root.items[0] = {{$i}}{key: key, value: value}
+		tr.root.numItems = 1
+		tr.length = 1
+		return
+	}
+	prev, replaced = tr.root.set(key, value, tr.height)
+	if replaced {
+		return
+	}
+	if tr.root.numItems == {{$max}} {
+		n := tr.root
+		right, median := n.split(tr.height)
+		tr.root = new({{$n}})
+		tr.root.children[0] = n
+		tr.root.items[0] = median
+		tr.root.children[1] = right
+		tr.root.numItems = 1
+		tr.height++
+	}
+	tr.length++
+	return
+}
+
+func (n *{{$n}}) split(height int) (right *{{$n}}, median {{$i}}) {
+	right = new({{$n}})
+	median = n.items[{{$max}}/2]
+	copy(right.items[:], n.items[{{$max}}/2+1:])
+	if height > 0 {
+		copy(right.children[:], n.children[{{$max}}/2+1:])
+		for i := {{$max}}/2 + 1; i < {{$max}}+1; i++ {
+			n.children[i] = nil
+		}
+	}
+	right.numItems = {{$max}} - {{$max}}/2 - 1
+	for i := {{$max}} / 2; i < {{$max}}; i++ {
+		n.items[i] = {{$i}}{}
+	}
+	n.numItems = {{$max}} / 2
+	return
+}
+
+func (n *{{$n}}) set(key {{.Key}}, value {{.Value}}, height int) (
+	prev {{.Value}}, replaced bool,
+) {
+	i, found := n.find(key)
+	if found {
+		prev = n.items[i].value
+		n.items[i].value = value
+		return prev, true
+	}
+	if height == 0 {
+		copy(n.items[i+1:n.numItems+1], n.items[i:n.numItems])
+		n.items[i] = {{$i}}{key: key, value: value}
+		n.numItems++
+		return prev, false
+	}
+	prev, replaced = n.children[i].set(key, value, height-1)
+	if replaced {
+		return
+	}
+	if n.children[i].numItems == {{$max}} {
+		right, median := n.children[i].split(height - 1)
+		copy(n.children[i+2:n.numItems+2], n.children[i+1:n.numItems+1])
+		copy(n.items[i+1:n.numItems+1], n.items[i:n.numItems])
+		n.items[i] = median
+		n.children[i+1] = right
+		n.numItems++
+	}
+	return
+}
+
+// Get a value for key
+func (tr *{{.Name}}) Get(key {{.Key}}) (value {{.Value}}, gotten bool) {
+	n := tr.root
+	for h := tr.height; n != nil; h-- {
+		i, found := n.find(key)
+		if found {
+			return n.items[i].value, true
+		}
+		if h == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	return value, false
+}
+
+// Len returns the number of items in the tree
+func (tr *{{.Name}}) Len() int {
+	return tr.length
+}
+
+// Delete a value for a key
+func (tr *{{.Name}}) Delete(key {{.Key}}) (prev {{.Value}}, deleted bool) {
+	if tr.root == nil {
+		return
+	}
+	var prevItem {{$i}}
+	prevItem, deleted = tr.root.delete(false, key, tr.height)
+	if !deleted {
+		return
+	}
+	if tr.root.numItems == 0 && tr.height > 0 {
+		tr.root = tr.root.children[0]
+		tr.height--
+	}
+	tr.length--
+	if tr.length == 0 {
+		tr.root = nil
+		tr.height = 0
+	}
+	return prevItem.value, true
+}
+
+func (n *{{$n}}) delete(max bool, key {{.Key}}, height int) (
+	prev {{$i}}, deleted bool,
+) {
+	var i int
+	var found bool
+	if max {
+		i, found = n.numItems-1, true
+	} else {
+		i, found = n.find(key)
+	}
+	if height == 0 {
+		if !found {
+			return prev, false
+		}
+		prev = n.items[i]
+		copy(n.items[i:], n.items[i+1:n.numItems])
+		n.items[n.numItems-1] = {{$i}}{}
+		n.numItems--
+		return prev, true
+	}
+	if found {
+		if max {
+			i++
+			prev, deleted = n.children[i].delete(true, key, height-1)
+		} else {
+			prev = n.items[i]
+			n.items[i], _ = n.children[i].delete(true, key, height-1)
+			deleted = true
+		}
+	} else {
+		prev, deleted = n.children[i].delete(max, key, height-1)
+	}
+	if deleted && n.children[i].numItems < {{$min}} {
+		n.rebalance(i, height)
+	}
+	return
+}
+
+// rebalance fixes the child at index i when it has too few items
+func (n *{{$n}}) rebalance(i, height int) {
+	if i == n.numItems {
+		i--
+	}
+	left, right := n.children[i], n.children[i+1]
+	if left.numItems+right.numItems+1 < {{$max}} {
+		// merge left + item + right
+		left.items[left.numItems] = n.items[i]
+		copy(left.items[left.numItems+1:], right.items[:right.numItems])
+		if height > 1 {
+			copy(left.children[left.numItems+1:],
+				right.children[:right.numItems+1])
+		}
+		left.numItems += right.numItems + 1
+		copy(n.items[i:], n.items[i+1:n.numItems])
+		copy(n.children[i+1:], n.children[i+2:n.numItems+1])
+		n.items[n.numItems-1] = {{$i}}{}
+		n.children[n.numItems] = nil
+		n.numItems--
+	} else if left.numItems > right.numItems {
+		// move left -> right
+		copy(right.items[1:], right.items[:right.numItems])
+		if height > 1 {
+			copy(right.children[1:], right.children[:right.numItems+1])
+			right.children[0] = left.children[left.numItems]
+			left.children[left.numItems] = nil
+		}
+		right.items[0] = n.items[i]
+		right.numItems++
+		n.items[i] = left.items[left.numItems-1]
+		left.items[left.numItems-1] = {{$i}}{}
+		left.numItems--
+	} else {
+		// move right -> left
+		left.items[left.numItems] = n.items[i]
+		if height > 1 {
+			left.children[left.numItems+1] = right.children[0]
+			copy(right.children[:], right.children[1:right.numItems+1])
+			right.children[right.numItems] = nil
+		}
+		left.numItems++
+		n.items[i] = right.items[0]
+		copy(right.items[:], right.items[1:right.numItems])
+		right.items[right.numItems-1] = {{$i}}{}
+		right.numItems--
+	}
+}
+
+// Scan all items in tree
+func (tr *{{.Name}}) Scan(iter func(key {{.Key}}, value {{.Value}}) bool) {
+	if tr.root != nil {
+		tr.root.scan(iter, tr.height)
+	}
+}
+
+func (n *{{$n}}) scan(
+	iter func(key {{.Key}}, value {{.Value}}) bool, height int,
+) bool {
+	for i := 0; i < n.numItems; i++ {
+		if height > 0 && !n.children[i].scan(iter, height-1) {
+			return false
+		}
+		if !iter(n.items[i].key, n.items[i].value) {
+			return false
+		}
+	}
+	return height == 0 || n.children[n.numItems].scan(iter, height-1)
+}
+
+// Ascend the tree within the range [pivot, last]
+func (tr *{{.Name}}) Ascend(
+	pivot {{.Key}}, iter func(key {{.Key}}, value {{.Value}}) bool,
+) {
+	if tr.root != nil {
+		tr.root.ascend(pivot, iter, tr.height)
+	}
+}
+
+func (n *{{$n}}) ascend(
+	pivot {{.Key}}, iter func(key {{.Key}}, value {{.Value}}) bool, height int,
+) bool {
+	i, found := n.find(pivot)
+	if !found && height > 0 && !n.children[i].ascend(pivot, iter, height-1) {
+		return false
+	}
+	for ; i < n.numItems; i++ {
+		if !iter(n.items[i].key, n.items[i].value) {
+			return false
+		}
+		if height > 0 && !n.children[i+1].scan(iter, height-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reverse all items in tree
+func (tr *{{.Name}}) Reverse(iter func(key {{.Key}}, value {{.Value}}) bool) {
+	if tr.root != nil {
+		tr.root.reverse(iter, tr.height)
+	}
+}
+
+func (n *{{$n}}) reverse(
+	iter func(key {{.Key}}, value {{.Value}}) bool, height int,
+) bool {
+	if height > 0 && !n.children[n.numItems].reverse(iter, height-1) {
+		return false
+	}
+	for i := n.numItems - 1; i >= 0; i-- {
+		if !iter(n.items[i].key, n.items[i].value) {
+			return false
+		}
+		if height > 0 && !n.children[i].reverse(iter, height-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// Descend the tree within the range [pivot, first]
+func (tr *{{.Name}}) Descend(
+	pivot {{.Key}}, iter func(key {{.Key}}, value {{.Value}}) bool,
+) {
+	if tr.root != nil {
+		tr.root.descend(pivot, iter, tr.height)
+	}
+}
+
+func (n *{{$n}}) descend(
+	pivot {{.Key}}, iter func(key {{.Key}}, value {{.Value}}) bool, height int,
+) bool {
+	i, found := n.find(pivot)
+	if !found {
+		if height > 0 && !n.children[i].descend(pivot, iter, height-1) {
+			return false
+		}
+		i--
+	}
+	for ; i >= 0; i-- {
+		if !iter(n.items[i].key, n.items[i].value) {
+			return false
+		}
+		if height > 0 && !n.children[i].reverse(iter, height-1) {
+			return false
+		}
+	}
+	return true
+}
+`
@@ -0,0 +1,62 @@
+// Command tinybtree-migrate upgrades snapshot files that were written by
+// earlier versions of tinybtree to the current format.
+//
+//	tinybtree-migrate file...
+//
+// Each file is rewritten in place. The values must be of the basic types
+// that encoding/gob knows without registration.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/scarbo87/tinybtree"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: tinybtree-migrate file...")
+		os.Exit(2)
+	}
+	for _, path := range os.Args[1:] {
+		if err := migrate(path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// migrate writes the upgraded snapshot next to the file and renames it over
+// the original once it's complete.
+func migrate(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	w := bufio.NewWriter(out)
+	if _, err := tinybtree.MigrateSnapshot(w, bufio.NewReader(in)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), path)
+}
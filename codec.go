@@ -0,0 +1,53 @@
+package tinybtree
+
+import "encoding/binary"
+
+// KeyCodec converts between int64 keys and byte slice keys.
+type KeyCodec interface {
+	Encode(key int64) []byte
+	Decode(b []byte) int64
+}
+
+// BigEndianCodec encodes keys as 8 big-endian bytes with the sign bit
+// flipped, so that the byte-wise order of encoded keys is the same as the
+// order of the keys. Decode pads shorter slices with zeros and ignores the
+// bytes after the first 8.
+var BigEndianCodec KeyCodec = bigEndianCodec{}
+
+type bigEndianCodec struct{}
+
+func (bigEndianCodec) Encode(key int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(key)^(1<<63))
+	return b
+}
+
+func (bigEndianCodec) Decode(b []byte) int64 {
+	var buf [8]byte
+	copy(buf[:], b)
+	return int64(binary.BigEndian.Uint64(buf[:]) ^ (1 << 63))
+}
+
+func (tr *BTree) keyCodec() KeyCodec {
+	if tr.codec == nil {
+		return BigEndianCodec
+	}
+	return tr.codec
+}
+
+// SetBytes is like Set but the key is decoded by the tree's KeyCodec
+func (tr *BTree) SetBytes(key []byte, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	return tr.Set(tr.keyCodec().Decode(key), value)
+}
+
+// GetBytes is like Get but the key is decoded by the tree's KeyCodec
+func (tr *BTree) GetBytes(key []byte) (value interface{}, gotten bool) {
+	return tr.Get(tr.keyCodec().Decode(key))
+}
+
+// DeleteBytes is like Delete but the key is decoded by the tree's KeyCodec
+func (tr *BTree) DeleteBytes(key []byte) (prev interface{}, deleted bool) {
+	return tr.Delete(tr.keyCodec().Decode(key))
+}
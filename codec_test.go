@@ -0,0 +1,50 @@
+package tinybtree
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBigEndianCodec(t *testing.T) {
+	keys := []int64{math.MinInt64, -1 << 40, -1, 0, 1, 1 << 40, math.MaxInt64}
+	for i := 0; i < 100; i++ {
+		keys = append(keys, rand.Int63()-rand.Int63())
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for i, key := range keys {
+		b := BigEndianCodec.Encode(key)
+		assert.Equal(t, key, BigEndianCodec.Decode(b))
+		if i > 0 && bytes.Compare(BigEndianCodec.Encode(keys[i-1]), b) > 0 {
+			t.Fatalf("%d encodes greater than %d", keys[i-1], key)
+		}
+	}
+}
+
+func TestBTreeBytes(t *testing.T) {
+	var tr BTree
+	tr.SetBytes([]byte("a"), 1)
+	tr.SetBytes([]byte("b"), 2)
+	v, ok := tr.GetBytes([]byte("a"))
+	if !ok || v != 1 {
+		t.Fatalf("expected 1, got %v", v)
+	}
+	var keys [][]byte
+	tr.Scan(func(key int64, value interface{}) bool {
+		keys = append(keys, BigEndianCodec.Encode(key))
+		return true
+	})
+	assert.Equal(t, 2, len(keys))
+	if !bytes.HasPrefix(keys[0], []byte("a")) || keys[0][1] != 0 {
+		t.Fatalf("unexpected key %q", keys[0])
+	}
+	prev, deleted := tr.DeleteBytes([]byte("b"))
+	if !deleted || prev != 2 {
+		t.Fatalf("expected 2, got %v", prev)
+	}
+	assert.Equal(t, 1, tr.Len())
+}
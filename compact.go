@@ -0,0 +1,141 @@
+package tinybtree
+
+import "unsafe"
+
+// Compact merges the trees into a new tree whose nodes are filled up as far
+// as possible. When a key is in several trees only the value of the first
+// of them is kept, as with ScanMerged, unless the first tree is in multi
+// mode, in which case all values are kept. The new tree has the Multi,
+// Aggregator, KeyCodec and Tracer options of the first tree.
+func Compact(trees []*BTree) *BTree {
+	tr := new(BTree)
+	if len(trees) == 0 {
+		return tr
+	}
+	tr.multi, tr.agg, tr.codec = trees[0].multi, trees[0].agg, trees[0].codec
+	tr.tracer = trees[0].tracer
+	span := tr.span("Compact")
+	span.SetAttribute("tinybtree.trees", int64(len(trees)))
+	defer span.End(nil)
+	var items []item
+	mergeTrees(trees, !tr.multi, func(it item) bool {
+		items = append(items, it)
+		return true
+	})
+	tr.load(items)
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	return tr
+}
+
+// ShrinkToFit rebuilds the tree with its nodes filled up as far as
+// possible, like Compact, and returns the number of bytes of the nodes that
+// were given up. After many deletes the nodes may be barely above half
+// full, and nothing is given up until they are merged. Nodes that are
+// shared with a clone stay in use by the clone and are not counted, so the
+// result may be negative right after Clone.
+func (tr *BTree) ShrinkToFit() (reclaimed int) {
+	tr.checkWrite()
+	if tr.root == nil {
+		return 0
+	}
+	span := tr.span("ShrinkToFit")
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	defer span.End(nil)
+	old := subtree{tr.root, tr.height}
+	before := old.root.nodes(tr, old.height)
+	items := make([]item, 0, tr.length)
+	c := newCursor(tr)
+	for ok := c.first(); ok; ok = c.next() {
+		items = append(items, c.item())
+	}
+	tr.load(items)
+	old.root.free(tr, old.height)
+	after := tr.root.nodes(tr, tr.height)
+	reclaimed = (before - after) * int(unsafe.Sizeof(node{}))
+	span.SetAttribute("tinybtree.reclaimed_bytes", int64(reclaimed))
+	return reclaimed
+}
+
+// nodes returns the number of nodes of the subtree that belong to tr
+func (n *node) nodes(tr *BTree, height int) int {
+	if !tr.owned(n) {
+		return 0
+	}
+	count := 1
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			count += n.children[i].nodes(tr, height-1)
+		}
+	}
+	return count
+}
+
+// load replaces the contents of the tree with the sorted items. The tree is
+// built bottom up, with the items spread evenly over as few nodes as
+// possible.
+func (tr *BTree) load(items []item) {
+	tr.detach()
+	if len(items) == 0 {
+		return
+	}
+	const fill = maxItems - 1 // most items a node can hold
+	// the leaves and the items between them
+	k := (len(items) + 1 + fill) / (fill + 1)
+	nodes := make([]*node, k)
+	seps := make([]item, 0, k-1)
+	size, extra := (len(items)-(k-1))/k, (len(items)-(k-1))%k
+	for i := range nodes {
+		n := tr.newNode()
+		n.numItems = size
+		if i < extra {
+			n.numItems++
+		}
+		for j := 0; j < n.numItems; j++ {
+			n.setItem(j, items[j])
+		}
+		n.count = n.numItems
+		n.aggregate(tr, 0)
+		nodes[i] = n
+		items = items[n.numItems:]
+		if i < k-1 {
+			seps = append(seps, items[0])
+			items = items[1:]
+		}
+	}
+	height := 0
+	for len(nodes) > 1 {
+		// group the nodes under parents, with the separators between the
+		// children of a parent moving into it
+		height++
+		p := (len(nodes) + fill) / (fill + 1)
+		parents := make([]*node, p)
+		upper := make([]item, 0, p-1)
+		size, extra := len(nodes)/p, len(nodes)%p
+		for i := range parents {
+			n := tr.newNode()
+			children := size
+			if i < extra {
+				children++
+			}
+			n.numItems = children - 1
+			for j := 0; j < children; j++ {
+				n.children[j] = nodes[j]
+				n.count += nodes[j].count
+				if j < n.numItems {
+					n.setItem(j, seps[j])
+				}
+			}
+			n.count += n.numItems
+			n.aggregate(tr, height)
+			parents[i] = n
+			nodes = nodes[children:]
+			seps = seps[n.numItems:]
+			if i < p-1 {
+				upper = append(upper, seps[0])
+				seps = seps[1:]
+			}
+		}
+		nodes, seps = parents, upper
+	}
+	tr.attach(subtree{nodes[0], height})
+}
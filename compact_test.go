@@ -0,0 +1,105 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompact(t *testing.T) {
+	for _, N := range []int{0, 1, 30, 31, 32, 100, 1000, 12345} {
+		trees := make([]*BTree, 3)
+		for i := range trees {
+			trees[i] = New(&Options{Aggregator: sumAggregator{}})
+			for j := 0; j < N/len(trees)+1; j++ {
+				trees[i].Set(int64(rand.Intn(N+1)), i)
+			}
+			trees[i].Freeze()
+		}
+		tr := Compact(trees)
+		if err := tr.sane(); err != nil {
+			t.Fatal(err)
+		}
+		var want []int64
+		var sum int
+		ScanMerged(trees, true, func(key int64, value interface{}) bool {
+			want = append(want, key)
+			sum += value.(int)
+			return true
+		})
+		keys, _ := treeItems(tr)
+		assert.Equal(t, want, keys)
+		if tr.Len() > 0 {
+			assert.Equal(t, sum, tr.Aggregate())
+		}
+		// a compacted tree is at most as high as a tree that was built by
+		// setting the items one by one
+		var grown BTree
+		for _, key := range keys {
+			grown.Set(key, nil)
+		}
+		if tr.height > grown.height {
+			t.Fatalf("expected height %d at most, got %d", grown.height,
+				tr.height)
+		}
+		tr.Set(-1, 0)
+		tr.Delete(keys[len(keys)/2])
+		if err := tr.sane(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, 0, Compact(nil).Len())
+}
+
+func TestCompactMulti(t *testing.T) {
+	a := New(&Options{Multi: true})
+	b := New(&Options{Multi: true})
+	for i := 0; i < 100; i++ {
+		a.Set(int64(i%10), i)
+		b.Set(int64(i%10), -i)
+	}
+	tr := Compact([]*BTree{a, b})
+	assert.Equal(t, 200, tr.Len())
+	assert.Equal(t, 20, len(tr.GetAll(3)))
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShrinkToFit(t *testing.T) {
+	alloc := &trackingAllocator{live: map[*Node]bool{}}
+	tr := New(&Options{Allocator: alloc})
+	assert.Equal(t, 0, tr.ShrinkToFit())
+	for i := 0; i < 20000; i++ {
+		tr.Set(int64(i), i)
+	}
+	for i := 0; i < 20000; i++ {
+		if i%10 != 0 {
+			tr.Delete(int64(i))
+		}
+	}
+	before := tr.countNodes()
+	reclaimed := tr.ShrinkToFit()
+	after := tr.countNodes()
+	if after >= before {
+		t.Fatalf("expected fewer than %d nodes, got %d", before, after)
+	}
+	assert.Equal(t, (before-after)*int(unsafe.Sizeof(node{})), reclaimed)
+	assert.Equal(t, after, len(alloc.live))
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2000, tr.Len())
+	tr.Scan(func(key int64, value interface{}) bool {
+		assert.Equal(t, int(key), value)
+		return true
+	})
+
+	// the nodes shared with a clone are not given up
+	c := tr.Clone()
+	assert.Equal(t, -after*int(unsafe.Sizeof(node{})), tr.ShrinkToFit())
+	assert.Equal(t, 2*after, len(alloc.live))
+	assert.Equal(t, 2000, c.Len())
+}
@@ -0,0 +1,75 @@
+package tinybtree
+
+// cursor walks the items of a BTree in order. It's only valid while the
+// tree is not modified.
+type cursor struct {
+	tr    *BTree
+	stack []cursorFrame
+}
+
+// cursorFrame is a node on the path to the current item. For a leaf, index
+// is the position of the current item. For a branch, index is the child that
+// is being walked, or when the branch is on top of the stack, the position
+// of the current item.
+type cursorFrame struct {
+	n     *node
+	index int
+}
+
+func newCursor(tr *BTree) *cursor {
+	return &cursor{tr: tr, stack: make([]cursorFrame, 0, tr.height+1)}
+}
+
+// item returns the current item
+func (c *cursor) item() item {
+	f := c.stack[len(c.stack)-1]
+	return f.n.item(f.index)
+}
+
+// first moves to the first item, returns false when the tree is empty
+func (c *cursor) first() bool {
+	c.stack = c.stack[:0]
+	if c.tr.root == nil {
+		return false
+	}
+	c.leftmost(c.tr.root, c.tr.height)
+	return true
+}
+
+// leftmost pushes the path to the first item of the subtree
+func (c *cursor) leftmost(n *node, height int) {
+	for ; height > 0; height-- {
+		c.stack = append(c.stack, cursorFrame{n, 0})
+		n = n.children[0]
+	}
+	c.stack = append(c.stack, cursorFrame{n, 0})
+}
+
+// next moves to the next item, returns false when there are no more items
+func (c *cursor) next() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	top := &c.stack[len(c.stack)-1]
+	height := c.tr.height - (len(c.stack) - 1)
+	if height > 0 {
+		// move from the branch item into the child right of it
+		top.index++
+		c.leftmost(top.n.children[top.index], height-1)
+		return true
+	}
+	top.index++
+	if top.index < top.n.numItems {
+		return true
+	}
+	// go up until a branch with a following item is found
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top = &c.stack[len(c.stack)-1]
+		if top.index < top.n.numItems {
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package tinybtree
+
+import "time"
+
+// deadlineEvery is the number of items visited between two looks at the
+// clock
+const deadlineEvery = 64
+
+// withDeadline wraps iter to stop once deadline has passed. The returned
+// expired reports whether it did.
+func withDeadline(
+	deadline time.Time,
+	iter func(key int64, value interface{}) bool,
+) (wrapped func(key int64, value interface{}) bool, expired *bool) {
+	expired = new(bool)
+	var n int
+	wrapped = func(key int64, value interface{}) bool {
+		if n++; n%deadlineEvery == 0 && !time.Now().Before(deadline) {
+			*expired = true
+			return false
+		}
+		return iter(key, value)
+	}
+	return wrapped, expired
+}
+
+// ScanWithDeadline is like Scan but stops once deadline has passed, which
+// is looked at every few items. It returns false when the deadline stopped
+// the scan before it visited all items, and true when it finished or iter
+// stopped it.
+func (tr *BTree) ScanWithDeadline(
+	deadline time.Time,
+	iter func(key int64, value interface{}) bool,
+) (completed bool) {
+	wrapped, expired := withDeadline(deadline, iter)
+	tr.Scan(wrapped)
+	return !*expired
+}
+
+// AscendWithDeadline is like Ascend but stops once deadline has passed, see
+// ScanWithDeadline
+func (tr *BTree) AscendWithDeadline(
+	pivot int64,
+	deadline time.Time,
+	iter func(key int64, value interface{}) bool,
+) (completed bool) {
+	wrapped, expired := withDeadline(deadline, iter)
+	tr.Ascend(pivot, wrapped)
+	return !*expired
+}
+
+// DescendWithDeadline is like Descend but stops once deadline has passed,
+// see ScanWithDeadline
+func (tr *BTree) DescendWithDeadline(
+	pivot int64,
+	deadline time.Time,
+	iter func(key int64, value interface{}) bool,
+) (completed bool) {
+	wrapped, expired := withDeadline(deadline, iter)
+	tr.Descend(pivot, wrapped)
+	return !*expired
+}
@@ -0,0 +1,42 @@
+package tinybtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanWithDeadline(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 10000; i++ {
+		tr.Set(int64(i), i)
+	}
+	var n int
+	count := func(key int64, value interface{}) bool {
+		n++
+		return true
+	}
+	future := time.Now().Add(time.Hour)
+	assert.Equal(t, true, tr.ScanWithDeadline(future, count))
+	assert.Equal(t, 10000, n)
+
+	n = 0
+	assert.Equal(t, false, tr.ScanWithDeadline(time.Now(), count))
+	assert.Equal(t, deadlineEvery-1, n)
+
+	n = 0
+	assert.Equal(t, true, tr.ScanWithDeadline(time.Now(),
+		func(key int64, value interface{}) bool {
+			n++
+			return n < 10
+		}))
+	assert.Equal(t, 10, n)
+
+	n = 0
+	assert.Equal(t, true, tr.AscendWithDeadline(9990, future, count))
+	assert.Equal(t, 10, n)
+	n = 0
+	assert.Equal(t, false, tr.DescendWithDeadline(9990, time.Now(), count))
+	assert.Equal(t, deadlineEvery-1, n)
+}
@@ -0,0 +1,9 @@
+//go:build !tinybtree_degree_64 && !tinybtree_degree_128 && !tinybtree_degree_256
+
+package tinybtree
+
+// maxItems is the capacity of a node, nodes split when they fill up. The
+// tinybtree_degree_64, tinybtree_degree_128 and tinybtree_degree_256 build
+// tags choose nodes with that many children instead, the largest one wins
+// when several are set.
+const maxItems = defaultMaxItems
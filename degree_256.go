@@ -0,0 +1,7 @@
+//go:build tinybtree_degree_256
+
+package tinybtree
+
+// maxItems is the capacity of a node, nodes split when they fill up. With
+// the tinybtree_degree_256 build tag nodes have up to 256 children.
+const maxItems = 255
@@ -0,0 +1,74 @@
+package tinybtree
+
+import "reflect"
+
+// DiffKind describes how a key differs between two trees
+type DiffKind int
+
+const (
+	// DiffAdded is a key that only exists in the other tree
+	DiffAdded DiffKind = iota
+	// DiffRemoved is a key that only exists in this tree
+	DiffRemoved
+	// DiffChanged is a key that exists in both trees with different values
+	DiffChanged
+)
+
+func (kind DiffKind) String() string {
+	switch kind {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// Diff walks both trees in key order and calls fn for every key that
+// differs. The left value is the value in tr and the right value is the
+// value in other, either of them is nil when the key is missing on that
+// side. Values are compared with == when possible, otherwise with
+// reflect.DeepEqual. In multi mode the items with the same key are paired in
+// order. Neither tree may be modified during the diff.
+func (tr *BTree) Diff(
+	other *BTree,
+	fn func(key int64, left, right interface{}, kind DiffKind),
+) {
+	lc, rc := newCursor(tr), newCursor(other)
+	lok, rok := lc.first(), rc.first()
+	for lok || rok {
+		switch {
+		case !rok || (lok && lc.item().key < rc.item().key):
+			l := lc.item()
+			fn(l.key, l.val(), nil, DiffRemoved)
+			lok = lc.next()
+		case !lok || rc.item().key < lc.item().key:
+			r := rc.item()
+			fn(r.key, nil, r.val(), DiffAdded)
+			rok = rc.next()
+		default:
+			l, r := lc.item(), rc.item()
+			lv, rv := l.val(), r.val()
+			if !valuesEqual(lv, rv) {
+				fn(l.key, lv, rv, DiffChanged)
+			}
+			lok, rok = lc.next(), rc.next()
+		}
+	}
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta.Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,82 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	for _, n := range []int{0, 1, 30, 31, 1000, 10000} {
+		var tr BTree
+		for _, i := range rand.Perm(n) {
+			tr.Set(int64(i), i)
+		}
+		c := newCursor(&tr)
+		var count int
+		for ok := c.first(); ok; ok = c.next() {
+			if c.item().key != int64(count) {
+				t.Fatalf("expected %d, got %d", count, c.item().key)
+			}
+			count++
+		}
+		assert.Equal(t, n, count)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var left, right BTree
+	for i := 0; i < 1000; i++ {
+		if i%3 != 0 {
+			left.Set(int64(i), i)
+		}
+		if i%5 != 0 {
+			right.Set(int64(i), i)
+		}
+	}
+	right.Set(7, []int{7})
+	left.Set(8, []int{8})
+	right.Set(8, []int{8})
+
+	type change struct {
+		key         int64
+		left, right interface{}
+		kind        DiffKind
+	}
+	var changes []change
+	left.Diff(&right, func(key int64, l, r interface{}, kind DiffKind) {
+		changes = append(changes, change{key, l, r, kind})
+	})
+	var added, removed, changed int
+	for _, c := range changes {
+		switch c.kind {
+		case DiffAdded:
+			added++
+			if c.key%3 != 0 || c.key%5 == 0 || c.left != nil {
+				t.Fatalf("unexpected %v", c)
+			}
+		case DiffRemoved:
+			removed++
+			if c.key%5 != 0 || c.key%3 == 0 || c.right != nil {
+				t.Fatalf("unexpected %v", c)
+			}
+		case DiffChanged:
+			changed++
+			assert.Equal(t, int64(7), c.key)
+		}
+	}
+	assert.Equal(t, 334-67, added)
+	assert.Equal(t, 200-67, removed)
+	assert.Equal(t, 1, changed)
+
+	var empty BTree
+	var count int
+	empty.Diff(&right, func(key int64, l, r interface{}, kind DiffKind) {
+		if kind != DiffAdded {
+			t.Fatalf("unexpected %v", kind)
+		}
+		count++
+	})
+	assert.Equal(t, right.Len(), count)
+}
@@ -0,0 +1,78 @@
+package tinybtree
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrKeyNotFound is returned when a key that must exist is not in the
+	// tree.
+	ErrKeyNotFound = errors.New("tinybtree: key not found")
+	// ErrFrozen is the value of the panic when writing to a frozen tree.
+	ErrFrozen = errors.New("tinybtree: write to frozen tree")
+	// ErrCorruptSnapshot is matched by errors.Is for errors returned when a
+	// snapshot is truncated or can't be decoded. See SnapshotError.
+	ErrCorruptSnapshot = errors.New("tinybtree: corrupt snapshot")
+	// ErrChecksum is the error of a SnapshotError when a checksum of a
+	// snapshot doesn't match its contents.
+	ErrChecksum = errors.New("tinybtree: checksum mismatch")
+	// ErrIDOverflow is returned by ComposeKey when the id doesn't fit into
+	// a key.
+	ErrIDOverflow = errors.New("tinybtree: id overflows the key")
+	// ErrConcurrentModification is the value of the panic when an Iter or
+	// a BPlusIter is used after items were inserted into or removed from
+	// its tree.
+	ErrConcurrentModification = errors.New(
+		"tinybtree: tree modified during iteration")
+	// ErrSnapshotVersion is returned when reading a snapshot that was
+	// written by a newer version of this package.
+	ErrSnapshotVersion = errors.New("tinybtree: unsupported snapshot version")
+	// ErrRetainerPublish is the value of the panic when a tree with a
+	// Retainer is published by AtomicTree.Publish or an SPMCTree.
+	ErrRetainerPublish = errors.New(
+		"tinybtree: publish of a tree with a Retainer")
+)
+
+// IterPanic is the value of the panic when the callback of an iteration
+// panics. The original panic is still part of the stack trace.
+type IterPanic struct {
+	Key   int64       // the key the callback was called for
+	Value interface{} // the value of the original panic
+}
+
+func (e *IterPanic) Error() string {
+	return fmt.Sprintf("tinybtree: panic in callback for key %d: %v", e.Key,
+		e.Value)
+}
+
+// Unwrap returns the value of the original panic when it's an error
+func (e *IterPanic) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// SnapshotError is returned when a snapshot is truncated or can't be
+// decoded. Errors of the underlying reader are returned as they are.
+type SnapshotError struct {
+	// Offset is where the corruption was detected, which is the start of
+	// the block for ErrChecksum. Decoding errors are detected a little
+	// after the corrupt data because the decoder reads ahead.
+	Offset int64
+	Err    error // the decoding error, io.ErrUnexpectedEOF when truncated
+}
+
+func (e *SnapshotError) Error() string {
+	return fmt.Sprintf("%v at offset %d: %v", ErrCorruptSnapshot, e.Offset,
+		e.Err)
+}
+
+// Unwrap returns the decoding error
+func (e *SnapshotError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrCorruptSnapshot
+func (e *SnapshotError) Is(target error) bool {
+	return target == ErrCorruptSnapshot
+}
@@ -0,0 +1,52 @@
+package tinybtree
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetErr(t *testing.T) {
+	var tr BTree
+	tr.Set(1, "a")
+	v, err := tr.GetErr(1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a", v)
+	_, err = tr.GetErr(2)
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+type failReader struct{ err error }
+
+func (r failReader) Read(p []byte) (int, error) { return 0, r.err }
+
+func TestSnapshotErrors(t *testing.T) {
+	var tr BTree
+	for _, data := range [][]byte{
+		nil,
+		[]byte(snapshotMagic),
+		append([]byte(snapshotMagic), snapshotVersion, 0xff, 0xff, 0xff),
+		[]byte("garbage that is not a snapshot"),
+	} {
+		_, err := tr.ReadFrom(bytes.NewReader(data))
+		if !errors.Is(err, ErrCorruptSnapshot) {
+			t.Fatalf("%q: expected %v, got %v", data, ErrCorruptSnapshot, err)
+		}
+		var serr *SnapshotError
+		if !errors.As(err, &serr) || serr.Err == nil {
+			t.Fatalf("%q: expected a SnapshotError, got %v", data, err)
+		}
+	}
+
+	// errors of the reader are not corruption
+	readErr := errors.New("read failed")
+	_, err := tr.ReadFrom(failReader{readErr})
+	assert.Equal(t, readErr, err)
+	_, err = tr.ReadFrom(io.MultiReader(
+		bytes.NewReader([]byte(snapshotMagic)), failReader{readErr}))
+	assert.Equal(t, readErr, err)
+	assert.Equal(t, 0, tr.Len())
+}
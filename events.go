@@ -0,0 +1,127 @@
+package tinybtree
+
+// EventKind describes how a tree was changed
+type EventKind int
+
+const (
+	// EventInsert is a value that was set for a new key, or for a key that
+	// already exists in multi mode
+	EventInsert EventKind = iota
+	// EventReplace is a value that replaced the value of a key
+	EventReplace
+	// EventDelete is a value that was deleted
+	EventDelete
+	// EventClear is sent when all items were removed by Clear
+	EventClear
+)
+
+func (kind EventKind) String() string {
+	switch kind {
+	case EventInsert:
+		return "insert"
+	case EventReplace:
+		return "replace"
+	case EventDelete:
+		return "delete"
+	case EventClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// Event is a change of a tree. Prev is the replaced value of an
+// EventReplace, Key and Value are zero for an EventClear.
+type Event struct {
+	Kind  EventKind
+	Key   int64
+	Value interface{}
+	Prev  interface{}
+}
+
+// NodeEventKind describes a change of the structure of a tree
+type NodeEventKind int
+
+const (
+	// NodeSplit is a full node that was split in two around Key
+	NodeSplit NodeEventKind = iota
+	// NodeMerge is a node that was merged with its right sibling and the
+	// separator Key between them
+	NodeMerge
+	// NodeBorrow is a node that took an item from a sibling through the
+	// separator Key between them
+	NodeBorrow
+)
+
+func (kind NodeEventKind) String() string {
+	switch kind {
+	case NodeSplit:
+		return "split"
+	case NodeMerge:
+		return "merge"
+	case NodeBorrow:
+		return "borrow"
+	}
+	return "unknown"
+}
+
+// NodeEvent is a split or rebalance of nodes, see Options.NodeHook. Min and
+// Max are the smallest and the largest key of the nodes involved, not
+// counting their children, which roughly is the range of keys below them.
+// Height is the height of the nodes, 0 for leaves.
+type NodeEvent struct {
+	Kind     NodeEventKind
+	Key      int64
+	Min, Max int64
+	Height   int
+}
+
+type subscription struct {
+	fn func(Event)
+}
+
+// Subscribe calls fn after every change of the tree until the returned
+// function is called. Items moved by MoveRange are deleted from one tree
+// and inserted into the other. The events are sent on the goroutine that
+// changed the tree.
+func (tr *BTree) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s := &subscription{fn}
+	// the list is replaced rather than changed so that (un)subscribing
+	// from within fn doesn't affect the event that is being sent
+	subs := make([]*subscription, len(tr.subs), len(tr.subs)+1)
+	copy(subs, tr.subs)
+	tr.subs = append(subs, s)
+	return func() {
+		for i, sub := range tr.subs {
+			if sub == s {
+				subs := make([]*subscription, 0, len(tr.subs)-1)
+				subs = append(subs, tr.subs[:i]...)
+				tr.subs = append(subs, tr.subs[i+1:]...)
+				break
+			}
+		}
+		if len(tr.subs) == 0 {
+			tr.subs = nil
+		}
+	}
+}
+
+func (tr *BTree) emit(e Event) {
+	for _, s := range tr.subs {
+		s.fn(e)
+	}
+}
+
+// setEvent returns the event for setting it
+func setEvent(it, prev item, replaced bool) Event {
+	if replaced {
+		return Event{Kind: EventReplace, Key: it.key, Value: it.val(),
+			Prev: prev.val()}
+	}
+	return Event{Kind: EventInsert, Key: it.key, Value: it.val()}
+}
+
+func (tr *BTree) emitSet(it, prev item, replaced bool) {
+	if tr.subs != nil {
+		tr.emit(setEvent(it, prev, replaced))
+	}
+}
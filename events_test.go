@@ -0,0 +1,96 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	var tr BTree
+	var events []Event
+	unsubscribe := tr.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+	var n int
+	unsubscribe2 := tr.Subscribe(func(e Event) {
+		n++
+	})
+	tr.Set(1, "a")
+	tr.Set(1, "b")
+	tr.SetInt(2, 20)
+	tr.Delete(1)
+	tr.Delete(3)
+	tr.Clear()
+	assert.Equal(t, []Event{
+		{Kind: EventInsert, Key: 1, Value: "a"},
+		{Kind: EventReplace, Key: 1, Value: "b", Prev: "a"},
+		{Kind: EventInsert, Key: 2, Value: int64(20)},
+		{Kind: EventDelete, Key: 1, Value: "b"},
+		{Kind: EventClear},
+	}, events)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "replace", EventReplace.String())
+
+	unsubscribe2()
+	unsubscribe2()
+	events = nil
+	var dst BTree
+	var dstEvents []Event
+	dst.Subscribe(func(e Event) {
+		dstEvents = append(dstEvents, e)
+	})
+	for i := 0; i < 5; i++ {
+		tr.Set(int64(i), i)
+	}
+	dst.Set(2, "x")
+	dstEvents = nil
+	tr.MoveRange(&dst, 1, 2)
+	assert.Equal(t, []Event{
+		{Kind: EventInsert, Key: 1, Value: 1},
+		{Kind: EventReplace, Key: 2, Value: 2, Prev: "x"},
+	}, dstEvents)
+	assert.Equal(t, []Event{
+		{Kind: EventDelete, Key: 1, Value: 1},
+		{Kind: EventDelete, Key: 2, Value: 2},
+	}, events[5:])
+	assert.Equal(t, 5, n)
+
+	unsubscribe()
+	tr.Set(10, 10)
+	assert.Equal(t, 7, len(events))
+	assert.Equal(t, 0, len(tr.subs))
+}
+
+func TestNodeHook(t *testing.T) {
+	counts := map[NodeEventKind]int{}
+	var splits []NodeEvent
+	tr := New(&Options{NodeHook: func(e NodeEvent) {
+		counts[e.Kind]++
+		if e.Kind == NodeSplit {
+			splits = append(splits, e)
+		}
+		if e.Min > e.Key || e.Key > e.Max {
+			t.Fatalf("%v: key %d outside of [%d, %d]", e.Kind, e.Key,
+				e.Min, e.Max)
+		}
+	}})
+	for i := 0; i < maxItems; i++ {
+		tr.Set(int64(i), i)
+	}
+	// the full root splits around its median
+	assert.Equal(t, []NodeEvent{{Kind: NodeSplit, Key: maxItems / 2,
+		Min: 0, Max: maxItems - 1}}, splits)
+	// enough items for a tree of three levels under every degree
+	n := maxItems * maxItems * 2
+	for i := maxItems; i < n; i++ {
+		tr.Set(int64(i), i)
+	}
+	for i := 0; i < n; i++ {
+		tr.Delete(int64(i))
+	}
+	if counts[NodeMerge] == 0 || counts[NodeBorrow] == 0 {
+		t.Fatalf("expected merges and borrows, got %v", counts)
+	}
+	assert.Equal(t, "split", NodeSplit.String())
+}
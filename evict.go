@@ -0,0 +1,48 @@
+package tinybtree
+
+// EvictPolicy returns the key whose first value is evicted when a tree grows
+// past its maximum length, see SetMaxLen. EvictMin and EvictMax are the
+// common policies.
+type EvictPolicy func(tr *BTree) (key int64)
+
+// EvictMin evicts the smallest key
+func EvictMin(tr *BTree) int64 {
+	return tr.root.at(0, tr.height).key
+}
+
+// EvictMax evicts the largest key
+func EvictMax(tr *BTree) int64 {
+	return tr.root.at(tr.length-1, tr.height).key
+}
+
+// SetMaxLen limits the tree to n items. Inserting an item beyond n evicts
+// the key chosen by policy, which may be the inserted one, and items over
+// the limit are evicted right away. Items moved in by MoveRange are evicted
+// the same way once they're all in. Evicted values are released and sent to
+// the subscribers like deleted ones. A policy that returns a missing key
+// evicts nothing. Zero or less removes the limit. The limit isn't an
+// option, so the trees of Builder, Compact and CopyRange have none.
+func (tr *BTree) SetMaxLen(n int, policy EvictPolicy) {
+	tr.checkWrite()
+	if n <= 0 || policy == nil {
+		tr.maxLen, tr.evict = 0, nil
+		return
+	}
+	tr.maxLen, tr.evict = n, policy
+	tr.enforceMaxLen()
+}
+
+// enforceMaxLen evicts items until the tree is within its maximum length
+func (tr *BTree) enforceMaxLen() {
+	for tr.maxLen > 0 && tr.length > tr.maxLen {
+		if !tr.evictOne() {
+			return
+		}
+	}
+}
+
+// evictOne deletes the first value of the key chosen by the policy
+func (tr *BTree) evictOne() bool {
+	_, deleted := tr.DeleteOne(tr.evict(tr))
+	return deleted
+}
@@ -0,0 +1,65 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMaxLen(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	tr.SetMaxLen(10, EvictMin)
+	assert.Equal(t, 10, tr.Len())
+	assert.Equal(t, []Item{{90, 90}}, tr.Head(1))
+
+	tr.Set(200, 200)
+	assert.Equal(t, 10, tr.Len())
+	assert.Equal(t, []Item{{91, 91}}, tr.Head(1))
+	// replacing doesn't evict
+	tr.Set(200, 201)
+	assert.Equal(t, []Item{{91, 91}}, tr.Head(1))
+
+	tr.SetMaxLen(5, EvictMax)
+	assert.Equal(t, []Item{{91, 91}, {92, 92}, {93, 93}, {94, 94},
+		{95, 95}}, tr.Head(10))
+	// the inserted key may be the evicted one
+	tr.Set(1000, 0)
+	_, ok := tr.Get(1000)
+	assert.Equal(t, false, ok)
+
+	// a callback choosing the key, with deletes sent to the subscribers
+	var evicted []int64
+	tr.Subscribe(func(e Event) {
+		if e.Kind == EventDelete {
+			evicted = append(evicted, e.Key)
+		}
+	})
+	tr.SetMaxLen(5, func(tr *BTree) int64 {
+		return 93
+	})
+	tr.Set(0, 0)
+	assert.Equal(t, []int64{93}, evicted)
+	assert.Equal(t, 5, tr.Len())
+
+	tr.SetMaxLen(0, nil)
+	tr.Set(1, 1)
+	assert.Equal(t, 6, tr.Len())
+}
+
+func TestSetMaxLenMoveRange(t *testing.T) {
+	var src, dst BTree
+	for i := 0; i < 100; i++ {
+		src.Set(int64(i), i)
+	}
+	dst.SetMaxLen(10, EvictMin)
+	assert.Equal(t, 50, src.MoveRange(&dst, 0, 49))
+	assert.Equal(t, 10, dst.Len())
+	assert.Equal(t, nil, dst.Verify())
+	_, ok := dst.Get(40)
+	assert.Equal(t, true, ok)
+	_, ok = dst.Get(39)
+	assert.Equal(t, false, ok)
+}
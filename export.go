@@ -0,0 +1,121 @@
+package tinybtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ExportCSV writes the items in key order to w as CSV, with a header row
+// of "key,value". The values are formatted by valueFmt, which defaults to
+// fmt.Sprint when nil.
+func (tr *BTree) ExportCSV(
+	w io.Writer,
+	valueFmt func(value interface{}) string,
+) (err error) {
+	span := tr.span("ExportCSV")
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	defer func() { span.End(err) }()
+	if valueFmt == nil {
+		valueFmt = func(value interface{}) string {
+			return fmt.Sprint(value)
+		}
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"key", "value"}); err != nil {
+		return err
+	}
+	record := make([]string, 2)
+	tr.Scan(func(key int64, value interface{}) bool {
+		record[0] = strconv.FormatInt(key, 10)
+		record[1] = valueFmt(value)
+		err = cw.Write(record)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ndjsonRecord is a line of ExportNDJSON
+type ndjsonRecord struct {
+	Key   int64       `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// ExportNDJSON writes the items in key order to w as newline delimited
+// JSON, one {"key":...,"value":...} object per line. The values are encoded
+// with encoding/json.
+func (tr *BTree) ExportNDJSON(w io.Writer) (err error) {
+	span := tr.span("ExportNDJSON")
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	defer func() { span.End(err) }()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	tr.Scan(func(key int64, value interface{}) bool {
+		err = enc.Encode(ndjsonRecord{Key: key, Value: value})
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ExportRecords writes the items in key order to w as binary records, each
+// being the key as 8 big-endian bytes, the length of the value as 4
+// big-endian bytes and the value, for tools that read fixed-width headers
+// such as encoding/binary or a reader of a memory-mapped file. The values
+// are encoded by marshal, which defaults to taking byte slices and strings
+// as they are when nil.
+func (tr *BTree) ExportRecords(
+	w io.Writer,
+	marshal func(value interface{}) ([]byte, error),
+) (err error) {
+	span := tr.span("ExportRecords")
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	defer func() { span.End(err) }()
+	if marshal == nil {
+		marshal = marshalRecord
+	}
+	bw := bufio.NewWriter(w)
+	var hdr [12]byte
+	tr.Scan(func(key int64, value interface{}) bool {
+		var data []byte
+		data, err = marshal(value)
+		if err != nil {
+			return false
+		}
+		if uint64(len(data)) > math.MaxUint32 {
+			err = fmt.Errorf("tinybtree: value of key %d is too long", key)
+			return false
+		}
+		binary.BigEndian.PutUint64(hdr[:8], uint64(key))
+		binary.BigEndian.PutUint32(hdr[8:], uint32(len(data)))
+		bw.Write(hdr[:])
+		_, err = bw.Write(data)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// marshalRecord is the default value encoding of ExportRecords
+func marshalRecord(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("tinybtree: no record encoding for %T", value)
+}
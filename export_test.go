@@ -0,0 +1,54 @@
+package tinybtree
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCSV(t *testing.T) {
+	var tr BTree
+	tr.Set(2, "b,c")
+	tr.Set(-1, "a")
+	tr.SetInt(3, 7)
+	var buf bytes.Buffer
+	if err := tr.ExportCSV(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "key,value\n-1,a\n2,\"b,c\"\n3,7\n", buf.String())
+
+	buf.Reset()
+	err := tr.ExportCSV(&buf, func(value interface{}) string {
+		return fmt.Sprintf("<%v>", value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "key,value\n-1,<a>\n2,\"<b,c>\"\n3,<7>\n", buf.String())
+}
+
+func TestExportNDJSON(t *testing.T) {
+	var tr BTree
+	var buf bytes.Buffer
+	if err := tr.ExportNDJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "", buf.String())
+	tr.Set(2, map[string]int{"x": 1})
+	tr.Set(1, "a")
+	tr.Set(3, nil)
+	if err := tr.ExportNDJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"key":1,"value":"a"}
+{"key":2,"value":{"x":1}}
+{"key":3,"value":null}
+`, buf.String())
+
+	tr.Set(4, func() {})
+	if err := tr.ExportNDJSON(&buf); err == nil {
+		t.Fatal("expected error")
+	}
+}
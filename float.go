@@ -0,0 +1,52 @@
+package tinybtree
+
+import "math"
+
+// canonicalNaN is the bits of the NaN that all NaNs are turned into by
+// FloatKey
+const canonicalNaN = 0x7FF8000000000001
+
+// FloatKey returns a key for a float64 so that the keys are in the order of
+// the floats. The order is total: -0.0 comes right before +0.0, and every
+// NaN is turned into the same key, which comes after +Inf. Casting the bits
+// of a float to an int64 instead puts the negative floats in reverse order.
+func FloatKey(f float64) int64 {
+	if f != f {
+		return canonicalNaN
+	}
+	bits := int64(math.Float64bits(f))
+	if bits < 0 {
+		// negative floats are ordered by their magnitude, flip it
+		bits ^= math.MaxInt64
+	}
+	return bits
+}
+
+// KeyFloat returns the float64 of a key of FloatKey
+func KeyFloat(key int64) float64 {
+	if key < 0 {
+		key ^= math.MaxInt64
+	}
+	return math.Float64frombits(uint64(key))
+}
+
+// AscendFloat visits the items starting with the float pivot, see FloatKey
+func (tr *BTree) AscendFloat(
+	pivot float64,
+	iter func(key float64, value interface{}) bool,
+) {
+	tr.Ascend(FloatKey(pivot), func(key int64, value interface{}) bool {
+		return iter(KeyFloat(key), value)
+	})
+}
+
+// DescendFloat visits the items starting with the float pivot in reverse
+// order, see FloatKey
+func (tr *BTree) DescendFloat(
+	pivot float64,
+	iter func(key float64, value interface{}) bool,
+) {
+	tr.Descend(FloatKey(pivot), func(key int64, value interface{}) bool {
+		return iter(KeyFloat(key), value)
+	})
+}
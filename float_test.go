@@ -0,0 +1,62 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloatKey(t *testing.T) {
+	ordered := []float64{
+		math.Inf(-1), -math.MaxFloat64, -1e10, -1, -math.SmallestNonzeroFloat64,
+		math.Copysign(0, -1), 0, math.SmallestNonzeroFloat64, 0.5, 1, 1e10,
+		math.MaxFloat64, math.Inf(1), math.NaN(),
+	}
+	for i := 1; i < len(ordered); i++ {
+		if FloatKey(ordered[i-1]) >= FloatKey(ordered[i]) {
+			t.Fatalf("expected %v < %v", ordered[i-1], ordered[i])
+		}
+	}
+	for _, f := range ordered[:len(ordered)-1] {
+		assert.Equal(t, math.Float64bits(f), math.Float64bits(KeyFloat(FloatKey(f))))
+	}
+	assert.Equal(t, true, math.IsNaN(KeyFloat(FloatKey(math.NaN()))))
+	assert.Equal(t, FloatKey(math.NaN()), FloatKey(-math.NaN()))
+
+	floats := make([]float64, 1000)
+	for i := range floats {
+		floats[i] = rand.NormFloat64() * 1000
+	}
+	keys := make([]int64, len(floats))
+	for i, f := range floats {
+		keys[i] = FloatKey(f)
+	}
+	sort.Float64s(floats)
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for i := range keys {
+		assert.Equal(t, floats[i], KeyFloat(keys[i]))
+	}
+}
+
+func TestAscendFloat(t *testing.T) {
+	var tr BTree
+	for _, f := range []float64{-2.5, -1, 0, 1.5, 3} {
+		tr.Set(FloatKey(f), f)
+	}
+	var keys []float64
+	tr.AscendFloat(-1.5, func(key float64, value interface{}) bool {
+		assert.Equal(t, key, value)
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []float64{-1, 0, 1.5, 3}, keys)
+	keys = nil
+	tr.DescendFloat(1, func(key float64, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []float64{0, -1, -2.5}, keys)
+}
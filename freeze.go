@@ -0,0 +1,20 @@
+package tinybtree
+
+// Freeze makes the tree read-only. Any following write panics with
+// ErrFrozen. A frozen tree may be read and cloned from multiple goroutines
+// without locking, as long as its Retainer, if any, is safe for concurrent
+// use, as Clone retains every value.
+func (tr *BTree) Freeze() {
+	tr.frozen = true
+}
+
+// Frozen returns true if the tree was frozen
+func (tr *BTree) Frozen() bool {
+	return tr.frozen
+}
+
+func (tr *BTree) checkWrite() {
+	if tr.frozen {
+		panic(ErrFrozen)
+	}
+}
@@ -0,0 +1,66 @@
+package tinybtree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	var tr BTree
+	tr.Set(1, 1)
+	tr.Freeze()
+	if !tr.Frozen() {
+		t.Fatal("expected true")
+	}
+	v, ok := tr.Get(1)
+	if !ok || v != 1 {
+		t.Fatalf("expected 1, got %v", v)
+	}
+	writes := []func(){
+		func() { tr.Set(2, 2) },
+		func() { tr.Delete(1) },
+		func() { tr.DeleteOne(1) },
+		func() { tr.DeleteFn(1, func(interface{}) {}) },
+	}
+	for _, write := range writes {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrFrozen {
+					t.Fatalf("expected %v, got %v", ErrFrozen, r)
+				}
+			}()
+			write()
+		}()
+	}
+	assert.Equal(t, 1, tr.Len())
+}
+
+func TestFrozenClone(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i), i)
+	}
+	tr.Freeze()
+	gen := tr.gen
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				c := tr.Clone()
+				c.Set(int64(i), -g)
+				c.Delete(int64(i + 500))
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, gen, tr.gen)
+	assert.Equal(t, nil, tr.Verify())
+	for i := 0; i < 1000; i++ {
+		v, _ := tr.Get(int64(i))
+		assert.Equal(t, i, v)
+	}
+}
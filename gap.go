@@ -0,0 +1,103 @@
+package tinybtree
+
+import "math"
+
+// FirstGap returns the smallest key greater or equal to from that is not in
+// the tree. It uses the subtree counts to skip over ranges of consecutive
+// keys, which takes O(log n) time no matter how dense the keys are. Returns
+// false when all keys from from up to math.MaxInt64 are in the tree.
+func (tr *BTree) FirstGap(from int64) (key int64, ok bool) {
+	if tr.root == nil {
+		return from, true
+	}
+	if tr.multi {
+		// duplicates break the counting, walk the keys instead
+		key, ok = from, true
+		tr.Ascend(from, func(k int64, _ interface{}) bool {
+			if k > key {
+				return false
+			}
+			if k == key {
+				if key == math.MaxInt64 {
+					ok = false
+					return false
+				}
+				key++
+			}
+			return true
+		})
+		return key, ok
+	}
+	// The keys are distinct, so key-index never decreases with the index.
+	// When the keys from the rank of from onwards are consecutive it stays
+	// the same, and the first index where it grows is the first gap.
+	d := from - int64(tr.root.rank(from, tr.height))
+	index, found := tr.root.gap(d, 0, tr.height)
+	if !found {
+		index = tr.length
+		if d+int64(index-1) == math.MaxInt64 {
+			return 0, false
+		}
+	}
+	return d + int64(index), true
+}
+
+// gap returns the index of the first item where key-index is greater than d,
+// where base is the index of the first item of the subtree
+func (n *node) gap(d int64, base, height int) (index int, found bool) {
+	if height == 0 {
+		for i := 0; i < n.numItems; i++ {
+			if n.keys[i]-int64(base+i) > d {
+				return base + i, true
+			}
+		}
+		return 0, false
+	}
+	for i := 0; i < n.numItems; i++ {
+		index = base + n.children[i].count
+		if n.keys[i]-int64(index) > d {
+			if j, ok := n.children[i].gap(d, base, height-1); ok {
+				return j, true
+			}
+			return index, true
+		}
+		base = index + 1
+	}
+	return n.children[n.numItems].gap(d, base, height-1)
+}
+
+// GapStats describes the gaps between consecutive keys of a tree
+type GapStats struct {
+	Count int     // number of gaps, one less than the number of items
+	Min   uint64  // smallest gap, zero with duplicate keys
+	Max   uint64  // largest gap
+	Mean  float64 // mean gap
+}
+
+// GapStats returns the smallest, largest and mean gap between consecutive
+// keys in one pass over the tree. All are zero with fewer than two items.
+func (tr *BTree) GapStats() GapStats {
+	var s GapStats
+	if tr.length < 2 {
+		return s
+	}
+	s.Min = math.MaxUint64
+	first := true
+	var prev int64
+	tr.Scan(func(key int64, _ interface{}) bool {
+		if !first {
+			gap := uint64(key - prev)
+			if gap < s.Min {
+				s.Min = gap
+			}
+			if gap > s.Max {
+				s.Max = gap
+			}
+		}
+		first, prev = false, key
+		return true
+	})
+	s.Count = tr.length - 1
+	s.Mean = float64(uint64(tr.max-tr.min)) / float64(s.Count)
+	return s
+}
@@ -0,0 +1,84 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstGap(t *testing.T) {
+	var tr BTree
+	key, ok := tr.FirstGap(5)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(5), key)
+	for i := 0; i < 10000; i++ {
+		tr.Set(int64(i), nil)
+	}
+	tr.Delete(7000)
+	tests := []struct{ from, key int64 }{
+		{-10, -10}, {0, 7000}, {6999, 7000}, {7000, 7000}, {7001, 10000},
+		{10000, 10000}, {20000, 20000},
+	}
+	for _, tt := range tests {
+		key, ok := tr.FirstGap(tt.from)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, tt.key, key)
+	}
+
+	tr = BTree{}
+	tr.Set(math.MaxInt64-1, nil)
+	tr.Set(math.MaxInt64, nil)
+	_, ok = tr.FirstGap(math.MaxInt64 - 1)
+	assert.Equal(t, false, ok)
+	key, _ = tr.FirstGap(math.MaxInt64 - 2)
+	assert.Equal(t, int64(math.MaxInt64-2), key)
+}
+
+func TestFirstGapRandom(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Multi: multi})
+		present := make(map[int64]bool)
+		for i := 0; i < 5000; i++ {
+			key := int64(rand.Intn(6000))
+			tr.Set(key, nil)
+			present[key] = true
+		}
+		for i := 0; i < 1000; i++ {
+			from := int64(rand.Intn(7000) - 500)
+			expect := from
+			for present[expect] {
+				expect++
+			}
+			key, ok := tr.FirstGap(from)
+			assert.Equal(t, true, ok)
+			assert.Equal(t, expect, key)
+		}
+	}
+}
+
+func TestGapStats(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, GapStats{}, tr.GapStats())
+	tr.Set(5, nil)
+	assert.Equal(t, GapStats{}, tr.GapStats())
+	for _, key := range []int64{7, 10, 20} {
+		tr.Set(key, nil)
+	}
+	assert.Equal(t, GapStats{Count: 3, Min: 2, Max: 10, Mean: 5}, tr.GapStats())
+
+	tr.Set(math.MinInt64, nil)
+	tr.Set(math.MaxInt64, nil)
+	s := tr.GapStats()
+	assert.Equal(t, uint64(2), s.Min)
+	assert.Equal(t, uint64(1<<63+5), s.Max)
+	assert.Equal(t, float64(math.MaxUint64)/5, s.Mean)
+
+	multi := New(&Options{Multi: true})
+	multi.Set(1, nil)
+	multi.Set(1, nil)
+	multi.Set(4, nil)
+	assert.Equal(t, GapStats{Count: 2, Min: 0, Max: 3, Mean: 1.5},
+		multi.GapStats())
+}
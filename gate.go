@@ -0,0 +1,33 @@
+package tinybtree
+
+import "context"
+
+// SetContext is like Set but first calls the Gate of the options with ctx.
+// When the gate returns an error the value is not set and the error is
+// returned.
+func (tr *BTree) SetContext(
+	ctx context.Context, key int64, value interface{},
+) (prev interface{}, replaced bool, err error) {
+	if tr.gate != nil {
+		if err := tr.gate(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+	prev, replaced = tr.Set(key, value)
+	return prev, replaced, nil
+}
+
+// DeleteContext is like Delete but first calls the Gate of the options
+// with ctx. When the gate returns an error nothing is deleted and the error
+// is returned.
+func (tr *BTree) DeleteContext(ctx context.Context, key int64) (
+	prev interface{}, deleted bool, err error,
+) {
+	if tr.gate != nil {
+		if err := tr.gate(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+	prev, deleted = tr.Delete(key)
+	return prev, deleted, nil
+}
@@ -0,0 +1,46 @@
+package tinybtree
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGate(t *testing.T) {
+	var calls int
+	tr := New(&Options{Gate: func(ctx context.Context) error {
+		calls++
+		return ctx.Err()
+	}})
+	ctx := context.Background()
+	_, _, err := tr.SetContext(ctx, 1, "a")
+	assert.Equal(t, nil, err)
+	prev, replaced, err := tr.SetContext(ctx, 1, "b")
+	assert.Equal(t, "a", prev)
+	assert.Equal(t, true, replaced)
+	assert.Equal(t, nil, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = tr.SetContext(canceled, 2, "c")
+	assert.Equal(t, context.Canceled, err)
+	_, deleted, err := tr.DeleteContext(canceled, 1)
+	assert.Equal(t, false, deleted)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, tr.Len())
+
+	prev, deleted, err = tr.DeleteContext(ctx, 1)
+	assert.Equal(t, "b", prev)
+	assert.Equal(t, true, deleted)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, calls)
+
+	// writes without a context and trees without a gate aren't gated
+	tr.Set(3, nil)
+	assert.Equal(t, 5, calls)
+	var plain BTree
+	_, _, err = plain.SetContext(canceled, 1, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, plain.Len())
+}
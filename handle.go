@@ -0,0 +1,8 @@
+package tinybtree
+
+//go:generate go run ./cmd/tinybtree-gen -name HandleTree -key int64 -value uint64 -less lessInt64 -doc "It holds values that are kept outside of the tree, such as in a slice that is managed by the caller, and are referred to by a uint64 handle. Its items hold no pointers, so the garbage collector only has to trace the links between the nodes, not one pointer per value."
+
+// lessInt64 is the less function of HandleTree
+func lessInt64(a, b int64) bool {
+	return a < b
+}
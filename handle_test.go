@@ -0,0 +1,48 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleTree(t *testing.T) {
+	var tr HandleTree
+	m := map[int64]uint64{}
+	for i := 0; i < 10000; i++ {
+		key := int64(rand.Intn(3000))
+		if i%4 == 0 {
+			tr.Delete(key)
+			delete(m, key)
+		} else {
+			tr.Set(key, uint64(i))
+			m[key] = uint64(i)
+		}
+	}
+	assert.Equal(t, len(m), tr.Len())
+	for key, handle := range m {
+		value, ok := tr.Get(key)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, handle, value)
+	}
+	var last int64 = -1
+	tr.Scan(func(key int64, value uint64) bool {
+		if key <= last {
+			t.Fatalf("out of order %d after %d", key, last)
+		}
+		last = key
+		return true
+	})
+
+	// the items hold no pointers
+	typ := reflect.TypeOf(handleTreeItem{})
+	for i := 0; i < typ.NumField(); i++ {
+		switch typ.Field(i).Type.Kind() {
+		case reflect.Int64, reflect.Uint64:
+		default:
+			t.Fatalf("field %s is a %s", typ.Field(i).Name, typ.Field(i).Type)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package tinybtree
+
+// Head returns the n items with the smallest keys in key order
+func (tr *BTree) Head(n int) []Item {
+	if n > tr.length {
+		n = tr.length
+	}
+	if n <= 0 {
+		return nil
+	}
+	items := make([]Item, 0, n)
+	tr.Scan(func(key int64, value interface{}) bool {
+		items = append(items, Item{key, value})
+		return len(items) < n
+	})
+	return items
+}
+
+// Tail returns the n items with the largest keys in key order. The tree is
+// walked from the right, so only those items are visited.
+func (tr *BTree) Tail(n int) []Item {
+	if n > tr.length {
+		n = tr.length
+	}
+	if n <= 0 {
+		return nil
+	}
+	items := make([]Item, n)
+	i := n
+	tr.Reverse(func(key int64, value interface{}) bool {
+		i--
+		items[i] = Item{key, value}
+		return i > 0
+	})
+	return items[i:]
+}
@@ -0,0 +1,23 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadTail(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, []Item(nil), tr.Head(3))
+	assert.Equal(t, []Item(nil), tr.Tail(3))
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	assert.Equal(t, []Item{{0, 0}, {1, 1}, {2, 2}}, tr.Head(3))
+	assert.Equal(t, []Item{{97, 97}, {98, 98}, {99, 99}}, tr.Tail(3))
+	assert.Equal(t, 100, len(tr.Head(1000)))
+	tail := tr.Tail(1000)
+	assert.Equal(t, 100, len(tail))
+	assert.Equal(t, Item{0, 0}, tail[0])
+	assert.Equal(t, []Item(nil), tr.Tail(0))
+}
@@ -0,0 +1,121 @@
+package tinybtree
+
+import (
+	"sort"
+	"time"
+)
+
+// History keeps earlier versions of a tree for queries such as how the
+// tree looked five minutes ago. Commit records the current contents as a
+// new version, which is a clone that shares its nodes with the tree, see
+// Clone, so a version only costs the nodes that were changed after it.
+// Up to depth versions are kept, older ones are pruned. A History is not
+// safe for concurrent use, the versions it returns are frozen and may be
+// read from any goroutine.
+type History struct {
+	tr       *BTree
+	depth    int
+	versions []historyVersion // oldest first
+	last     uint64
+}
+
+type historyVersion struct {
+	version uint64
+	at      time.Time
+	tr      *BTree
+}
+
+// NewHistory returns a History of tr that keeps up to depth versions, no
+// limit when depth is zero. The tree is changed as usual and a version of
+// it is recorded with every Commit.
+func NewHistory(tr *BTree, depth int) *History {
+	return &History{tr: tr, depth: depth}
+}
+
+// Tree returns the tree whose versions are kept
+func (h *History) Tree() *BTree {
+	return h.tr
+}
+
+// Commit records the current contents of the tree as a new version and
+// returns its number, which starts at one and goes up by one with every
+// commit.
+func (h *History) Commit() (version uint64) {
+	c := h.tr.Clone()
+	c.Freeze()
+	h.last++
+	h.versions = append(h.versions, historyVersion{h.last, time.Now(), c})
+	if h.depth > 0 && len(h.versions) > h.depth {
+		h.drop(len(h.versions) - h.depth)
+	}
+	return h.last
+}
+
+// AsOf returns the tree as of the version, which is the latest version
+// that is not newer than it. Returns nil when no such version is kept.
+func (h *History) AsOf(version uint64) *BTree {
+	i := sort.Search(len(h.versions), func(i int) bool {
+		return h.versions[i].version > version
+	})
+	if i == 0 {
+		return nil
+	}
+	return h.versions[i-1].tr
+}
+
+// AsOfTime returns the tree as of the time t, which is the latest version
+// that was committed at or before t. Returns nil when no such version is
+// kept.
+func (h *History) AsOfTime(t time.Time) *BTree {
+	i := sort.Search(len(h.versions), func(i int) bool {
+		return h.versions[i].at.After(t)
+	})
+	if i == 0 {
+		return nil
+	}
+	return h.versions[i-1].tr
+}
+
+// Versions returns the numbers of the kept versions, oldest first
+func (h *History) Versions() []uint64 {
+	versions := make([]uint64, len(h.versions))
+	for i, v := range h.versions {
+		versions[i] = v.version
+	}
+	return versions
+}
+
+// Prune drops the versions older than version and returns how many were
+// dropped. The nodes that only they used are left to the garbage
+// collector. A version must not be used after it was dropped.
+func (h *History) Prune(version uint64) int {
+	i := sort.Search(len(h.versions), func(i int) bool {
+		return h.versions[i].version >= version
+	})
+	h.drop(i)
+	return i
+}
+
+// PruneBefore drops the versions committed before t, see Prune
+func (h *History) PruneBefore(t time.Time) int {
+	i := sort.Search(len(h.versions), func(i int) bool {
+		return !h.versions[i].at.Before(t)
+	})
+	h.drop(i)
+	return i
+}
+
+// drop forgets the n oldest versions. Their values are released when the
+// tree has a Retainer, as Clone retained them.
+func (h *History) drop(n int) {
+	for i := 0; i < n; i++ {
+		if v := h.versions[i].tr; v.ret != nil {
+			// a frozen tree still owns the nodes it shares with its
+			// clones, see Clone
+			v.frozen, v.gen, v.recycling = false, nextGen(), false
+			v.Clear()
+		}
+		h.versions[i] = historyVersion{}
+	}
+	h.versions = h.versions[n:]
+}
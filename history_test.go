@@ -0,0 +1,60 @@
+package tinybtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	var tr BTree
+	h := NewHistory(&tr, 3)
+	assert.Equal(t, (*BTree)(nil), h.AsOf(1))
+	for v := 1; v <= 5; v++ {
+		for i := 0; i < 100; i++ {
+			tr.Set(int64(i), v)
+		}
+		tr.Set(int64(100+v), v)
+		assert.Equal(t, uint64(v), h.Commit())
+	}
+	tr.Delete(0)
+	assert.Equal(t, []uint64{3, 4, 5}, h.Versions())
+	assert.Equal(t, (*BTree)(nil), h.AsOf(2))
+	for v := 3; v <= 5; v++ {
+		old := h.AsOf(uint64(v))
+		assert.Equal(t, 100+v, old.Len())
+		value, _ := old.Get(0)
+		assert.Equal(t, v, value)
+		if !old.Frozen() {
+			t.Fatal("expected a frozen version")
+		}
+	}
+	assert.Equal(t, h.AsOf(5), h.AsOf(10))
+	assert.Equal(t, h.AsOf(5), h.AsOfTime(time.Now()))
+	assert.Equal(t, (*BTree)(nil), h.AsOfTime(time.Now().Add(-time.Hour)))
+
+	assert.Equal(t, 1, h.Prune(4))
+	assert.Equal(t, []uint64{4, 5}, h.Versions())
+	assert.Equal(t, 2, h.PruneBefore(time.Now()))
+	assert.Equal(t, 0, len(h.Versions()))
+	assert.Equal(t, &tr, h.Tree())
+}
+
+func TestHistoryRetainer(t *testing.T) {
+	refs := refCounter{}
+	tr := New(&Options{Retainer: refs})
+	h := NewHistory(tr, 2)
+	for v := 0; v < 5; v++ {
+		tr.Set(int64(v), v)
+		h.Commit()
+	}
+	// held by the tree and the two kept versions
+	assert.Equal(t, 2, refs[4])
+	assert.Equal(t, 3, refs[3])
+	assert.Equal(t, 3, refs[0])
+	h.Prune(10)
+	assert.Equal(t, 1, refs[0])
+	tr.Clear()
+	assert.Equal(t, 0, len(refs))
+}
@@ -0,0 +1,133 @@
+package tinybtree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LineError is a line of an import that could not be parsed
+type LineError struct {
+	Line int // the line number, starting at one
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("tinybtree: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the error of the parse function
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// ImportStats are the totals of an import
+type ImportStats struct {
+	Lines    int         // number of non-empty lines
+	Imported int         // number of lines that were set in the tree
+	Errors   []LineError // the lines that failed to parse, in order
+}
+
+// ImportNDJSON reads newline delimited records from r, parses each
+// non-empty line with parse and sets the resulting items in the tree. Lines
+// that fail to parse are skipped and reported in the stats. The returned
+// error is only set when reading from r fails. When parse is nil the lines
+// are expected in the format of ExportNDJSON, with the values decoded by
+// encoding/json.
+func (tr *BTree) ImportNDJSON(
+	r io.Reader,
+	parse func(line []byte) (key int64, value interface{}, err error),
+) (stats ImportStats, err error) {
+	span := tr.span("ImportNDJSON")
+	defer func() {
+		span.SetAttribute("tinybtree.items", int64(stats.Imported))
+		span.SetAttribute("tinybtree.errors", int64(len(stats.Errors)))
+		span.End(err)
+	}()
+	if parse == nil {
+		parse = parseNDJSON
+	}
+	br := bufio.NewReader(r)
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			stats.Lines++
+			key, value, perr := parse(bytes.TrimRight(line, "\r\n"))
+			if perr != nil {
+				stats.Errors = append(stats.Errors, LineError{lineNum, perr})
+			} else {
+				tr.Set(key, value)
+				stats.Imported++
+			}
+		}
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, err
+		}
+	}
+}
+
+// parseNDJSON parses a line that was written by ExportNDJSON
+func parseNDJSON(line []byte) (key int64, value interface{}, err error) {
+	var rec struct {
+		Key   *int64      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return 0, nil, err
+	}
+	if rec.Key == nil {
+		return 0, nil, errors.New("missing key")
+	}
+	return *rec.Key, rec.Value, nil
+}
+
+// ImportRecords reads the binary records of ExportRecords from r and sets
+// their items in the tree, returning the number of records. The values are
+// decoded by unmarshal, which defaults to a copy of the bytes when nil. A
+// truncated record is reported as io.ErrUnexpectedEOF.
+func (tr *BTree) ImportRecords(
+	r io.Reader,
+	unmarshal func(data []byte) (interface{}, error),
+) (n int, err error) {
+	span := tr.span("ImportRecords")
+	defer func() {
+		span.SetAttribute("tinybtree.items", int64(n))
+		span.End(err)
+	}()
+	br := bufio.NewReader(r)
+	var hdr [12]byte
+	for ; ; n++ {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		key := int64(binary.BigEndian.Uint64(hdr[:8]))
+		// the buffer grows with the bytes read instead of trusting the
+		// length of a record that may be corrupt
+		var buf bytes.Buffer
+		size := int64(binary.BigEndian.Uint32(hdr[8:]))
+		if _, err := io.CopyN(&buf, br, size); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+		data := buf.Bytes()
+		var value interface{} = data
+		if unmarshal != nil {
+			if value, err = unmarshal(data); err != nil {
+				return n, err
+			}
+		}
+		tr.Set(key, value)
+	}
+}
@@ -0,0 +1,119 @@
+package tinybtree
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportNDJSON(t *testing.T) {
+	var src BTree
+	for i := 0; i < 100; i++ {
+		src.Set(int64(i), strconv.Itoa(i))
+	}
+	var buf bytes.Buffer
+	if err := src.ExportNDJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var tr BTree
+	stats, err := tr.ImportNDJSON(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ImportStats{Lines: 100, Imported: 100}, stats)
+	src.Diff(&tr, func(key int64, l, r interface{}, kind DiffKind) {
+		t.Fatalf("%d %v: %v != %v", key, kind, l, r)
+	})
+
+	// bad lines are reported and skipped
+	input := "{\"key\":1,\"value\":\"a\"}\n\nnot json\r\n" +
+		"{\"value\":2}\n{\"key\":3,\"value\":3}"
+	tr = BTree{}
+	stats, err = tr.ImportNDJSON(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, stats.Lines)
+	assert.Equal(t, 2, stats.Imported)
+	assert.Equal(t, 2, len(stats.Errors))
+	assert.Equal(t, 3, stats.Errors[0].Line)
+	assert.Equal(t, 4, stats.Errors[1].Line)
+	assert.Equal(t, []int64{1, 3}, tr.Keys(nil))
+	v, _ := tr.Get(3)
+	assert.Equal(t, 3.0, v)
+
+	// custom parser
+	errOdd := errors.New("odd")
+	tr = BTree{}
+	stats, _ = tr.ImportNDJSON(strings.NewReader("1\n2\n3\n4\n"),
+		func(line []byte) (int64, interface{}, error) {
+			key, err := strconv.ParseInt(string(line), 10, 64)
+			if err == nil && key%2 == 1 {
+				err = errOdd
+			}
+			return key, nil, err
+		})
+	assert.Equal(t, 2, stats.Imported)
+	if !errors.Is(stats.Errors[0], errOdd) {
+		t.Fatalf("expected %v, got %v", errOdd, stats.Errors[0])
+	}
+	assert.Equal(t, []int64{2, 4}, tr.Keys(nil))
+
+	// read errors are returned
+	readErr := errors.New("read failed")
+	_, err = tr.ImportNDJSON(failReader{readErr}, nil)
+	assert.Equal(t, readErr, err)
+}
+
+func TestRecords(t *testing.T) {
+	var tr BTree
+	tr.Set(-1, []byte("minus one"))
+	tr.Set(0, "")
+	tr.Set(1<<40, "big")
+	var buf bytes.Buffer
+	assert.Equal(t, nil, tr.ExportRecords(&buf, nil))
+	data := buf.Bytes()
+	assert.Equal(t, 3*12+9+3, len(data))
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0, 0, 0, 9, 'm'}, data[:13])
+
+	var read BTree
+	n, err := read.ImportRecords(bytes.NewReader(data),
+		func(data []byte) (interface{}, error) {
+			return string(data), nil
+		})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, n)
+	v, _ := read.Get(-1)
+	assert.Equal(t, "minus one", v)
+	v, _ = read.Get(1 << 40)
+	assert.Equal(t, "big", v)
+
+	var raw BTree
+	n, err = raw.ImportRecords(bytes.NewReader(data[:len(data)-1]), nil)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 2, n)
+	v, _ = raw.Get(-1)
+	assert.Equal(t, []byte("minus one"), v)
+
+	// a corrupt length doesn't allocate a value of that size
+	corrupt := []byte{0, 0, 0, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 'x'}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = raw.ImportRecords(bytes.NewReader(corrupt), nil)
+	runtime.ReadMemStats(&after)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Fatalf("expected a small allocation, got %d bytes", alloc)
+	}
+
+	tr.Set(2, 2)
+	assert.Equal(t, "tinybtree: no record encoding for int",
+		tr.ExportRecords(&buf, nil).Error())
+}
@@ -0,0 +1,43 @@
+package tinybtree
+
+// inlineInt is the value of an item that holds an int64 in slot.num, which
+// saves boxing the int64 into an interface{}.
+type inlineInt struct{}
+
+// val returns the value of the slot
+func (s slot) val() interface{} {
+	if _, ok := s.value.(inlineInt); ok {
+		return s.num
+	}
+	return s.value
+}
+
+// int returns the value of the slot when it's an int64
+func (s slot) int() (int64, bool) {
+	switch v := s.value.(type) {
+	case inlineInt:
+		return s.num, true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// SetInt is like Set but stores the int64 value within the node instead of
+// an interface{}, which doesn't allocate. Get and the other accessors return
+// the value as an int64. The prev value is zero when the previous value was
+// not an int64.
+func (tr *BTree) SetInt(key, value int64) (prev int64, replaced bool) {
+	var prevItem item
+	prevItem, replaced = tr.set(item{key: key, slot: slot{value: inlineInt{}, num: value}})
+	prev, _ = prevItem.int()
+	return prev, replaced
+}
+
+// GetInt returns the value for key when the value is an int64
+func (tr *BTree) GetInt(key int64) (value int64, gotten bool) {
+	if it := tr.get(key); it != nil {
+		return it.int()
+	}
+	return 0, false
+}
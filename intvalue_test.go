@@ -0,0 +1,78 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTreeInt(t *testing.T) {
+	var tr BTree
+	for _, i := range rand.Perm(1000) {
+		prev, replaced := tr.SetInt(int64(i), int64(i*10))
+		if replaced || prev != 0 {
+			t.Fatal("expected false")
+		}
+	}
+	tr.Set(1000, "x")
+	tr.Set(1001, int64(10010))
+	for i := int64(0); i < 1000; i++ {
+		v, ok := tr.GetInt(i)
+		if !ok || v != i*10 {
+			t.Fatalf("expected %d, got %d", i*10, v)
+		}
+		iv, ok := tr.Get(i)
+		if !ok || iv != i*10 {
+			t.Fatalf("expected %d, got %v", i*10, iv)
+		}
+	}
+	if _, ok := tr.GetInt(1000); ok {
+		t.Fatal("expected false")
+	}
+	v, ok := tr.GetInt(1001)
+	if !ok || v != 10010 {
+		t.Fatalf("expected 10010, got %d", v)
+	}
+	tr.Scan(func(key int64, value interface{}) bool {
+		if key < 1000 && value != key*10 {
+			t.Fatalf("expected %d, got %v", key*10, value)
+		}
+		return true
+	})
+	prev, replaced := tr.SetInt(5, 1)
+	if !replaced || prev != 50 {
+		t.Fatalf("expected 50, got %d", prev)
+	}
+	pv, deleted := tr.Delete(5)
+	if !deleted || pv != int64(1) {
+		t.Fatalf("expected 1, got %v", pv)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.SetInt(10, 1<<40)
+		tr.GetInt(10)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkBTreeSetInt(b *testing.B) {
+	var tree BTree
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tree.SetInt(int64(n), int64(n)<<20)
+	}
+}
+
+func BenchmarkBTreeGetInt(b *testing.B) {
+	var tree BTree
+	for i := 0; i < 1000000; i++ {
+		tree.SetInt(int64(i), int64(i)<<20)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tree.GetInt(int64(n % 1000000))
+	}
+}
@@ -0,0 +1,180 @@
+package tinybtree
+
+import "runtime"
+
+// iterState carries a traversal through the tree. When the callback inserts
+// or removes items the nodes on the current path may have been split,
+// merged or rebalanced, so the traversal stops and is resumed by seeking to
+// the last visited key again. With duplicate keys the items are told apart
+// only by the count of visited duplicates, so adding or removing duplicates
+// of the last visited key from within the callback may repeat or skip some
+// of them.
+//
+// Iterating must not allocate, which TestIterateAllocs checks. The state is
+// passed down by pointer and must not be captured by closures or stored
+// anywhere, so that it stays on the stack of the caller.
+type iterState struct {
+	cur  *uint64 // modification counter of the tree
+	iter func(key int64, value interface{}) bool
+	mods uint64 // modification counter when the traversal started
+	dups bool   // the tree may hold duplicate keys
+
+	last  int64 // last visited key
+	seen  int   // number of visited items with the last key
+	skip  int   // number of items with the last key to skip after a seek
+	stale bool  // the tree was modified by the callback
+
+	key     int64 // key passed to the running callback
+	calling bool  // the callback is running, see catch
+
+	touched int // sink of BPlusTree prefetches
+	yield   int // yield the processor every this many items, see YieldEvery
+	visited int // number of items visited since the last yield
+}
+
+func (it *iterState) visit(key int64, value interface{}) bool {
+	if it.skip > 0 {
+		if key == it.last {
+			it.skip--
+			return true
+		}
+		it.skip = 0
+	}
+	if it.yield > 0 {
+		if it.visited++; it.visited == it.yield {
+			it.visited = 0
+			runtime.Gosched()
+		}
+	}
+	it.key, it.calling = key, true
+	ok := it.iter(key, value)
+	it.calling = false
+	if !ok {
+		return false
+	}
+	if it.seen > 0 && key == it.last {
+		it.seen++
+	} else {
+		it.last, it.seen = key, 1
+	}
+	if *it.cur != it.mods {
+		it.stale = true
+		return false
+	}
+	return true
+}
+
+// catch turns a panic of the callback into an IterPanic holding the key the
+// callback was called for, which is hard to tell from a stack trace that
+// goes through the recursion of a traversal. It's deferred by the functions
+// that start a traversal, and those further up let the IterPanic pass.
+func (it *iterState) catch() {
+	if !it.calling {
+		return
+	}
+	it.calling = false
+	if r := recover(); r != nil {
+		panic(&IterPanic{Key: it.key, Value: r})
+	}
+}
+
+// resume prepares the state for seeking to the last visited key and returns
+// that key.
+func (it *iterState) resume() int64 {
+	it.stale = false
+	it.skip = it.seen
+	return it.last
+}
+
+// ScanErr scans all items in tree until iter returns an error, which is
+// then returned.
+func (tr *BTree) ScanErr(iter func(key int64, value interface{}) error) (
+	err error,
+) {
+	tr.Scan(func(key int64, value interface{}) bool {
+		err = iter(key, value)
+		return err == nil
+	})
+	return err
+}
+
+// AscendErr ascends the tree within the range [pivot, last] until iter
+// returns an error, which is then returned.
+func (tr *BTree) AscendErr(
+	pivot int64,
+	iter func(key int64, value interface{}) error,
+) (err error) {
+	tr.Ascend(pivot, func(key int64, value interface{}) bool {
+		err = iter(key, value)
+		return err == nil
+	})
+	return err
+}
+
+// ReverseErr reverses all items in tree until iter returns an error, which
+// is then returned.
+func (tr *BTree) ReverseErr(iter func(key int64, value interface{}) error) (
+	err error,
+) {
+	tr.Reverse(func(key int64, value interface{}) bool {
+		err = iter(key, value)
+		return err == nil
+	})
+	return err
+}
+
+// DescendErr descends the tree within the range [pivot, first] until iter
+// returns an error, which is then returned.
+func (tr *BTree) DescendErr(
+	pivot int64,
+	iter func(key int64, value interface{}) error,
+) (err error) {
+	tr.Descend(pivot, func(key int64, value interface{}) bool {
+		err = iter(key, value)
+		return err == nil
+	})
+	return err
+}
+
+// ScanCopy scans all items in tree, passing the copy that copyFn makes of
+// each value to iter instead of the value itself. The copies may be handed
+// to other goroutines while the values in the tree are modified in place.
+func (tr *BTree) ScanCopy(
+	iter func(key int64, value interface{}) bool,
+	copyFn func(value interface{}) interface{},
+) {
+	tr.Scan(func(key int64, value interface{}) bool {
+		return iter(key, copyFn(value))
+	})
+}
+
+// ScanIndexed scans all items in tree, passing the position of each item
+// in key order to iter, counting from zero
+func (tr *BTree) ScanIndexed(
+	iter func(i int, key int64, value interface{}) bool,
+) {
+	i := 0
+	tr.Scan(func(key int64, value interface{}) bool {
+		i++
+		return iter(i-1, key, value)
+	})
+}
+
+// AscendIndexed ascends the tree within the range [pivot, last], passing
+// the position of each item in key order to iter. The position of the
+// first item is found with the subtree counts in O(log n) time. When iter
+// inserts or removes items the positions of the following items are
+// counted on from the last one as if it hadn't.
+func (tr *BTree) AscendIndexed(
+	pivot int64,
+	iter func(i int, key int64, value interface{}) bool,
+) {
+	if tr.root == nil {
+		return
+	}
+	i := tr.root.rank(pivot, tr.height)
+	tr.Ascend(pivot, func(key int64, value interface{}) bool {
+		i++
+		return iter(i-1, key, value)
+	})
+}
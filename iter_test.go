@@ -0,0 +1,287 @@
+package tinybtree
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanMutate(t *testing.T) {
+	var tr BTree
+	const N = 1000
+	for _, i := range rand.Perm(N) {
+		tr.Set(int64(i*2), i)
+	}
+
+	// delete every visited item and insert one after it
+	var keys []int64
+	tr.Scan(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		tr.Delete(key)
+		if key%2 == 0 {
+			tr.Set(key+1, nil)
+		}
+		return true
+	})
+	assert.Equal(t, 2*N, len(keys))
+	for i, key := range keys {
+		assert.Equal(t, int64(i), key)
+	}
+	assert.Equal(t, 0, tr.Len())
+
+	// delete every visited item and the one before it
+	for _, i := range rand.Perm(N) {
+		tr.Set(int64(i), i)
+	}
+	keys = keys[:0]
+	tr.Reverse(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		tr.Delete(key)
+		tr.Delete(key - 1)
+		return true
+	})
+	assert.Equal(t, N/2, len(keys))
+	for i, key := range keys {
+		assert.Equal(t, int64(N-1-i*2), key)
+	}
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanMutateMulti(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	const N, D = 200, 4
+	for i := 0; i < N*D; i++ {
+		tr.Set(int64(i/D), i)
+	}
+
+	// inserting duplicates of visited keys must not repeat items
+	var values []int
+	tr.Ascend(0, func(key int64, value interface{}) bool {
+		values = append(values, value.(int))
+		if value.(int)%D == 0 {
+			tr.Set(key-1, -1)
+		}
+		return true
+	})
+	assert.Equal(t, N*D, len(values))
+	for i, value := range values {
+		assert.Equal(t, i, value)
+	}
+
+	// neither must inserting duplicates of keys that were already passed
+	values = values[:0]
+	tr.Descend(N, func(key int64, value interface{}) bool {
+		if value.(int) >= 0 {
+			values = append(values, value.(int))
+		}
+		tr.Set(key+1, -2)
+		return true
+	})
+	assert.Equal(t, N*D, len(values))
+	for i, value := range values {
+		assert.Equal(t, N*D-1-i, value)
+	}
+}
+
+func TestScanErr(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	errStop := errors.New("stop")
+	stopAt := func(stop int64, keys *[]int64) func(int64, interface{}) error {
+		return func(key int64, value interface{}) error {
+			*keys = append(*keys, key)
+			if key == stop {
+				return errStop
+			}
+			return nil
+		}
+	}
+	var keys []int64
+	assert.Equal(t, errStop, tr.ScanErr(stopAt(9, &keys)))
+	assert.Equal(t, 10, len(keys))
+	keys = keys[:0]
+	assert.Equal(t, nil, tr.ScanErr(stopAt(-1, &keys)))
+	assert.Equal(t, 100, len(keys))
+	keys = keys[:0]
+	assert.Equal(t, errStop, tr.AscendErr(50, stopAt(59, &keys)))
+	assert.Equal(t, 10, len(keys))
+	keys = keys[:0]
+	assert.Equal(t, errStop, tr.ReverseErr(stopAt(90, &keys)))
+	assert.Equal(t, 10, len(keys))
+	keys = keys[:0]
+	assert.Equal(t, errStop, tr.DescendErr(50, stopAt(41, &keys)))
+	assert.Equal(t, 10, len(keys))
+}
+
+// TestIterateAllocs makes sure that iterating doesn't allocate
+func TestIterateAllocs(t *testing.T) {
+	var tr BTree
+	var bp BPlusTree
+	for i := 0; i < 10000; i++ {
+		tr.Set(int64(i), i)
+		bp.Set(int64(i), i)
+	}
+	var count int
+	iter := func(key int64, value interface{}) bool {
+		count++
+		return count%1000 != 0
+	}
+	iterErr := func(key int64, value interface{}) error {
+		return nil
+	}
+	for name, fn := range map[string]func(){
+		"Scan":           func() { tr.Scan(iter) },
+		"ScanFrom":       func() { tr.ScanFrom(5000, iter) },
+		"Sample":         func() { tr.Sample(3, iter) },
+		"Ascend":         func() { tr.Ascend(5000, iter) },
+		"Reverse":        func() { tr.Reverse(iter) },
+		"Descend":        func() { tr.Descend(5000, iter) },
+		"GreaterOrEqual": func() { tr.GreaterOrEqual(5000, iter) },
+		"LessOrEqual":    func() { tr.LessOrEqual(5000, iter) },
+		"AscendErr":      func() { tr.AscendErr(9990, iterErr) },
+		"DescendErr":     func() { tr.DescendErr(10, iterErr) },
+		"BPlusScan":      func() { bp.Scan(iter) },
+		"BPlusAscend":    func() { bp.Ascend(5000, iter) },
+		"BPlusReverse":   func() { bp.Reverse(iter) },
+		"BPlusDescend":   func() { bp.Descend(5000, iter) },
+	} {
+		if allocs := testing.AllocsPerRun(100, fn); allocs != 0 {
+			t.Fatalf("%s: expected no allocations, got %v", name, allocs)
+		}
+	}
+}
+
+func TestYieldEvery(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	tr := New(&Options{YieldEvery: 100})
+	for i := 0; i < 10000; i++ {
+		tr.Set(int64(i), i)
+	}
+	// with a single P the goroutine only runs when the scan yields
+	var ran int32
+	go atomic.StoreInt32(&ran, 1)
+	var visited int
+	tr.Scan(func(key int64, value interface{}) bool {
+		if atomic.LoadInt32(&ran) != 0 {
+			return false
+		}
+		visited++
+		return true
+	})
+	if visited >= 10000 {
+		t.Fatal("expected the scan to yield")
+	}
+}
+
+func TestIterPanic(t *testing.T) {
+	var tr BTree
+	var bp BPlusTree
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i), i)
+		bp.Set(int64(i), i)
+	}
+	errBoom := errors.New("boom")
+	iter := func(key int64, value interface{}) bool {
+		if key == 500 {
+			panic(errBoom)
+		}
+		return true
+	}
+	for name, fn := range map[string]func(){
+		"Scan":         func() { tr.Scan(iter) },
+		"ScanFrom":     func() { tr.ScanFrom(100, iter) },
+		"Sample":       func() { tr.Sample(1, iter) },
+		"Ascend":       func() { tr.Ascend(100, iter) },
+		"Reverse":      func() { tr.Reverse(iter) },
+		"Descend":      func() { tr.Descend(900, iter) },
+		"BPlusScan":    func() { bp.Scan(iter) },
+		"BPlusReverse": func() { bp.Reverse(iter) },
+		"Resume": func() {
+			tr.AscendResume(&Resume{}, iter)
+		},
+		"Mutate": func() {
+			// the panic happens after the scan was resumed
+			tr.Scan(func(key int64, value interface{}) bool {
+				tr.Set(key, value)
+				return iter(key, value)
+			})
+		},
+	} {
+		func() {
+			defer func() {
+				p, ok := recover().(*IterPanic)
+				if !ok {
+					t.Fatalf("%s: expected an IterPanic", name)
+				}
+				assert.Equal(t, int64(500), p.Key)
+				assert.Equal(t, true, errors.Is(p, errBoom))
+			}()
+			fn()
+		}()
+	}
+
+	// a panic that isn't from the callback passes through as it is
+	func() {
+		defer func() {
+			assert.Equal(t, "outer", recover())
+		}()
+		tr.Scan(func(key int64, value interface{}) bool {
+			return false
+		})
+		panic("outer")
+	}()
+}
+
+func TestScanCopy(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), &[1]int{i})
+	}
+	var copies []*[1]int
+	tr.ScanCopy(func(key int64, value interface{}) bool {
+		copies = append(copies, value.(*[1]int))
+		return key < 49
+	}, func(value interface{}) interface{} {
+		c := *value.(*[1]int)
+		return &c
+	})
+	assert.Equal(t, 50, len(copies))
+	tr.Scan(func(key int64, value interface{}) bool {
+		value.(*[1]int)[0] = -1
+		return true
+	})
+	for i, c := range copies {
+		assert.Equal(t, i, c[0])
+	}
+}
+
+func TestScanIndexed(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i*2), i)
+	}
+	var n int
+	tr.ScanIndexed(func(i int, key int64, value interface{}) bool {
+		assert.Equal(t, n, i)
+		assert.Equal(t, i, value)
+		n++
+		return true
+	})
+	assert.Equal(t, 1000, n)
+	n = 0
+	tr.AscendIndexed(501, func(i int, key int64, value interface{}) bool {
+		assert.Equal(t, 251+n, i)
+		assert.Equal(t, i, value)
+		n++
+		return n < 10
+	})
+	assert.Equal(t, 10, n)
+}
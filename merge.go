@@ -0,0 +1,71 @@
+package tinybtree
+
+import "container/heap"
+
+// ScanMerged visits the items of all trees in key order, as if they were
+// one tree. Items with the same key are visited in the order of the trees,
+// and when dedup is set only the first of them is visited, so earlier trees
+// shadow later ones. The trees must not be modified during the scan.
+func ScanMerged(
+	trees []*BTree, dedup bool,
+	iter func(key int64, value interface{}) bool,
+) {
+	mergeTrees(trees, dedup, func(it item) bool {
+		return iter(it.key, it.val())
+	})
+}
+
+func mergeTrees(trees []*BTree, dedup bool, iter func(it item) bool) {
+	h := make(mergeHeap, 0, len(trees))
+	for i, tr := range trees {
+		c := newCursor(tr)
+		if c.first() {
+			h = append(h, mergeCursor{c, i})
+		}
+	}
+	heap.Init(&h)
+	var last int64
+	var visited bool
+	for len(h) > 0 {
+		it := h[0].c.item()
+		if !dedup || !visited || it.key != last {
+			if !iter(it) {
+				return
+			}
+			last, visited = it.key, true
+		}
+		if h[0].c.next() {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+}
+
+// mergeCursor is a cursor of the tree at index in the list of trees
+type mergeCursor struct {
+	c     *cursor
+	index int
+}
+
+// mergeHeap orders the cursors by their current key and then by the order
+// of their trees
+type mergeHeap []mergeCursor
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	a, b := h[i].c.item().key, h[j].c.item().key
+	return a < b || (a == b && h[i].index < h[j].index)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeCursor)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,65 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanMerged(t *testing.T) {
+	trees := make([]*BTree, 4)
+	var all []int64
+	first := map[int64]int{}
+	for i := range trees {
+		trees[i] = new(BTree)
+		for j := 0; j < 1000; j++ {
+			key := int64(rand.Intn(3000))
+			if _, ok := trees[i].Set(key, i); !ok {
+				all = append(all, key)
+			}
+		}
+	}
+	for i := len(trees) - 1; i >= 0; i-- {
+		trees[i].Scan(func(key int64, value interface{}) bool {
+			first[key] = i
+			return true
+		})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	var keys []int64
+	var lastKey int64 = -1
+	var lastTree int
+	ScanMerged(trees, false, func(key int64, value interface{}) bool {
+		if key == lastKey && value.(int) <= lastTree {
+			t.Fatalf("%d: tree %d after tree %d", key, value, lastTree)
+		}
+		keys = append(keys, key)
+		lastKey, lastTree = key, value.(int)
+		return true
+	})
+	assert.Equal(t, all, keys)
+
+	keys = nil
+	ScanMerged(trees, true, func(key int64, value interface{}) bool {
+		if first[key] != value.(int) {
+			t.Fatalf("%d: expected tree %d, got %d", key, first[key], value)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, len(first), len(keys))
+
+	var n int
+	ScanMerged(trees, true, func(key int64, value interface{}) bool {
+		n++
+		return n < 10
+	})
+	assert.Equal(t, 10, n)
+	ScanMerged(nil, true, func(key int64, value interface{}) bool {
+		t.Fatal("no items expected")
+		return true
+	})
+}
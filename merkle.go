@@ -0,0 +1,63 @@
+package tinybtree
+
+// MerkleAggregator is an Aggregator that maintains a hash of the items of
+// every subtree, so that RangeHash hashes any range in O(log n) time. The
+// hash of a range is the sum of the hashes of its items, which doesn't
+// depend on the shape of the tree, so two replicas holding the same items
+// in a range agree on its hash.
+type MerkleAggregator struct {
+	// ValueHash hashes the values. Defaults to HashValue.
+	ValueHash func(value interface{}) uint64
+}
+
+// Lift returns the hash of an item as an uint64
+func (m MerkleAggregator) Lift(key int64, value interface{}) interface{} {
+	hash := m.ValueHash
+	if hash == nil {
+		hash = HashValue
+	}
+	return mix64(mix64(uint64(key)) ^ hash(value))
+}
+
+// Merge adds the hashes
+func (MerkleAggregator) Merge(a, b interface{}) interface{} {
+	return a.(uint64) + b.(uint64)
+}
+
+// mix64 is the finalizer of splitmix64
+func mix64(h uint64) uint64 {
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
+
+// RangeHash returns the hash of the items within the range [lo, hi] when
+// the Aggregator of the tree is a MerkleAggregator, and 0 when the range is
+// empty.
+func (tr *BTree) RangeHash(lo, hi int64) uint64 {
+	h, _ := tr.AggregateRange(lo, hi).(uint64)
+	return h
+}
+
+// DivergentRanges finds the ranges within [lo, hi] whose items differ
+// between two replicas, given the RangeHash of each of them, which may be
+// a call to another machine. Ranges whose hashes differ are halved until
+// they're no wider than width keys, and those are passed to fn, after which
+// only their items need to be exchanged. Each divergent range takes about
+// log2((hi-lo)/width) rounds of hashing.
+func DivergentRanges(
+	local, remote func(lo, hi int64) uint64,
+	lo, hi int64, width uint64,
+	fn func(lo, hi int64),
+) {
+	if lo > hi || local(lo, hi) == remote(lo, hi) {
+		return
+	}
+	if uint64(hi)-uint64(lo) < width || lo == hi {
+		fn(lo, hi)
+		return
+	}
+	mid := lo + int64((uint64(hi)-uint64(lo))/2)
+	DivergentRanges(local, remote, lo, mid, width, fn)
+	DivergentRanges(local, remote, mid+1, hi, width, fn)
+}
@@ -0,0 +1,66 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeHash(t *testing.T) {
+	a := New(&Options{Aggregator: MerkleAggregator{}})
+	b := New(&Options{Aggregator: MerkleAggregator{}})
+	assert.Equal(t, uint64(0), a.RangeHash(0, 100))
+	for _, i := range rand.Perm(10000) {
+		a.Set(int64(i), i)
+	}
+	// a tree of another shape with the same items
+	for i := 0; i < 20000; i++ {
+		b.Set(int64(i), i)
+	}
+	for i := 10000; i < 20000; i++ {
+		b.Delete(int64(i))
+	}
+	assert.Equal(t, a.RangeHash(math.MinInt64, math.MaxInt64),
+		b.RangeHash(math.MinInt64, math.MaxInt64))
+	b.Set(5000, -1)
+	if a.RangeHash(0, 9999) == b.RangeHash(0, 9999) {
+		t.Fatal("expected the hashes to differ")
+	}
+	assert.Equal(t, a.RangeHash(0, 4999), b.RangeHash(0, 4999))
+	assert.Equal(t, a.RangeHash(5001, 9999), b.RangeHash(5001, 9999))
+}
+
+func TestDivergentRanges(t *testing.T) {
+	a := New(&Options{Aggregator: MerkleAggregator{}})
+	b := New(&Options{Aggregator: MerkleAggregator{}})
+	for i := 0; i < 100000; i++ {
+		a.Set(int64(i), i)
+		b.Set(int64(i), i)
+	}
+	b.Set(1234, -1)
+	b.Delete(77777)
+	b.Set(math.MaxInt64, 0)
+
+	var ranges [][2]int64
+	rounds := 0
+	remote := func(lo, hi int64) uint64 {
+		rounds++
+		return b.RangeHash(lo, hi)
+	}
+	DivergentRanges(a.RangeHash, remote, math.MinInt64, math.MaxInt64, 100,
+		func(lo, hi int64) {
+			ranges = append(ranges, [2]int64{lo, hi})
+		})
+	assert.Equal(t, 3, len(ranges))
+	for i, key := range []int64{1234, 77777, math.MaxInt64} {
+		if key < ranges[i][0] || key > ranges[i][1] ||
+			ranges[i][1]-ranges[i][0] >= 100 {
+			t.Fatalf("%d: unexpected range %v", key, ranges[i])
+		}
+	}
+	if rounds > 3*2*64 {
+		t.Fatalf("too many rounds: %d", rounds)
+	}
+}
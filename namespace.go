@@ -0,0 +1,56 @@
+package tinybtree
+
+const (
+	// NamespaceIDBits is the number of bits of the id in a key of
+	// ComposeKey, the upper 16 bits hold the namespace
+	NamespaceIDBits = 48
+	// MaxNamespaceID is the largest id that fits into a key
+	MaxNamespaceID = 1<<NamespaceIDBits - 1
+)
+
+// ComposeKey packs a namespace and an id into a key, for keeping several
+// maps in one tree. The keys of a namespace are next to each other and
+// ordered by id, and the namespaces are ordered by their number, which is
+// why the sign bit is flipped. Returns ErrIDOverflow when the id is larger
+// than MaxNamespaceID.
+func ComposeKey(ns uint16, id uint64) (int64, error) {
+	if id > MaxNamespaceID {
+		return 0, ErrIDOverflow
+	}
+	return int64((uint64(ns)<<NamespaceIDBits | id) ^ 1<<63), nil
+}
+
+// DecomposeKey returns the namespace and the id of a key of ComposeKey
+func DecomposeKey(key int64) (ns uint16, id uint64) {
+	k := uint64(key) ^ 1<<63
+	return uint16(k >> NamespaceIDBits), k & MaxNamespaceID
+}
+
+// ScanNamespace visits the items of the namespace ns in the order of their
+// ids, see ComposeKey
+func (tr *BTree) ScanNamespace(
+	ns uint16,
+	iter func(id uint64, value interface{}) bool,
+) {
+	tr.AscendNamespace(ns, 0, iter)
+}
+
+// AscendNamespace visits the items of the namespace ns starting with the
+// id pivot, see ComposeKey
+func (tr *BTree) AscendNamespace(
+	ns uint16, pivot uint64,
+	iter func(id uint64, value interface{}) bool,
+) {
+	lo, err := ComposeKey(ns, pivot)
+	if err != nil {
+		return
+	}
+	hi, _ := ComposeKey(ns, MaxNamespaceID)
+	tr.Ascend(lo, func(key int64, value interface{}) bool {
+		if key > hi {
+			return false
+		}
+		_, id := DecomposeKey(key)
+		return iter(id, value)
+	})
+}
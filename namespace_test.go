@@ -0,0 +1,67 @@
+package tinybtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeKey(t *testing.T) {
+	tests := []struct {
+		ns  uint16
+		id  uint64
+		key int64
+	}{
+		{0, 0, math.MinInt64},
+		{0x8000, 0, 0},
+		{math.MaxUint16, MaxNamespaceID, math.MaxInt64},
+		{1, 5, math.MinInt64 + 1<<48 + 5},
+	}
+	for _, tt := range tests {
+		key, err := ComposeKey(tt.ns, tt.id)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.key, key)
+		ns, id := DecomposeKey(key)
+		assert.Equal(t, tt.ns, ns)
+		assert.Equal(t, tt.id, id)
+	}
+	_, err := ComposeKey(1, MaxNamespaceID+1)
+	assert.Equal(t, ErrIDOverflow, err)
+
+	// the keys are ordered by namespace and then by id
+	a, _ := ComposeKey(0x7fff, MaxNamespaceID)
+	b, _ := ComposeKey(0x8000, 0)
+	if a >= b {
+		t.Fatalf("expected %d < %d", a, b)
+	}
+}
+
+func TestScanNamespace(t *testing.T) {
+	var tr BTree
+	for _, ns := range []uint16{0, 1, 2, math.MaxUint16} {
+		for _, id := range []uint64{0, 1, MaxNamespaceID} {
+			key, _ := ComposeKey(ns, id)
+			tr.Set(key, ns)
+		}
+	}
+	for _, ns := range []uint16{1, math.MaxUint16} {
+		var ids []uint64
+		tr.ScanNamespace(ns, func(id uint64, value interface{}) bool {
+			assert.Equal(t, ns, value)
+			ids = append(ids, id)
+			return true
+		})
+		assert.Equal(t, []uint64{0, 1, MaxNamespaceID}, ids)
+	}
+	var ids []uint64
+	tr.AscendNamespace(2, 1, func(id uint64, value interface{}) bool {
+		ids = append(ids, id)
+		return true
+	})
+	assert.Equal(t, []uint64{1, MaxNamespaceID}, ids)
+	tr.ScanNamespace(3, func(id uint64, value interface{}) bool {
+		t.Fatal("expected an empty namespace")
+		return false
+	})
+}
@@ -0,0 +1,119 @@
+package tinybtree
+
+import "math"
+
+// Overlay holds changes to a tree that are not applied yet. Reads through
+// the overlay see the changes on top of the tree, until Apply writes them
+// to the tree or Reset drops them. A key that is changed in the overlay
+// hides all values of the key in the tree, and Apply replaces them.
+type Overlay struct {
+	tr  *BTree
+	ops BTree // the values set in the overlay, or overlayDelete
+}
+
+// overlayDelete is the value of a key that was deleted in the overlay
+type overlayDelete struct{}
+
+// NewOverlay returns an empty overlay of the tree
+func (tr *BTree) NewOverlay() *Overlay {
+	return &Overlay{tr: tr}
+}
+
+// Set a value for a key in the overlay
+func (o *Overlay) Set(key int64, value interface{}) {
+	o.ops.Set(key, value)
+}
+
+// Delete a key in the overlay
+func (o *Overlay) Delete(key int64) {
+	o.ops.Set(key, overlayDelete{})
+}
+
+// Len returns the number of changed keys
+func (o *Overlay) Len() int {
+	return o.ops.Len()
+}
+
+// Get a value for key, looking in the overlay first
+func (o *Overlay) Get(key int64) (value interface{}, gotten bool) {
+	if value, ok := o.ops.Get(key); ok {
+		if _, del := value.(overlayDelete); del {
+			return nil, false
+		}
+		return value, true
+	}
+	return o.tr.Get(key)
+}
+
+// Scan all items of the tree with the changes of the overlay
+func (o *Overlay) Scan(iter func(key int64, value interface{}) bool) {
+	o.Ascend(math.MinInt64, iter)
+}
+
+// Ascend the items of the tree with the changes of the overlay within the
+// range [pivot, last]. Neither the tree nor the overlay may be modified
+// during the iteration.
+func (o *Overlay) Ascend(
+	pivot int64,
+	iter func(key int64, value interface{}) bool,
+) {
+	c := newCursor(&o.ops)
+	ok := c.first()
+	for ok && c.item().key < pivot {
+		ok = c.next()
+	}
+	var shadow int64 // the last key visited from the overlay
+	shadowed := false
+	// changes visits the changes up to key, or all of them, and returns
+	// false when iter stopped
+	changes := func(key int64, all bool) bool {
+		for ok && (all || c.item().key <= key) {
+			it := c.item()
+			shadow, shadowed = it.key, true
+			ok = c.next()
+			if _, del := it.value.(overlayDelete); del {
+				continue
+			}
+			if !iter(it.key, it.val()) {
+				return false
+			}
+		}
+		return true
+	}
+	stopped := false
+	o.tr.Ascend(pivot, func(key int64, value interface{}) bool {
+		if !changes(key, false) {
+			stopped = true
+			return false
+		}
+		if shadowed && key == shadow {
+			return true
+		}
+		stopped = !iter(key, value)
+		return !stopped
+	})
+	if !stopped {
+		changes(0, true)
+	}
+}
+
+// Apply writes the changes to the tree in key order and empties the
+// overlay. In multi mode the values of a key that was set are deleted first.
+func (o *Overlay) Apply() {
+	o.ops.Scan(func(key int64, value interface{}) bool {
+		_, del := value.(overlayDelete)
+		if del || o.tr.multi {
+			o.tr.Delete(key)
+		}
+		if !del {
+			o.tr.Set(key, value)
+		}
+		return true
+	})
+	o.Reset()
+}
+
+// Reset drops the changes
+func (o *Overlay) Reset() {
+	o.ops = BTree{}
+}
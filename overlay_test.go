@@ -0,0 +1,73 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlay(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 10; i++ {
+		tr.Set(int64(i), i)
+	}
+	o := tr.NewOverlay()
+	o.Set(3, 30)
+	o.Set(20, 200)
+	o.Set(-1, -10)
+	o.Delete(5)
+	o.Delete(50)
+	assert.Equal(t, 5, o.Len())
+
+	v, ok := o.Get(3)
+	assert.Equal(t, 30, v)
+	assert.Equal(t, true, ok)
+	_, ok = o.Get(5)
+	assert.Equal(t, false, ok)
+	v, _ = o.Get(4)
+	assert.Equal(t, 4, v)
+	// the tree is unchanged
+	v, _ = tr.Get(3)
+	assert.Equal(t, 3, v)
+
+	var keys []int64
+	var values []interface{}
+	o.Scan(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	assert.Equal(t, []int64{-1, 0, 1, 2, 3, 4, 6, 7, 8, 9, 20}, keys)
+	assert.Equal(t, []interface{}{-10, 0, 1, 2, 30, 4, 6, 7, 8, 9, 200},
+		values)
+
+	keys = nil
+	o.Ascend(3, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	assert.Equal(t, []int64{3, 4, 6}, keys)
+
+	o.Apply()
+	assert.Equal(t, 0, o.Len())
+	assert.Equal(t, 11, tr.Len())
+	v, _ = tr.Get(3)
+	assert.Equal(t, 30, v)
+	_, ok = tr.Get(5)
+	assert.Equal(t, false, ok)
+
+	// in multi mode a key set in the overlay replaces all of its values
+	m := New(&Options{Multi: true})
+	m.Set(1, "a")
+	m.Set(1, "b")
+	o = m.NewOverlay()
+	o.Set(1, "c")
+	values = nil
+	o.Scan(func(key int64, value interface{}) bool {
+		values = append(values, value)
+		return true
+	})
+	assert.Equal(t, []interface{}{"c"}, values)
+	o.Apply()
+	assert.Equal(t, []interface{}{"c"}, m.GetAll(1))
+}
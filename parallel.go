@@ -0,0 +1,77 @@
+package tinybtree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ScanParallel visits all items in tree using the provided number of
+// goroutines. The tree is split along its nodes into subtrees which are
+// scanned concurrently, so iter is called from multiple goroutines and the
+// items are not visited in order. Returning false from iter stops all of the
+// goroutines. The tree must not be modified until ScanParallel returns.
+func (tr *BTree) ScanParallel(
+	workers int,
+	iter func(key int64, value interface{}) bool,
+) {
+	if tr.root == nil {
+		return
+	}
+	if workers < 2 {
+		tr.Scan(iter)
+		return
+	}
+
+	// split the tree into a few subtrees for each worker, the items of the
+	// split branches are visited on their own
+	subtrees := []subtree{{tr.root, tr.height}}
+	var items []item
+	for height := tr.height; height > 0 && len(subtrees) < workers*4; height-- {
+		var next []subtree
+		for _, s := range subtrees {
+			for i := 0; i <= s.root.numItems; i++ {
+				next = append(next, subtree{s.root.children[i], height - 1})
+				if i < s.root.numItems {
+					items = append(items, s.root.item(i))
+				}
+			}
+		}
+		subtrees = next
+	}
+
+	var stop int32
+	visit := func(key int64, value interface{}) bool {
+		if atomic.LoadInt32(&stop) != 0 {
+			return false
+		}
+		if !iter(key, value) {
+			atomic.StoreInt32(&stop, 1)
+			return false
+		}
+		return true
+	}
+	for _, it := range items {
+		if !visit(it.key, it.val()) {
+			return
+		}
+	}
+	var next int32 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			it := iterState{cur: &tr.mods, iter: visit, mods: tr.mods,
+				yield: tr.yield}
+			defer it.catch()
+			for {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(subtrees) || atomic.LoadInt32(&stop) != 0 {
+					return
+				}
+				subtrees[i].root.scan(&it, subtrees[i].height)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,42 @@
+package tinybtree
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanParallel(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 100000} {
+		var tr BTree
+		for i := 0; i < n; i++ {
+			tr.Set(int64(i), i)
+		}
+		var mu sync.Mutex
+		seen := make(map[int64]bool)
+		tr.ScanParallel(8, func(key int64, value interface{}) bool {
+			mu.Lock()
+			if seen[key] {
+				t.Errorf("%d visited twice", key)
+			}
+			seen[key] = true
+			mu.Unlock()
+			return true
+		})
+		assert.Equal(t, n, len(seen))
+	}
+
+	var tr BTree
+	for i := 0; i < 100000; i++ {
+		tr.Set(int64(i), i)
+	}
+	var count int32
+	tr.ScanParallel(8, func(key int64, value interface{}) bool {
+		return atomic.AddInt32(&count, 1) < 1000
+	})
+	if count < 1000 || count > 1000+8 {
+		t.Fatalf("unexpected count %d", count)
+	}
+}
@@ -0,0 +1,163 @@
+package tinybtree
+
+import (
+	"math"
+	"sort"
+)
+
+// TimePartitionedTree keeps its items in one BTree per time window, where
+// the keys are timestamps. A window is created when the first key within it
+// is set. Old windows are discarded as a whole by DropBefore, which is much
+// faster than deleting their keys one by one.
+type TimePartitionedTree struct {
+	window int64
+	opts   *Options
+	parts  []partition // ordered by start
+	length int
+}
+
+type partition struct {
+	start int64 // the first key of the window
+	tr    *BTree
+}
+
+// NewTimePartitionedTree returns a tree with windows of the given size, in
+// the unit of the keys. Every window is a BTree that is created using opts.
+func NewTimePartitionedTree(window int64, opts *Options) *TimePartitionedTree {
+	if window < 1 {
+		panic("tinybtree: window must be positive")
+	}
+	return &TimePartitionedTree{window: window, opts: opts}
+}
+
+// start returns the start of the window that holds key
+func (pt *TimePartitionedTree) start(key int64) int64 {
+	start := key - key%pt.window
+	if key < 0 && start != key {
+		if start < math.MinInt64+pt.window {
+			return math.MinInt64
+		}
+		start -= pt.window
+	}
+	return start
+}
+
+// search returns the index of the first window that starts at or after
+// start
+func (pt *TimePartitionedTree) search(start int64) int {
+	return sort.Search(len(pt.parts), func(i int) bool {
+		return pt.parts[i].start >= start
+	})
+}
+
+// partition returns the tree of the window that holds key, or nil
+func (pt *TimePartitionedTree) partition(key int64) *BTree {
+	start := pt.start(key)
+	i := pt.search(start)
+	if i < len(pt.parts) && pt.parts[i].start == start {
+		return pt.parts[i].tr
+	}
+	return nil
+}
+
+// Set or replace a value for a key, creating its window when needed
+func (pt *TimePartitionedTree) Set(key int64, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	start := pt.start(key)
+	i := pt.search(start)
+	if i == len(pt.parts) || pt.parts[i].start != start {
+		pt.parts = append(pt.parts, partition{})
+		copy(pt.parts[i+1:], pt.parts[i:])
+		pt.parts[i] = partition{start, New(pt.opts)}
+	}
+	tr := pt.parts[i].tr
+	n := tr.Len()
+	prev, replaced = tr.Set(key, value)
+	pt.length += tr.Len() - n
+	return prev, replaced
+}
+
+// Get a value for key
+func (pt *TimePartitionedTree) Get(key int64) (value interface{}, gotten bool) {
+	if tr := pt.partition(key); tr != nil {
+		return tr.Get(key)
+	}
+	return nil, false
+}
+
+// Delete a value for a key. A window is kept when its last key is deleted,
+// until it's dropped.
+func (pt *TimePartitionedTree) Delete(key int64) (
+	prev interface{}, deleted bool,
+) {
+	tr := pt.partition(key)
+	if tr == nil {
+		return nil, false
+	}
+	n := tr.Len()
+	prev, deleted = tr.Delete(key)
+	pt.length -= n - tr.Len()
+	return prev, deleted
+}
+
+// Len returns the number of items in all windows
+func (pt *TimePartitionedTree) Len() int {
+	return pt.length
+}
+
+// Partitions returns the number of windows
+func (pt *TimePartitionedTree) Partitions() int {
+	return len(pt.parts)
+}
+
+// Scan all items in key order across the windows
+func (pt *TimePartitionedTree) Scan(
+	iter func(key int64, value interface{}) bool,
+) {
+	pt.Ascend(math.MinInt64, iter)
+}
+
+// Ascend the items within the range [pivot, last] across the windows
+func (pt *TimePartitionedTree) Ascend(
+	pivot int64,
+	iter func(key int64, value interface{}) bool,
+) {
+	ok := true
+	visit := func(key int64, value interface{}) bool {
+		ok = iter(key, value)
+		return ok
+	}
+	// the windows are looked up again after each one, in case iter set
+	// or dropped some of them
+	for i := pt.search(pt.start(pivot)); ok && i < len(pt.parts); {
+		p := pt.parts[i]
+		p.tr.Ascend(pivot, visit)
+		if p.start == math.MaxInt64 {
+			return
+		}
+		i = pt.search(p.start + 1)
+	}
+}
+
+// DropBefore removes all keys less than t and returns the number of removed
+// items. The windows that end at or before t are discarded as a whole, only
+// the window that holds t has its keys removed individually. The values of
+// the discarded windows are still released when opts has a Retainer.
+func (pt *TimePartitionedTree) DropBefore(t int64) int {
+	start := pt.start(t)
+	i := pt.search(start)
+	removed := 0
+	for _, p := range pt.parts[:i] {
+		removed += p.tr.Len()
+		// releases the values to a Retainer and the nodes to an Allocator
+		p.tr.Clear()
+	}
+	pt.parts = append(pt.parts[:0], pt.parts[i:]...)
+	if len(pt.parts) > 0 && pt.parts[0].start == start && t > start {
+		var discard BTree
+		removed += pt.parts[0].tr.MoveRange(&discard, start, t-1)
+	}
+	pt.length -= removed
+	return removed
+}
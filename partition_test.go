@@ -0,0 +1,88 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimePartitionedTree(t *testing.T) {
+	pt := NewTimePartitionedTree(100, nil)
+	var tr BTree
+	for _, i := range rand.Perm(1000) {
+		key := int64(i - 300)
+		pt.Set(key, i)
+		tr.Set(key, i)
+	}
+	assert.Equal(t, 1000, pt.Len())
+	assert.Equal(t, 10, pt.Partitions())
+	_, replaced := pt.Set(5, "x")
+	assert.Equal(t, true, replaced)
+	tr.Set(5, "x")
+	v, ok := pt.Get(5)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "x", v)
+	if _, ok := pt.Get(5000); ok {
+		t.Fatal("expected false")
+	}
+
+	keys := func(pivot int64) (keys []int64) {
+		pt.Ascend(pivot, func(key int64, value interface{}) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return keys
+	}
+	assert.Equal(t, tr.Keys(nil), keys(math.MinInt64))
+	var expect []int64
+	tr.Ascend(-150, func(key int64, value interface{}) bool {
+		expect = append(expect, key)
+		return true
+	})
+	assert.Equal(t, expect, keys(-150))
+
+	// stop early
+	var n int
+	pt.Scan(func(key int64, value interface{}) bool {
+		n++
+		return n < 150
+	})
+	assert.Equal(t, 150, n)
+
+	_, deleted := pt.Delete(-300)
+	assert.Equal(t, true, deleted)
+	_, deleted = pt.Delete(-300)
+	assert.Equal(t, false, deleted)
+	assert.Equal(t, 999, pt.Len())
+
+	// the windows [-300,-200) and [-200,-100) go as a whole, -100 to -51
+	// are removed from the next window
+	assert.Equal(t, 249, pt.DropBefore(-50))
+	assert.Equal(t, 750, pt.Len())
+	assert.Equal(t, 8, pt.Partitions())
+	assert.Equal(t, int64(-50), keys(math.MinInt64)[0])
+	assert.Equal(t, 0, pt.DropBefore(-50))
+	assert.Equal(t, 750, pt.DropBefore(math.MaxInt64))
+	assert.Equal(t, 0, pt.Len())
+
+	pt.Set(math.MaxInt64, 1)
+	pt.Set(math.MinInt64, 2)
+	pt.Set(math.MinInt64+50, 3)
+	assert.Equal(t, []int64{math.MinInt64, math.MinInt64 + 50, math.MaxInt64},
+		keys(math.MinInt64))
+}
+
+func TestTimePartitionedRetainer(t *testing.T) {
+	refs := refCounter{}
+	pt := NewTimePartitionedTree(10, &Options{Retainer: refs})
+	for i := 0; i < 100; i++ {
+		pt.Set(int64(i), i)
+	}
+	assert.Equal(t, 100, len(refs))
+	assert.Equal(t, 35, pt.DropBefore(35))
+	assert.Equal(t, 65, len(refs))
+	assert.Equal(t, 0, refs[34])
+	assert.Equal(t, 1, refs[35])
+}
@@ -0,0 +1,223 @@
+// Package persist makes a tinybtree.BTree crash-safe. Every change is first
+// appended to a write-ahead log in a directory, and from time to time the
+// whole tree is written to a checkpoint file after which the log starts over.
+// After a crash Recover loads the latest checkpoint and replays the log.
+//
+// Values are encoded with encoding/gob, so the concrete types of the values
+// other than the basic types must be registered with gob.Register.
+package persist
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/scarbo87/tinybtree"
+)
+
+const (
+	checkpointFile = "checkpoint"
+	walFile        = "wal"
+)
+
+// Options for Open
+type Options struct {
+	// CheckpointEvery is the number of writes after which a checkpoint is
+	// made. Zero means that checkpoints are only made by Open, Close and
+	// Checkpoint.
+	CheckpointEvery int
+	// Sync commits the log to disk after every write. Without it the most
+	// recent writes may be lost when the machine crashes.
+	Sync bool
+}
+
+// Store is a BTree that is persisted to a directory. All writes must go
+// through the Store, the tree returned by Tree is for reading only. A Store
+// is not safe for concurrent use.
+type Store struct {
+	dir    string
+	opts   Options
+	tr     *tinybtree.BTree
+	wal    *os.File
+	enc    *gob.Encoder
+	writes int
+}
+
+type walRecord struct {
+	Delete bool
+	Key    int64
+	Value  interface{}
+}
+
+// Open recovers the tree that is stored in dir, creating the directory when
+// it doesn't exist, and returns a Store for making further changes.
+func Open(dir string, opts *Options) (*Store, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	tr, err := Recover(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, tr: tr}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if err := s.Checkpoint(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Tree returns the tree of the store
+func (s *Store) Tree() *tinybtree.BTree {
+	return s.tr
+}
+
+// Set or replace a value for a key
+func (s *Store) Set(key int64, value interface{}) (
+	prev interface{}, replaced bool, err error,
+) {
+	if err := s.log(walRecord{Key: key, Value: value}); err != nil {
+		return nil, false, err
+	}
+	prev, replaced = s.tr.Set(key, value)
+	return prev, replaced, s.written()
+}
+
+// Delete a value for a key
+func (s *Store) Delete(key int64) (prev interface{}, deleted bool, err error) {
+	if err := s.log(walRecord{Delete: true, Key: key}); err != nil {
+		return nil, false, err
+	}
+	prev, deleted = s.tr.Delete(key)
+	return prev, deleted, s.written()
+}
+
+func (s *Store) log(rec walRecord) error {
+	if err := s.enc.Encode(rec); err != nil {
+		return err
+	}
+	if s.opts.Sync {
+		return s.wal.Sync()
+	}
+	return nil
+}
+
+func (s *Store) written() error {
+	s.writes++
+	if s.opts.CheckpointEvery > 0 && s.writes >= s.opts.CheckpointEvery {
+		return s.Checkpoint()
+	}
+	return nil
+}
+
+// Checkpoint writes the whole tree to the checkpoint file and starts a new
+// log.
+func (s *Store) Checkpoint() error {
+	path := filepath.Join(s.dir, checkpointFile)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := s.tr.WriteTo(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	// the rename must be on disk before the log is emptied, or a crash may
+	// leave the old checkpoint with an empty log
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+	// the log is replayed on top of the new checkpoint until it's replaced,
+	// which is harmless because replaying the writes again is idempotent
+	if s.wal != nil {
+		s.wal.Close()
+	}
+	s.wal, err = os.Create(filepath.Join(s.dir, walFile))
+	if err != nil {
+		return err
+	}
+	s.enc = gob.NewEncoder(s.wal)
+	s.writes = 0
+	return nil
+}
+
+// Close makes a final checkpoint and closes the store
+func (s *Store) Close() error {
+	err := s.Checkpoint()
+	if cerr := s.wal.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Recover loads the latest checkpoint in dir and replays the log on top of
+// it. A record that was only partially written at the end of the log is
+// ignored. An empty or missing directory yields an empty tree.
+func Recover(dir string) (*tinybtree.BTree, error) {
+	tr := new(tinybtree.BTree)
+	f, err := os.Open(filepath.Join(dir, checkpointFile))
+	if err == nil {
+		_, err = tr.ReadFrom(bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err = os.Open(filepath.Join(dir, walFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tr, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	for {
+		var rec walRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return tr, nil
+			}
+			return nil, err
+		}
+		if rec.Delete {
+			tr.Delete(rec.Key)
+		} else {
+			tr.Set(rec.Key, rec.Value)
+		}
+	}
+}
+
+// syncDir commits the entries of a directory to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
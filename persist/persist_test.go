@@ -0,0 +1,94 @@
+package persist
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scarbo87/tinybtree"
+)
+
+func equal(t *testing.T, a, b *tinybtree.BTree) {
+	t.Helper()
+	if a.Len() != b.Len() {
+		t.Fatalf("expected %d items, got %d", a.Len(), b.Len())
+	}
+	a.Diff(b, func(key int64, l, r interface{}, kind tinybtree.DiffKind) {
+		t.Fatalf("%d %v: %v != %v", key, kind, l, r)
+	})
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, &Options{CheckpointEvery: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expect tinybtree.BTree
+	for i := 0; i < 1000; i++ {
+		key := int64(rand.Intn(500))
+		if rand.Intn(3) == 0 {
+			if _, _, err := s.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+			expect.Delete(key)
+		} else {
+			if _, _, err := s.Set(key, i); err != nil {
+				t.Fatal(err)
+			}
+			expect.Set(key, i)
+		}
+	}
+	equal(t, &expect, s.Tree())
+
+	// crash without closing the store
+	tr, err := Recover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, &expect, tr)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, &expect, s.Tree())
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverTornLog(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, &Options{Sync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		s.Set(int64(i), i)
+	}
+	path := filepath.Join(dir, walFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := Recover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Len() != 99 {
+		t.Fatalf("expected 99 items, got %d", tr.Len())
+	}
+
+	tr, err = Recover(filepath.Join(dir, "missing"))
+	if err != nil || tr.Len() != 0 {
+		t.Fatalf("expected empty tree, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package tinybtree
+
+// SetPooled sets a value for a key that is taken from the pool of the
+// Pool option and filled in by fill, so that values which are small
+// structs don't have to be allocated for every Set. When the value is later
+// replaced or deleted it's put back into the pool, and the value returned
+// by Set, Delete or DeleteOne must not be used anymore. DeleteFn puts it
+// back once onDelete returns, and DeleteItem hands it to the caller
+// instead. A value must not be stored twice. Values removed otherwise, such as by Clear or within
+// Update, are left to the garbage collector, and so are all values of a
+// tree once it was cloned.
+// Panics when the tree has no Pool.
+func (tr *BTree) SetPooled(key int64, fill func(value interface{})) (
+	replaced bool,
+) {
+	if tr.pool == nil {
+		panic("tinybtree: SetPooled without a Pool")
+	}
+	value := tr.pool.Get()
+	fill(value)
+	_, replaced = tr.Set(key, value)
+	return replaced
+}
+
+// recycle puts a replaced or deleted value back into the pool. It takes
+// the slot so that ints of SetInt aren't boxed when there's no pool.
+func (tr *BTree) recycle(s slot) {
+	if tr.recycling {
+		tr.pool.Put(s.val())
+	}
+}
@@ -0,0 +1,77 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pooledPoint struct{ x, y int }
+
+func TestSetPooled(t *testing.T) {
+	var allocs int
+	tr := New(&Options{Pool: func() interface{} {
+		allocs++
+		return new(pooledPoint)
+	}})
+	for i := 0; i < 100; i++ {
+		replaced := tr.SetPooled(int64(i%10), func(value interface{}) {
+			*value.(*pooledPoint) = pooledPoint{i, -i}
+		})
+		assert.Equal(t, i >= 10, replaced)
+	}
+	v, _ := tr.Get(3)
+	assert.Equal(t, pooledPoint{93, -93}, *v.(*pooledPoint))
+	assert.Equal(t, 10, tr.Len())
+	// the pool may drop values at any time, but not all of them were
+	// allocated anew
+	if allocs >= 100 {
+		t.Fatalf("expected values to be reused, got %d allocations", allocs)
+	}
+
+	// values of a clone are never put back into the pool
+	c := tr.Clone()
+	c.Delete(3)
+	tr.Delete(4)
+	v, _ = tr.Get(3)
+	assert.Equal(t, pooledPoint{93, -93}, *v.(*pooledPoint))
+	v, _ = c.Get(4)
+	assert.Equal(t, pooledPoint{94, -94}, *v.(*pooledPoint))
+
+	defer func() {
+		assert.Equal(t, "tinybtree: SetPooled without a Pool", recover())
+	}()
+	var plain BTree
+	plain.SetPooled(1, func(interface{}) {})
+}
+
+func TestSetPooledDeleteFn(t *testing.T) {
+	tr := New(&Options{Pool: func() interface{} { return new(pooledPoint) }})
+	for i := 0; i < 10; i++ {
+		tr.SetPooled(int64(i), func(value interface{}) {
+			*value.(*pooledPoint) = pooledPoint{i, -i}
+		})
+	}
+	var taken []*pooledPoint
+	tr.DeleteFn(1, func(value interface{}) {
+		// the value is still the deleted one while onDelete runs
+		tr.SetPooled(100, func(value interface{}) {
+			*value.(*pooledPoint) = pooledPoint{}
+		})
+		assert.Equal(t, pooledPoint{1, -1}, *value.(*pooledPoint))
+	})
+	item, ok := tr.DeleteItem(2)
+	assert.Equal(t, true, ok)
+	for i := 0; i < 100; i++ {
+		tr.SetPooled(int64(200+i), func(value interface{}) {
+			taken = append(taken, value.(*pooledPoint))
+			*value.(*pooledPoint) = pooledPoint{}
+		})
+	}
+	assert.Equal(t, pooledPoint{2, -2}, *item.Value.(*pooledPoint))
+	for _, p := range taken {
+		if p == item.Value {
+			t.Fatal("the value of DeleteItem went back into the pool")
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package tinybtree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// protoChunkItems is the number of items in a Chunk of a protobuf snapshot
+const protoChunkItems = 1024
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errProtoInvalid = errors.New("tinybtree: invalid protobuf")
+
+// WriteProto writes a snapshot of the tree to w in the protobuf format of
+// snapshot.proto, which tools in other languages can read. Integers,
+// floats, strings, byte slices and bools are written as such and values of
+// types with a Serializer as tagged values, other values are an error.
+func (tr *BTree) WriteProto(w io.Writer) (n int64, err error) {
+	span := tr.span("WriteProto")
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	cw := &countWriter{w: w}
+	defer func() {
+		span.SetAttribute("tinybtree.bytes", cw.n)
+		span.End(err)
+	}()
+	var chunk, it []byte
+	var items int
+	flush := func() error {
+		var size [binary.MaxVarintLen64]byte
+		_, err := cw.Write(append(size[:binary.PutUvarint(size[:],
+			uint64(len(chunk)))], chunk...))
+		chunk, items = chunk[:0], 0
+		return err
+	}
+	tr.Scan(func(key int64, value interface{}) bool {
+		it, err = appendProtoItem(it[:0], key, value)
+		if err != nil {
+			return false
+		}
+		chunk = appendProtoBytes(chunk, 1, it)
+		if items++; items == protoChunkItems {
+			err = flush()
+		}
+		return err == nil
+	})
+	if err == nil && items > 0 {
+		err = flush()
+	}
+	return cw.n, err
+}
+
+// appendProtoItem appends an Item message
+func appendProtoItem(b []byte, key int64, value interface{}) ([]byte, error) {
+	b = appendProtoVarint(b, 1, zigzag(key))
+	value, err := serialize(value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case nil:
+	case int:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case int8:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case int16:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case int32:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case int64:
+		b = appendProtoVarint(b, 2, zigzag(v))
+	case uint8:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case uint16:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case uint32:
+		b = appendProtoVarint(b, 2, zigzag(int64(v)))
+	case uint:
+		b = appendProtoVarint(b, 8, uint64(v))
+	case uint64:
+		b = appendProtoVarint(b, 8, v)
+	case float32:
+		b = appendProtoFixed64(b, 3, math.Float64bits(float64(v)))
+	case float64:
+		b = appendProtoFixed64(b, 3, math.Float64bits(v))
+	case string:
+		b = appendProtoBytes(b, 4, []byte(v))
+	case []byte:
+		b = appendProtoBytes(b, 5, v)
+	case bool:
+		x := uint64(0)
+		if v {
+			x = 1
+		}
+		b = appendProtoVarint(b, 6, x)
+	case taggedValue:
+		tagged := appendProtoBytes(nil, 1, []byte(v.Tag))
+		tagged = appendProtoBytes(tagged, 2, v.Data)
+		b = appendProtoBytes(b, 7, tagged)
+	default:
+		return nil, fmt.Errorf("tinybtree: no protobuf encoding for %T",
+			value)
+	}
+	return b, nil
+}
+
+func zigzag(x int64) uint64 {
+	return uint64(x<<1) ^ uint64(x>>63)
+}
+
+func unzigzag(x uint64) int64 {
+	return int64(x>>1) ^ -int64(x&1)
+}
+
+func appendProtoTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendProtoVarint(b []byte, field int, x uint64) []byte {
+	return binary.AppendUvarint(appendProtoTag(b, field, wireVarint), x)
+}
+
+func appendProtoFixed64(b []byte, field int, x uint64) []byte {
+	return binary.LittleEndian.AppendUint64(
+		appendProtoTag(b, field, wireFixed64), x)
+}
+
+func appendProtoBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(appendProtoTag(b, field, wireBytes),
+		uint64(len(data)))
+	return append(b, data...)
+}
+
+// protoField is a field of a protobuf message. The value of a varint or a
+// fixed field is in x, the data of a length-delimited field in data.
+type protoField struct {
+	num  int
+	wire int
+	x    uint64
+	data []byte
+}
+
+// eachProtoField calls fn for each field of the message in b
+func eachProtoField(b []byte, fn func(f protoField) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoInvalid
+		}
+		b = b[n:]
+		f := protoField{num: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.x, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errProtoInvalid
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errProtoInvalid
+			}
+			f.x, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errProtoInvalid
+			}
+			f.x, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return errProtoInvalid
+			}
+			f.data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return errProtoInvalid
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseProtoItem parses an Item message. Integers are returned as int64,
+// unsigned ones of the uint field as uint64, and floats as float64.
+func parseProtoItem(b []byte) (key int64, value interface{}, err error) {
+	err = eachProtoField(b, func(f protoField) error {
+		switch {
+		case f.num == 1 && f.wire == wireVarint:
+			key = unzigzag(f.x)
+		case f.num == 2 && f.wire == wireVarint:
+			value = unzigzag(f.x)
+		case f.num == 3 && f.wire == wireFixed64:
+			value = math.Float64frombits(f.x)
+		case f.num == 4 && f.wire == wireBytes:
+			value = string(f.data)
+		case f.num == 5 && f.wire == wireBytes:
+			value = append([]byte{}, f.data...)
+		case f.num == 6 && f.wire == wireVarint:
+			value = f.x != 0
+		case f.num == 8 && f.wire == wireVarint:
+			value = f.x
+		case f.num == 7 && f.wire == wireBytes:
+			var tv taggedValue
+			err := eachProtoField(f.data, func(f protoField) error {
+				switch {
+				case f.num == 1 && f.wire == wireBytes:
+					tv.Tag = string(f.data)
+				case f.num == 2 && f.wire == wireBytes:
+					tv.Data = append([]byte{}, f.data...)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			value = tv
+		}
+		// other fields are unknown and skipped
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	value, err = deserialize(value)
+	return key, value, err
+}
+
+// ReadProto reads a snapshot in the protobuf format of snapshot.proto and
+// sets its items in the tree. Integers are set as int64, uint and uint64
+// values as uint64 and floats as float64, whatever their type was when
+// written. A malformed snapshot is reported as a SnapshotError. The items
+// that were read before are still set.
+func (tr *BTree) ReadProto(r io.Reader) (n int64, err error) {
+	span := tr.span("ReadProto")
+	items := 0
+	cr := &countReader{r: r}
+	defer func() {
+		span.SetAttribute("tinybtree.items", int64(items))
+		span.SetAttribute("tinybtree.bytes", cr.n)
+		span.End(err)
+	}()
+	br := bufio.NewReader(cr)
+	var offset int64 // of the current chunk
+	corrupt := func(err error) error {
+		if err == cr.err {
+			return err
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return &SnapshotError{Offset: offset, Err: err}
+	}
+	var chunk bytes.Buffer
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return cr.n, nil
+		}
+		if err != nil {
+			return cr.n, corrupt(err)
+		}
+		if size > math.MaxInt32 {
+			return cr.n, corrupt(errProtoInvalid)
+		}
+		// the buffer grows with the bytes read instead of trusting the
+		// size of a chunk that may be corrupt
+		chunk.Reset()
+		if _, err := io.CopyN(&chunk, br, int64(size)); err != nil {
+			return cr.n, corrupt(err)
+		}
+		err = eachProtoField(chunk.Bytes(), func(f protoField) error {
+			if f.num != 1 || f.wire != wireBytes {
+				return nil
+			}
+			key, value, err := parseProtoItem(f.data)
+			if err != nil {
+				return err
+			}
+			tr.Set(key, value)
+			items++
+			return nil
+		})
+		if err == errProtoInvalid {
+			return cr.n, corrupt(err)
+		}
+		if err != nil {
+			return cr.n, err
+		}
+		offset = cr.n - int64(br.Buffered())
+	}
+}
@@ -0,0 +1,86 @@
+package tinybtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtoSnapshot(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 3000; i++ {
+		tr.Set(int64(i-1000), i)
+	}
+	values := []interface{}{nil, int8(-3), uint32(7), float32(1.5),
+		math.Inf(-1), "text", []byte{0, 1}, true, false, decimal{-99},
+		uint(3), uint64(math.MaxUint64)}
+	for i, v := range values {
+		tr.Set(int64(math.MaxInt64-i), v)
+	}
+	var buf bytes.Buffer
+	n, err := tr.WriteProto(&buf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	data := buf.Bytes()
+
+	var read BTree
+	n, err = read.ReadProto(bytes.NewReader(data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, tr.Len(), read.Len())
+	v, _ := read.Get(-1000)
+	assert.Equal(t, int64(0), v)
+	expect := []interface{}{nil, int64(-3), int64(7), float64(1.5),
+		math.Inf(-1), "text", []byte{0, 1}, true, false, decimal{-99},
+		uint64(3), uint64(math.MaxUint64)}
+	for i, want := range expect {
+		v, ok := read.Get(int64(math.MaxInt64 - i))
+		assert.Equal(t, true, ok)
+		assert.Equal(t, want, v)
+	}
+
+	// truncated
+	var partial BTree
+	_, err = partial.ReadProto(bytes.NewReader(data[:len(data)-1]))
+	if !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("expected a corrupt snapshot, got %v", err)
+	}
+	if partial.Len() != protoChunkItems*2 {
+		t.Fatalf("expected the first chunks, got %d items", partial.Len())
+	}
+
+	// a corrupt chunk size doesn't allocate a chunk of that size
+	corrupt := append(binary.AppendUvarint(nil, math.MaxInt32), 0x0a)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = partial.ReadProto(bytes.NewReader(corrupt))
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("expected a corrupt snapshot, got %v", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Fatalf("expected a small allocation, got %d bytes", alloc)
+	}
+
+	var bad BTree
+	bad.Set(1, struct{}{})
+	_, err = bad.WriteProto(&buf)
+	assert.Equal(t, "tinybtree: no protobuf encoding for struct {}",
+		err.Error())
+}
+
+func TestProtoItemWire(t *testing.T) {
+	// Item{key: -1, string: "a"} as encoded by protoc
+	b, err := appendProtoItem(nil, -1, "a")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []byte{0x08, 0x01, 0x22, 0x01, 'a'}, b)
+	key, value, err := parseProtoItem(append(b, 0x78, 0x05))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(-1), key)
+	assert.Equal(t, "a", value)
+}
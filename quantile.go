@@ -0,0 +1,86 @@
+package tinybtree
+
+import "math"
+
+// Quantile returns the key at quantile q, where q is within the range
+// [0, 1], using the nearest-rank method. Returns false when the tree is
+// empty or q is out of range.
+func (tr *BTree) Quantile(q float64) (key int64, ok bool) {
+	if tr.root == nil || !(q >= 0 && q <= 1) {
+		return 0, false
+	}
+	index := int(math.Ceil(q*float64(tr.length))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return tr.root.at(index, tr.height).key, true
+}
+
+// Histogram counts the keys that fall between the sorted bucket boundaries.
+// The result has len(buckets)+1 entries, where the first entry counts the
+// keys less than buckets[0], entry i counts the keys within the range
+// [buckets[i-1], buckets[i]) and the last entry counts the keys greater or
+// equal to the last boundary.
+func (tr *BTree) Histogram(buckets []int64) []int {
+	counts := make([]int, len(buckets)+1)
+	if tr.root == nil {
+		return counts
+	}
+	var prev int
+	for i, bound := range buckets {
+		rank := tr.root.rank(bound, tr.height)
+		counts[i] = rank - prev
+		prev = rank
+	}
+	counts[len(buckets)] = tr.length - prev
+	return counts
+}
+
+// EstimateCount returns the number of items with a key within the range
+// [lo, hi] in O(log n) time, such as for a query planner choosing between
+// a range scan and a full scan. The tree keeps the count of every subtree,
+// so the estimate is exact.
+func (tr *BTree) EstimateCount(lo, hi int64) int {
+	return tr.countRange(lo, hi)
+}
+
+// countRange returns the number of items within the range [lo, hi]
+func (tr *BTree) countRange(lo, hi int64) int {
+	if tr.root == nil || lo > hi {
+		return 0
+	}
+	end := tr.length
+	if hi < math.MaxInt64 {
+		end = tr.root.rank(hi+1, tr.height)
+	}
+	return end - tr.root.rank(lo, tr.height)
+}
+
+// KeyRange is the range of keys [Lo, Hi]
+type KeyRange struct {
+	Lo, Hi int64
+}
+
+// Partitions splits the key space into up to n ranges that hold about the
+// same number of items, using the counts of the subtrees to find the keys
+// to split at in O(n log n) time, so that the ranges can be processed in
+// parallel with balanced work. The ranges are in order and cover all keys
+// from math.MinInt64 to math.MaxInt64. There are fewer than n ranges when
+// the tree holds fewer than n distinct keys, and none when it's empty.
+func (tr *BTree) Partitions(n int) []KeyRange {
+	if tr.root == nil || n < 1 {
+		return nil
+	}
+	ranges := make([]KeyRange, 0, n)
+	lo := int64(math.MinInt64)
+	for i := 1; i < n; i++ {
+		key := tr.root.at(i*tr.length/n, tr.height).key
+		if key == lo || key == tr.min {
+			// duplicate keys, or fewer keys than ranges
+			continue
+		}
+		ranges = append(ranges, KeyRange{lo, key - 1})
+		lo = key
+	}
+	return append(ranges, KeyRange{lo, math.MaxInt64})
+}
@@ -0,0 +1,98 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuantile(t *testing.T) {
+	var tr BTree
+	if _, ok := tr.Quantile(0.5); ok {
+		t.Fatal("expected false")
+	}
+	for _, i := range rand.Perm(1000) {
+		tr.Set(int64(i+1), nil)
+	}
+	tests := []struct {
+		q   float64
+		key int64
+	}{
+		{0, 1}, {0.001, 1}, {0.0011, 2}, {0.5, 500}, {0.99, 990}, {1, 1000},
+	}
+	for _, tt := range tests {
+		key, ok := tr.Quantile(tt.q)
+		if !ok {
+			t.Fatal("expected true")
+		}
+		assert.Equal(t, tt.key, key)
+	}
+	if _, ok := tr.Quantile(1.1); ok {
+		t.Fatal("expected false")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, []int{0, 0}, tr.Histogram([]int64{10}))
+	for _, i := range rand.Perm(1000) {
+		tr.Set(int64(i), nil)
+	}
+	assert.Equal(t, []int{10, 90, 900, 0},
+		tr.Histogram([]int64{10, 100, 1000}))
+	assert.Equal(t, []int{1000}, tr.Histogram(nil))
+}
+
+func TestEstimateCount(t *testing.T) {
+	tr := New(&Options{Multi: true})
+	assert.Equal(t, 0, tr.EstimateCount(0, 10))
+	for _, i := range rand.Perm(1000) {
+		tr.Set(int64(i/2), nil)
+	}
+	assert.Equal(t, 22, tr.EstimateCount(-5, 10))
+	assert.Equal(t, 2, tr.EstimateCount(10, 10))
+	assert.Equal(t, 0, tr.EstimateCount(10, 9))
+	assert.Equal(t, 20, tr.EstimateCount(490, math.MaxInt64))
+	assert.Equal(t, 1000, tr.EstimateCount(math.MinInt64, math.MaxInt64))
+}
+
+func TestPartitions(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, 0, len(tr.Partitions(4)))
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i*2), i)
+	}
+	ranges := tr.Partitions(4)
+	assert.Equal(t, []KeyRange{
+		{math.MinInt64, 499}, {500, 999}, {1000, 1499},
+		{1500, math.MaxInt64},
+	}, ranges)
+	for _, n := range []int{1, 3, 7, 100} {
+		ranges := tr.Partitions(n)
+		assert.Equal(t, n, len(ranges))
+		total := 0
+		for _, r := range ranges {
+			count := tr.EstimateCount(r.Lo, r.Hi)
+			if count < 1000/n || count > 1000/n+1 {
+				t.Fatalf("%d: unbalanced range %v with %d items", n, r, count)
+			}
+			total += count
+		}
+		assert.Equal(t, 1000, total)
+	}
+
+	var small BTree
+	small.Set(5, nil)
+	small.Set(6, nil)
+	assert.Equal(t, []KeyRange{{math.MinInt64, 5}, {6, math.MaxInt64}},
+		small.Partitions(10))
+
+	multi := New(&Options{Multi: true})
+	for i := 0; i < 100; i++ {
+		multi.Set(int64(i%2), i)
+	}
+	assert.Equal(t, []KeyRange{{math.MinInt64, 0}, {1, math.MaxInt64}},
+		multi.Partitions(4))
+}
@@ -0,0 +1,36 @@
+package tinybtree
+
+// Item is a key and its value
+type Item struct {
+	Key   int64
+	Value interface{}
+}
+
+// ReadRange copies the items within the range [lo, hi] into buf, as many as
+// fit, and returns their number. The range is done when n is less than
+// len(buf) or the last item read has the key hi. Otherwise the items that
+// follow are read by passing next as lo. Reading doesn't allocate.
+//
+// In multi mode the duplicates of the last key read that didn't fit into
+// buf are skipped by the following call, use AscendResume to page through
+// them instead.
+func (tr *BTree) ReadRange(lo, hi int64, buf []Item) (n int, next int64) {
+	if lo > hi || len(buf) == 0 {
+		return 0, lo
+	}
+	tr.Ascend(lo, func(key int64, value interface{}) bool {
+		if key > hi {
+			return false
+		}
+		buf[n] = Item{key, value}
+		n++
+		return n < len(buf)
+	})
+	if n == 0 {
+		return 0, lo
+	}
+	if last := buf[n-1].Key; last < hi {
+		return n, last + 1
+	}
+	return n, hi
+}
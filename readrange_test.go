@@ -0,0 +1,47 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRange(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i*2), i)
+	}
+	buf := make([]Item, 7)
+	var keys []int64
+	lo, hi := int64(11), int64(60)
+	for {
+		n, next := tr.ReadRange(lo, hi, buf)
+		for _, it := range buf[:n] {
+			keys = append(keys, it.Key)
+			assert.Equal(t, int(it.Key/2), it.Value)
+		}
+		if n < len(buf) || buf[n-1].Key == hi {
+			break
+		}
+		lo = next
+	}
+	var want []int64
+	for k := int64(12); k <= 60; k += 2 {
+		want = append(want, k)
+	}
+	assert.Equal(t, want, keys)
+
+	// the last key read is hi
+	n, _ := tr.ReadRange(0, 12, buf)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, int64(12), buf[n-1].Key)
+
+	n, next := tr.ReadRange(500, 600, buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, int64(500), next)
+	if allocs := testing.AllocsPerRun(100, func() {
+		tr.ReadRange(0, 200, buf)
+	}); allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
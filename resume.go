@@ -0,0 +1,60 @@
+package tinybtree
+
+// Resume is the position of an AscendResume or DescendResume. Set Key to
+// the pivot of the first call and pass the same Resume to the following
+// calls, each of which continues after the last visited item.
+type Resume struct {
+	Key  int64 // the pivot, or the key of the last visited item
+	Done bool  // the iteration reached the end of the tree
+	skip int   // number of visited items with Key
+}
+
+// AscendResume ascends the tree from r.Key like Ascend, skipping the items
+// that were visited by earlier calls with r. The item for which iter
+// returns false counts as visited. When iter never returns false r.Done is
+// set.
+func (tr *BTree) AscendResume(
+	r *Resume,
+	iter func(key int64, value interface{}) bool,
+) {
+	it := r.state(tr, iter)
+	tr.ascend(r.Key, &it)
+}
+
+// DescendResume descends the tree from r.Key like Descend, skipping the
+// items that were visited by earlier calls with r. The item for which iter
+// returns false counts as visited. When iter never returns false r.Done is
+// set.
+func (tr *BTree) DescendResume(
+	r *Resume,
+	iter func(key int64, value interface{}) bool,
+) {
+	it := r.state(tr, iter)
+	tr.descend(r.Key, &it)
+}
+
+// state returns the iterState for continuing at r. It records every
+// visited item in r and sets r.Done until iter returns false.
+func (r *Resume) state(
+	tr *BTree,
+	iter func(key int64, value interface{}) bool,
+) iterState {
+	r.Done = true
+	return iterState{
+		cur:   &tr.mods,
+		yield: tr.yield,
+		last:  r.Key, seen: r.skip, skip: r.skip,
+		iter: func(key int64, value interface{}) bool {
+			if key == r.Key && r.skip > 0 {
+				r.skip++
+			} else {
+				r.Key, r.skip = key, 1
+			}
+			if !iter(key, value) {
+				r.Done = false
+				return false
+			}
+			return true
+		},
+	}
+}
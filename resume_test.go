@@ -0,0 +1,64 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAscendResume(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Multi: multi})
+		var expect []int64
+		for _, i := range rand.Perm(1000) {
+			tr.Set(int64(i/3), i)
+		}
+		tr.Ascend(10, func(key int64, value interface{}) bool {
+			expect = append(expect, key)
+			return true
+		})
+
+		// pages of 7 items
+		var keys []int64
+		r := Resume{Key: 10}
+		for pages := 0; !r.Done; pages++ {
+			if pages > len(expect) {
+				t.Fatal("too many pages")
+			}
+			n := 0
+			tr.AscendResume(&r, func(key int64, value interface{}) bool {
+				keys = append(keys, key)
+				n++
+				return n < 7
+			})
+		}
+		assert.Equal(t, expect, keys)
+
+		expect = expect[:0]
+		tr.Descend(200, func(key int64, value interface{}) bool {
+			expect = append(expect, key)
+			return true
+		})
+		keys = keys[:0]
+		r = Resume{Key: 200}
+		for !r.Done {
+			n := 0
+			tr.DescendResume(&r, func(key int64, value interface{}) bool {
+				keys = append(keys, key)
+				n++
+				return n < 5
+			})
+		}
+		assert.Equal(t, expect, keys)
+	}
+
+	var tr BTree
+	r := Resume{Key: math.MinInt64}
+	tr.AscendResume(&r, func(key int64, value interface{}) bool {
+		t.Fatal("empty tree")
+		return true
+	})
+	assert.Equal(t, true, r.Done)
+}
@@ -0,0 +1,45 @@
+package tinybtree
+
+// Retainer keeps track of the values held by a tree, such as reference
+// counted values which must be released once no tree holds them anymore.
+// Release is called exactly once for every Retain, when the value is
+// replaced, deleted, moved to another tree or cleared. Values of a tree
+// that is dropped without calling Clear are never released.
+type Retainer interface {
+	// Retain is called when value is stored in the tree
+	Retain(value interface{})
+	// Release is called when value is removed from the tree
+	Release(value interface{})
+}
+
+// Clear removes all items from the tree, releasing their values when the
+// tree has a Retainer.
+func (tr *BTree) Clear() {
+	tr.checkWrite()
+	t := tr.detach()
+	if tr.bloom != nil {
+		tr.bloom = newBloom(0)
+	}
+	if tr.ret != nil && t.root != nil {
+		t.root.each(func(key int64, value interface{}) {
+			tr.ret.Release(value)
+		}, t.height)
+	}
+	if tr.alloc != nil && t.root != nil {
+		t.root.free(tr, t.height)
+	}
+	tr.emit(Event{Kind: EventClear})
+}
+
+// each calls fn for each item in the subtree
+func (n *node) each(fn func(key int64, value interface{}), height int) {
+	for i := 0; i < n.numItems; i++ {
+		if height > 0 {
+			n.children[i].each(fn, height-1)
+		}
+		fn(n.keys[i], n.vals[i].val())
+	}
+	if height > 0 {
+		n.children[n.numItems].each(fn, height-1)
+	}
+}
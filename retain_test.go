@@ -0,0 +1,71 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type refCounter map[interface{}]int
+
+func (r refCounter) Retain(value interface{}) {
+	r[value]++
+}
+
+func (r refCounter) Release(value interface{}) {
+	if r[value] == 0 {
+		panic("released too often")
+	}
+	r[value]--
+	if r[value] == 0 {
+		delete(r, value)
+	}
+}
+
+func TestRetainer(t *testing.T) {
+	refs := refCounter{}
+	tr := New(&Options{Retainer: refs})
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	assert.Equal(t, 100, len(refs))
+
+	// overwrite
+	tr.Set(5, 500)
+	assert.Equal(t, 0, refs[5])
+	assert.Equal(t, 1, refs[500])
+	tr.Set(6, 6)
+	assert.Equal(t, 1, refs[6])
+
+	// delete
+	tr.Delete(7)
+	assert.Equal(t, 0, refs[7])
+	tr.DeleteOne(8)
+	assert.Equal(t, 0, refs[8])
+	tr.Delete(1000)
+	assert.Equal(t, 98, len(refs))
+
+	// move
+	refs2 := refCounter{}
+	dst := New(&Options{Retainer: refs2})
+	dst.Set(10, "x")
+	assert.Equal(t, 11, tr.MoveRange(dst, 10, 20))
+	assert.Equal(t, 87, len(refs))
+	assert.Equal(t, 11, len(refs2))
+	assert.Equal(t, 0, refs2["x"])
+
+	tr.Clear()
+	assert.Equal(t, 0, len(refs))
+	assert.Equal(t, 0, tr.Len())
+	dst.Clear()
+	assert.Equal(t, 0, len(refs2))
+
+	// multi
+	tr = New(&Options{Multi: true, Retainer: refs})
+	for i := 0; i < 10; i++ {
+		tr.Set(1, i)
+	}
+	assert.Equal(t, 10, len(refs))
+	tr.Delete(1)
+	assert.Equal(t, 0, len(refs))
+}
@@ -0,0 +1,76 @@
+package tinybtree
+
+import "math"
+
+// Sample visits every k-th item in key order, starting with the first item,
+// where k is every. Whole subtrees between the visited items are skipped
+// using their counts, so sampling m items takes about O(m log n) time
+// instead of visiting all items. The iter function may modify the tree, in
+// which case sampling continues every items after the last visited one.
+func (tr *BTree) Sample(
+	every int,
+	iter func(key int64, value interface{}) bool,
+) {
+	if every < 1 {
+		every = 1
+	}
+	it := iterState{cur: &tr.mods, iter: iter, yield: tr.yield}
+	defer it.catch()
+	skip := 0
+	for tr.root != nil {
+		it.mods = tr.mods
+		if tr.root.sample(&skip, every, &it, tr.height) || !it.stale {
+			return
+		}
+		it.stale = false
+		if tr.root == nil {
+			return
+		}
+		skip = tr.after(it.last, it.seen) + every - 1
+	}
+}
+
+// after returns the index of the item following the seen'th item with key
+func (tr *BTree) after(key int64, seen int) int {
+	index := tr.root.rank(key, tr.height)
+	next := tr.length
+	if key < math.MaxInt64 {
+		next = tr.root.rank(key+1, tr.height)
+	}
+	if next-index < seen {
+		return next
+	}
+	return index + seen
+}
+
+func (n *node) sample(skip *int, every int, it *iterState, height int) bool {
+	if height == 0 {
+		i := *skip
+		for ; i < n.numItems; i += every {
+			if !it.visit(n.keys[i], n.vals[i].val()) {
+				return false
+			}
+		}
+		*skip = i - n.numItems
+		return true
+	}
+	for i := 0; i <= n.numItems; i++ {
+		if *skip >= n.children[i].count {
+			*skip -= n.children[i].count
+		} else if !n.children[i].sample(skip, every, it, height-1) {
+			return false
+		}
+		if i == n.numItems {
+			break
+		}
+		if *skip > 0 {
+			*skip--
+			continue
+		}
+		if !it.visit(n.keys[i], n.vals[i].val()) {
+			return false
+		}
+		*skip = every - 1
+	}
+	return true
+}
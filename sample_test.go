@@ -0,0 +1,58 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	var tr BTree
+	tr.Sample(10, func(key int64, value interface{}) bool {
+		t.Fatal("empty tree")
+		return true
+	})
+	const N = 10000
+	for _, i := range rand.Perm(N) {
+		tr.Set(int64(i), i)
+	}
+	for _, every := range []int{0, 1, 2, 7, 100, N - 1, N, N + 1} {
+		var keys []int64
+		tr.Sample(every, func(key int64, value interface{}) bool {
+			assert.Equal(t, int(key), value)
+			keys = append(keys, key)
+			return true
+		})
+		step := every
+		if step < 1 {
+			step = 1
+		}
+		var expect []int64
+		for i := 0; i < N; i += step {
+			expect = append(expect, int64(i))
+		}
+		assert.Equal(t, expect, keys)
+	}
+
+	// stop early
+	var keys []int64
+	tr.Sample(1000, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	assert.Equal(t, []int64{0, 1000, 2000}, keys)
+
+	// delete every visited item
+	keys = keys[:0]
+	tr.Sample(10, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		tr.Delete(key)
+		return true
+	})
+	assert.Equal(t, N/10, len(keys))
+	for i, key := range keys {
+		assert.Equal(t, int64(i*10), key)
+	}
+	assert.Equal(t, N-N/10, tr.Len())
+}
@@ -0,0 +1,65 @@
+//go:build !tinybtree_linear
+
+package tinybtree
+
+// defaultMaxItems is the capacity of a node unless a degree is chosen with
+// a build tag
+const defaultMaxItems = 31
+
+func (n *node) find(key int64) (index int, found bool) {
+	i, j := 0, n.numItems
+	for i < j {
+		h := i + (j-i)/2
+		if key >= n.keys[h] {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i > 0 && n.keys[i-1] >= key {
+		return i - 1, true
+	}
+	return i, false
+}
+
+// findFirst returns the index of the first item that is not less than key.
+func (n *node) findFirst(key int64) (index int, found bool) {
+	i, j := 0, n.numItems
+	for i < j {
+		h := i + (j-i)/2
+		if key > n.keys[h] {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	return i, i < n.numItems && n.keys[i] == key
+}
+
+// upper returns the number of items with a key less than or equal to key
+func (n *bpNode) upper(key int64) int {
+	i, j := 0, n.numItems
+	for i < j {
+		h := i + (j-i)/2
+		if key >= n.items[h].key {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	return i
+}
+
+// lower returns the number of items with a key less than key
+func (n *bpNode) lower(key int64) int {
+	i, j := 0, n.numItems
+	for i < j {
+		h := i + (j-i)/2
+		if key > n.items[h].key {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	return i
+}
@@ -0,0 +1,49 @@
+//go:build tinybtree_linear
+
+package tinybtree
+
+// With the tinybtree_linear build tag nodes are a power of two in size and
+// are searched with linear scans, which beat binary searches for small nodes
+// on many CPUs.
+
+// defaultMaxItems is the capacity of a node unless a degree is chosen with
+// a build tag
+const defaultMaxItems = 16
+
+func (n *node) find(key int64) (index int, found bool) {
+	i := 0
+	for i < n.numItems && key >= n.keys[i] {
+		i++
+	}
+	if i > 0 && n.keys[i-1] >= key {
+		return i - 1, true
+	}
+	return i, false
+}
+
+// findFirst returns the index of the first item that is not less than key.
+func (n *node) findFirst(key int64) (index int, found bool) {
+	i := 0
+	for i < n.numItems && key > n.keys[i] {
+		i++
+	}
+	return i, i < n.numItems && n.keys[i] == key
+}
+
+// upper returns the number of items with a key less than or equal to key
+func (n *bpNode) upper(key int64) int {
+	i := 0
+	for i < n.numItems && key >= n.items[i].key {
+		i++
+	}
+	return i
+}
+
+// lower returns the number of items with a key less than key
+func (n *bpNode) lower(key int64) int {
+	i := 0
+	for i < n.numItems && key > n.items[i].key {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,182 @@
+package tinybtree
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// Serializer encodes the values of one type for snapshots, as an
+// alternative to registering the type with gob, such as for protobuf
+// messages or types from other packages. See RegisterSerializer.
+type Serializer interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// taggedValue is how a snapshot holds a value of a type with a Serializer
+type taggedValue struct {
+	Tag  string
+	Data []byte
+}
+
+func init() {
+	gob.RegisterName("tinybtree.taggedValue", taggedValue{})
+}
+
+var serializers struct {
+	sync.RWMutex
+	byType map[reflect.Type]taggedSerializer
+	byTag  map[string]Serializer
+}
+
+type taggedSerializer struct {
+	tag string
+	s   Serializer
+}
+
+// RegisterSerializer makes WriteTo encode the values with the type of
+// sample with s, and ReadFrom decode them again. The tag is written along
+// with each value to find the Serializer when reading, so it must stay the
+// same as long as snapshots are around. Like gob.Register it's usually
+// called from an init function. It panics when the tag or the type was
+// registered already.
+func RegisterSerializer(tag string, sample interface{}, s Serializer) {
+	typ := reflect.TypeOf(sample)
+	serializers.Lock()
+	defer serializers.Unlock()
+	if serializers.byType == nil {
+		serializers.byType = make(map[reflect.Type]taggedSerializer)
+		serializers.byTag = make(map[string]Serializer)
+	}
+	if _, ok := serializers.byTag[tag]; ok {
+		panic(fmt.Sprintf("tinybtree: serializer tag %q registered twice",
+			tag))
+	}
+	if _, ok := serializers.byType[typ]; ok {
+		panic(fmt.Sprintf("tinybtree: serializer for %v registered twice",
+			typ))
+	}
+	serializers.byType[typ] = taggedSerializer{tag, s}
+	serializers.byTag[tag] = s
+}
+
+// serialize returns the value as it's written to a snapshot
+func serialize(value interface{}) (interface{}, error) {
+	serializers.RLock()
+	ts, ok := serializers.byType[reflect.TypeOf(value)]
+	serializers.RUnlock()
+	if !ok {
+		return value, nil
+	}
+	data, err := ts.s.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return taggedValue{Tag: ts.tag, Data: data}, nil
+}
+
+// deserialize returns the value that was written to a snapshot
+func deserialize(value interface{}) (interface{}, error) {
+	tv, ok := value.(taggedValue)
+	if !ok {
+		return value, nil
+	}
+	serializers.RLock()
+	s, ok := serializers.byTag[tv.Tag]
+	serializers.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("tinybtree: no serializer for tag %q",
+			tv.Tag)
+	}
+	return s.Unmarshal(tv.Data)
+}
+
+// ScalarSerializer returns a Serializer for the type of sample, which must
+// be a bool, a string or a number, such as a named string type of an enum.
+// Unlike gob it needs no registration of the type and keeps the type when
+// the value is read back.
+func ScalarSerializer(sample interface{}) Serializer {
+	typ := reflect.TypeOf(sample)
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return scalarSerializer{typ}
+	}
+	panic(fmt.Sprintf("tinybtree: %v is not a scalar type", typ))
+}
+
+type scalarSerializer struct {
+	typ reflect.Type
+}
+
+var errScalarType = errors.New("tinybtree: value of another type")
+
+func (s scalarSerializer) Marshal(value interface{}) ([]byte, error) {
+	v := reflect.ValueOf(value)
+	if v.Type() != s.typ {
+		return nil, errScalarType
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case reflect.String:
+		return []byte(v.String()), nil
+	case reflect.Float32, reflect.Float64:
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(v.Float()))
+		return data, nil
+	}
+	data := make([]byte, binary.MaxVarintLen64)
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return data[:binary.PutUvarint(data, v.Uint())], nil
+	}
+	return data[:binary.PutVarint(data, v.Int())], nil
+}
+
+func (s scalarSerializer) Unmarshal(data []byte) (interface{}, error) {
+	v := reflect.New(s.typ).Elem()
+	var n int
+	switch v.Kind() {
+	case reflect.Bool:
+		if len(data) != 1 {
+			return nil, fmt.Errorf("tinybtree: invalid %v", s.typ)
+		}
+		v.SetBool(data[0] != 0)
+		return v.Interface(), nil
+	case reflect.String:
+		v.SetString(string(data))
+		return v.Interface(), nil
+	case reflect.Float32, reflect.Float64:
+		if len(data) != 8 {
+			return nil, fmt.Errorf("tinybtree: invalid %v", s.typ)
+		}
+		v.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(data)))
+		return v.Interface(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		var x uint64
+		x, n = binary.Uvarint(data)
+		v.SetUint(x)
+	default:
+		var x int64
+		x, n = binary.Varint(data)
+		v.SetInt(x)
+	}
+	if n <= 0 || n != len(data) {
+		return nil, fmt.Errorf("tinybtree: invalid %v", s.typ)
+	}
+	return v.Interface(), nil
+}
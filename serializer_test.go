@@ -0,0 +1,84 @@
+package tinybtree
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testStatus string
+
+type testLevel uint8
+
+type testScore float32
+
+// decimal is serialized as text
+type decimal struct{ cents int64 }
+
+type decimalSerializer struct{}
+
+func (decimalSerializer) Marshal(value interface{}) ([]byte, error) {
+	return []byte(strconv.FormatInt(value.(decimal).cents, 10)), nil
+}
+
+func (decimalSerializer) Unmarshal(data []byte) (interface{}, error) {
+	cents, err := strconv.ParseInt(string(data), 10, 64)
+	return decimal{cents}, err
+}
+
+func init() {
+	RegisterSerializer("test.status", testStatus(""),
+		ScalarSerializer(testStatus("")))
+	RegisterSerializer("test.level", testLevel(0),
+		ScalarSerializer(testLevel(0)))
+	RegisterSerializer("test.score", testScore(0),
+		ScalarSerializer(testScore(0)))
+	RegisterSerializer("test.decimal", decimal{}, decimalSerializer{})
+}
+
+func TestSerializer(t *testing.T) {
+	var tr BTree
+	values := []interface{}{
+		testStatus("open"), testLevel(200), testScore(-1.5),
+		decimal{-1234}, "plain", 42,
+	}
+	for i, v := range values {
+		tr.Set(int64(i), v)
+	}
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var read BTree
+	if _, err := read.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		got, _ := read.Get(int64(i))
+		assert.Equal(t, v, got)
+	}
+
+	s := ScalarSerializer(int16(0))
+	for _, x := range []int16{0, -1, 300, -32768} {
+		data, err := s.Marshal(x)
+		assert.Equal(t, nil, err)
+		v, err := s.Unmarshal(data)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, x, v)
+	}
+	_, err := s.Marshal(1)
+	assert.Equal(t, errScalarType, err)
+	_, err = s.Unmarshal(nil)
+	assert.Equal(t, "tinybtree: invalid int16", err.Error())
+
+	_, err = deserialize(taggedValue{Tag: "missing"})
+	assert.Equal(t, `tinybtree: no serializer for tag "missing"`, err.Error())
+
+	defer func() {
+		assert.Equal(t, `tinybtree: serializer tag "test.status" registered `+
+			"twice", recover())
+	}()
+	RegisterSerializer("test.status", "", ScalarSerializer(""))
+}
@@ -0,0 +1,74 @@
+package tinybtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteShape writes the shape of the tree to w for diagnosing trees that
+// were left badly filled, such as by adversarial inserts and deletes. The
+// first line sums up the tree and every following line is a node, indented
+// by its depth, with its number of items, how full it is, its first and
+// last key and the number of items of its subtree:
+//
+//	height 1, 40 items, 3 nodes, 43% full
+//	[ 1/31   3%] 15..15 (40)
+//	  [15/31  48%] 0..14
+//	  [24/31  77%] 16..39
+//
+// Lines longer than width are cut, there is no limit when width is zero.
+// All nodes are visited, so use it on small trees or with a small width.
+func (tr *BTree) WriteShape(w io.Writer, width int) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		if width > 0 && len(s) > width {
+			s = s[:width]
+		}
+		bw.WriteString(s)
+		bw.WriteByte('\n')
+	}
+	var nodes, items int
+	if tr.root != nil {
+		tr.root.shape(func(n *node, depth, height int) {
+			nodes++
+			items += n.numItems
+		}, 0, tr.height)
+	}
+	fill := 0
+	if nodes > 0 {
+		fill = items * 100 / (nodes * maxItems)
+	}
+	line(fmt.Sprintf("height %d, %d items, %d nodes, %d%% full",
+		tr.height, tr.length, nodes, fill))
+	if tr.root != nil {
+		tr.root.shape(func(n *node, depth, height int) {
+			s := fmt.Sprintf("%s[%2d/%d %3d%%] %d..%d",
+				strings.Repeat("  ", depth), n.numItems, maxItems,
+				n.numItems*100/maxItems, n.keys[0], n.keys[n.numItems-1])
+			if height > 0 {
+				s += fmt.Sprintf(" (%d)", n.count)
+			}
+			line(s)
+		}, 0, tr.height)
+	}
+	return bw.Flush()
+}
+
+// Shape returns what WriteShape writes
+func (tr *BTree) Shape(width int) string {
+	var sb strings.Builder
+	tr.WriteShape(&sb, width)
+	return sb.String()
+}
+
+// shape calls fn for each node of the subtree, parents before children
+func (n *node) shape(fn func(n *node, depth, height int), depth, height int) {
+	fn(n, depth, height)
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			n.children[i].shape(fn, depth+1, height-1)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package tinybtree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShape(t *testing.T) {
+	var tr BTree
+	assert.Equal(t, "height 0, 0 items, 0 nodes, 0% full\n", tr.Shape(0))
+	tr.Set(1, nil)
+	tr.Set(2, nil)
+	assert.Equal(t, fmt.Sprintf("height 0, 2 items, 1 nodes, %d%% full\n"+
+		"[ 2/%d %3d%%] 1..2\n", 200/maxItems, maxItems, 200/maxItems),
+		tr.Shape(0))
+
+	for i := 0; i < 1000; i++ {
+		tr.Set(int64(i), nil)
+	}
+	lines := strings.Split(strings.TrimSuffix(tr.Shape(20), "\n"), "\n")
+	var nodes int
+	tr.root.shape(func(*node, int, int) { nodes++ }, 0, tr.height)
+	assert.Equal(t, nodes+1, len(lines))
+	for _, line := range lines {
+		if len(line) > 20 {
+			t.Fatalf("line too long: %q", line)
+		}
+	}
+	assert.Equal(t, "  [", lines[2][:3])
+}
@@ -0,0 +1,66 @@
+package tinybtree
+
+// SortedSlice is a read-only view of a tree as a slice that is sorted by
+// key. It implements sort.Interface, each access takes O(log n) time.
+type SortedSlice struct {
+	tr *BTree
+}
+
+// AsSortedSlice returns a view of the tree as a sorted slice. The view must
+// not be used after the tree was modified.
+func (tr *BTree) AsSortedSlice() SortedSlice {
+	return SortedSlice{tr}
+}
+
+// Len returns the number of items
+func (s SortedSlice) Len() int {
+	return s.tr.length
+}
+
+// Less reports whether the key at index i is less than the key at index j
+func (s SortedSlice) Less(i, j int) bool {
+	return s.Key(i) < s.Key(j)
+}
+
+// Swap does nothing, the items are always in order
+func (s SortedSlice) Swap(i, j int) {}
+
+// Key returns the key at index i
+func (s SortedSlice) Key(i int) int64 {
+	return s.tr.root.at(i, s.tr.height).key
+}
+
+// Value returns the value at index i
+func (s SortedSlice) Value(i int) interface{} {
+	return s.tr.root.at(i, s.tr.height).val()
+}
+
+// Keys returns the keys of the tree in order. They are written to buf,
+// which is grown when it's too small.
+func (tr *BTree) Keys(buf []int64) []int64 {
+	keys := buf[:0]
+	if cap(keys) < tr.length {
+		keys = make([]int64, 0, tr.length)
+	}
+	if tr.root != nil {
+		tr.root.each(func(key int64, value interface{}) {
+			keys = append(keys, key)
+		}, tr.height)
+	}
+	return keys
+}
+
+// Values returns the values of the tree in the order of their keys. They
+// are written to buf, which is grown when it's too small.
+func (tr *BTree) Values(buf []interface{}) []interface{} {
+	values := buf[:0]
+	if cap(values) < tr.length {
+		values = make([]interface{}, 0, tr.length)
+	}
+	if tr.root != nil {
+		tr.root.each(func(key int64, value interface{}) {
+			values = append(values, value)
+		}, tr.height)
+	}
+	return values
+}
@@ -0,0 +1,42 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedSlice(t *testing.T) {
+	var tr BTree
+	for _, i := range rand.Perm(1000) {
+		tr.Set(int64(i*2), i)
+	}
+	s := tr.AsSortedSlice()
+	assert.Equal(t, 1000, s.Len())
+	assert.Equal(t, true, sort.IsSorted(s))
+	sort.Sort(s)
+	assert.Equal(t, int64(20), s.Key(10))
+	assert.Equal(t, 10, s.Value(10))
+	i := sort.Search(s.Len(), func(i int) bool { return s.Key(i) >= 301 })
+	assert.Equal(t, 151, i)
+
+	keys := tr.Keys(nil)
+	assert.Equal(t, 1000, len(keys))
+	assert.Equal(t, true, sort.SliceIsSorted(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	}))
+	buf := make([]int64, 5, 2000)
+	keys2 := tr.Keys(buf)
+	assert.Equal(t, keys, keys2)
+	assert.Equal(t, &buf[:1][0], &keys2[0])
+
+	values := tr.Values(nil)
+	assert.Equal(t, 1000, len(values))
+	assert.Equal(t, 999, values[999])
+
+	var empty BTree
+	assert.Equal(t, 0, len(empty.Keys(nil)))
+	assert.Equal(t, 0, empty.AsSortedSlice().Len())
+}
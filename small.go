@@ -0,0 +1,151 @@
+package tinybtree
+
+import "sort"
+
+// SmallTreeMax is the number of items up to which a SmallTree keeps them in
+// a sorted slice
+const SmallTreeMax = 16
+
+// SmallTree is an ordered map for the many trees that only ever hold a few
+// items, such as one per user. Up to SmallTreeMax items are kept in a
+// sorted slice, which takes a fraction of the memory of a node, and past
+// that the items move into a BTree for good. The zero value is an empty
+// tree.
+type SmallTree struct {
+	items []Item
+	tr    *BTree // the tree once it grew past SmallTreeMax
+}
+
+// find returns the index of the first item with a key that is not less than
+// key, and whether it has the key
+func (t *SmallTree) find(key int64) (int, bool) {
+	i := sort.Search(len(t.items), func(i int) bool {
+		return t.items[i].Key >= key
+	})
+	return i, i < len(t.items) && t.items[i].Key == key
+}
+
+// Set or replace a value for a key
+func (t *SmallTree) Set(key int64, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	if t.tr != nil {
+		return t.tr.Set(key, value)
+	}
+	i, found := t.find(key)
+	if found {
+		prev = t.items[i].Value
+		t.items[i].Value = value
+		return prev, true
+	}
+	if len(t.items) == SmallTreeMax {
+		b := NewBuilder(nil)
+		for _, it := range t.items {
+			b.Add(it.Key, it.Value)
+		}
+		t.tr, t.items = b.Build(), nil
+		return t.tr.Set(key, value)
+	}
+	t.items = append(t.items, Item{})
+	copy(t.items[i+1:], t.items[i:])
+	t.items[i] = Item{key, value}
+	return nil, false
+}
+
+// Get a value for key
+func (t *SmallTree) Get(key int64) (value interface{}, gotten bool) {
+	if t.tr != nil {
+		return t.tr.Get(key)
+	}
+	if i, found := t.find(key); found {
+		return t.items[i].Value, true
+	}
+	return nil, false
+}
+
+// Delete a value for a key
+func (t *SmallTree) Delete(key int64) (prev interface{}, deleted bool) {
+	if t.tr != nil {
+		return t.tr.Delete(key)
+	}
+	i, found := t.find(key)
+	if !found {
+		return nil, false
+	}
+	prev = t.items[i].Value
+	copy(t.items[i:], t.items[i+1:])
+	t.items[len(t.items)-1] = Item{}
+	t.items = t.items[:len(t.items)-1]
+	return prev, true
+}
+
+// Len returns the number of items in the tree
+func (t *SmallTree) Len() int {
+	if t.tr != nil {
+		return t.tr.Len()
+	}
+	return len(t.items)
+}
+
+// Scan all items in the tree. The tree must not be modified during the scan.
+func (t *SmallTree) Scan(iter func(key int64, value interface{}) bool) {
+	if t.tr != nil {
+		t.tr.Scan(iter)
+		return
+	}
+	for _, it := range t.items {
+		if !iter(it.Key, it.Value) {
+			return
+		}
+	}
+}
+
+// Ascend the tree within the range [pivot, last]
+func (t *SmallTree) Ascend(
+	pivot int64,
+	iter func(key int64, value interface{}) bool,
+) {
+	if t.tr != nil {
+		t.tr.Ascend(pivot, iter)
+		return
+	}
+	i, _ := t.find(pivot)
+	for _, it := range t.items[i:] {
+		if !iter(it.Key, it.Value) {
+			return
+		}
+	}
+}
+
+// Reverse all items in the tree
+func (t *SmallTree) Reverse(iter func(key int64, value interface{}) bool) {
+	if t.tr != nil {
+		t.tr.Reverse(iter)
+		return
+	}
+	for i := len(t.items) - 1; i >= 0; i-- {
+		if !iter(t.items[i].Key, t.items[i].Value) {
+			return
+		}
+	}
+}
+
+// Descend the tree within the range [pivot, first]
+func (t *SmallTree) Descend(
+	pivot int64,
+	iter func(key int64, value interface{}) bool,
+) {
+	if t.tr != nil {
+		t.tr.Descend(pivot, iter)
+		return
+	}
+	i, found := t.find(pivot)
+	if found {
+		i++
+	}
+	for i--; i >= 0; i-- {
+		if !iter(t.items[i].Key, t.items[i].Value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmallTree(t *testing.T) {
+	var st SmallTree
+	m := map[int64]int{}
+	for i := 0; i < 2000; i++ {
+		key := int64(rand.Intn(100))
+		if i < 1000 {
+			// stay small for a while
+			key %= SmallTreeMax
+		}
+		if rand.Intn(3) == 0 {
+			_, ok := st.Delete(key)
+			_, want := m[key]
+			assert.Equal(t, want, ok)
+			delete(m, key)
+		} else {
+			_, ok := st.Set(key, i)
+			_, want := m[key]
+			assert.Equal(t, want, ok)
+			m[key] = i
+		}
+		if i == 999 && st.tr != nil {
+			t.Fatal("expected a small tree")
+		}
+		assert.Equal(t, len(m), st.Len())
+	}
+	if st.tr == nil {
+		t.Fatal("expected a BTree")
+	}
+	for key, want := range m {
+		v, _ := st.Get(key)
+		assert.Equal(t, want, v)
+	}
+}
+
+func TestSmallTreeIterate(t *testing.T) {
+	var st SmallTree
+	for _, i := range []int64{5, 1, 3, 9, 7} {
+		st.Set(i, int(i))
+	}
+	collect := func(fn func(iter func(key int64, value interface{}) bool)) (
+		keys []int64,
+	) {
+		fn(func(key int64, value interface{}) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return keys
+	}
+	for _, big := range []bool{false, true} {
+		if big {
+			for i := int64(100); i < 100+SmallTreeMax; i++ {
+				st.Set(i, int(i))
+			}
+			for i := int64(100); i < 100+SmallTreeMax; i++ {
+				st.Delete(i)
+			}
+		}
+		assert.Equal(t, []int64{1, 3, 5, 7, 9}, collect(st.Scan))
+		assert.Equal(t, []int64{9, 7, 5, 3, 1}, collect(st.Reverse))
+		assert.Equal(t, []int64{5, 7, 9},
+			collect(func(iter func(int64, interface{}) bool) {
+				st.Ascend(4, iter)
+			}))
+		assert.Equal(t, []int64{5, 3, 1},
+			collect(func(iter func(int64, interface{}) bool) {
+				st.Descend(5, iter)
+			}))
+	}
+	if st.tr == nil {
+		t.Fatal("expected a BTree")
+	}
+}
@@ -0,0 +1,349 @@
+package tinybtree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"hash/crc32"
+	"io"
+)
+
+// A snapshot starts with snapshotMagic and a version byte, followed by a gob
+// stream of a snapshotHeader and one snapshotItem for each item in key
+// order. Version 0 snapshots have no magic and version, they start with the
+// gob stream right away. A gob stream never starts with a zero byte, so the
+// two can be told apart.
+//
+// Since version 2 the gob stream is split into blocks, each of which is a
+// 4 byte length, the data and the CRC-32C of the data. The last block is
+// empty and its checksum is the CRC-32C of the whole stream.
+const (
+	snapshotMagic   = "\x00TBS"
+	snapshotVersion = 2
+	blockSize       = 32 << 10
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type snapshotHeader struct {
+	Count int
+}
+
+type snapshotItem struct {
+	Key   int64
+	Value interface{}
+}
+
+type snapshotWriter struct {
+	cw  *countWriter
+	bw  *blockWriter
+	enc *gob.Encoder
+}
+
+// newSnapshotWriter writes the header of a snapshot of count items
+func newSnapshotWriter(w io.Writer, count int) (*snapshotWriter, error) {
+	sw := &snapshotWriter{cw: &countWriter{w: w}}
+	_, err := sw.cw.Write(append([]byte(snapshotMagic), snapshotVersion))
+	if err != nil {
+		return sw, err
+	}
+	sw.bw = &blockWriter{w: sw.cw}
+	sw.enc = gob.NewEncoder(sw.bw)
+	return sw, sw.enc.Encode(snapshotHeader{Count: count})
+}
+
+func (sw *snapshotWriter) write(key int64, value interface{}) error {
+	value, err := serialize(value)
+	if err != nil {
+		return err
+	}
+	return sw.enc.Encode(snapshotItem{Key: key, Value: value})
+}
+
+// close writes the last blocks of the snapshot
+func (sw *snapshotWriter) close() error {
+	return sw.bw.close()
+}
+
+// blockWriter splits a stream into checksummed blocks
+type blockWriter struct {
+	w   io.Writer
+	buf []byte
+	sum uint32 // checksum of the whole stream
+}
+
+func (bw *blockWriter) Write(p []byte) (int, error) {
+	bw.buf = append(bw.buf, p...)
+	for len(bw.buf) >= blockSize {
+		if err := bw.flush(bw.buf[:blockSize]); err != nil {
+			return 0, err
+		}
+		bw.buf = bw.buf[:copy(bw.buf, bw.buf[blockSize:])]
+	}
+	return len(p), nil
+}
+
+// flush writes data as a block
+func (bw *blockWriter) flush(data []byte) error {
+	bw.sum = crc32.Update(bw.sum, castagnoli, data)
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(data)))
+	if _, err := bw.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := bw.w.Write(data); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.Checksum(data, castagnoli))
+	_, err := bw.w.Write(sum[:])
+	return err
+}
+
+// close writes the buffered data and the last block
+func (bw *blockWriter) close() error {
+	if len(bw.buf) > 0 {
+		if err := bw.flush(bw.buf); err != nil {
+			return err
+		}
+		bw.buf = bw.buf[:0]
+	}
+	var end [8]byte
+	binary.BigEndian.PutUint32(end[4:], bw.sum)
+	_, err := bw.w.Write(end[:])
+	return err
+}
+
+// blockReader reads the stream of a blockWriter, verifying the checksums
+type blockReader struct {
+	cr   *countReader
+	buf  []byte // the unread data of the current block
+	sum  uint32 // checksum of the stream so far
+	done bool   // the last block was read
+}
+
+func (br *blockReader) Read(p []byte) (int, error) {
+	for len(br.buf) == 0 {
+		if br.done {
+			return 0, io.EOF
+		}
+		if err := br.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, br.buf)
+	br.buf = br.buf[n:]
+	return n, nil
+}
+
+// next reads the next block
+func (br *blockReader) next() error {
+	offset := br.cr.n
+	var hdr [4]byte
+	if _, err := io.ReadFull(br.cr, hdr[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(hdr[:])
+	if size > blockSize {
+		return &SnapshotError{Offset: offset, Err: ErrChecksum}
+	}
+	data := make([]byte, size+4)
+	if _, err := io.ReadFull(br.cr, data); err != nil {
+		return err
+	}
+	data, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if len(data) == 0 {
+		br.done = true
+		if sum != br.sum {
+			return &SnapshotError{Offset: offset, Err: ErrChecksum}
+		}
+		return nil
+	}
+	if sum != crc32.Checksum(data, castagnoli) {
+		return &SnapshotError{Offset: offset, Err: ErrChecksum}
+	}
+	br.sum = crc32.Update(br.sum, castagnoli, data)
+	br.buf = data
+	return nil
+}
+
+type snapshotReader struct {
+	cr      *countReader
+	br      *blockReader // since version 2
+	dec     *gob.Decoder
+	version int
+	count   int // number of items
+	read    int // number of items read so far
+}
+
+// newSnapshotReader reads the header of a snapshot of any known version
+func newSnapshotReader(r io.Reader) (*snapshotReader, error) {
+	sr := &snapshotReader{cr: &countReader{r: r}}
+	prefix := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(sr.cr, prefix); err != nil {
+		return sr, sr.corrupt(err)
+	}
+	var stream io.Reader = sr.cr
+	if string(prefix) == snapshotMagic {
+		var version [1]byte
+		if _, err := io.ReadFull(sr.cr, version[:]); err != nil {
+			return sr, sr.corrupt(err)
+		}
+		sr.version = int(version[0])
+		if sr.version > snapshotVersion {
+			return sr, ErrSnapshotVersion
+		}
+		if sr.version >= 2 {
+			sr.br = &blockReader{cr: sr.cr}
+			stream = sr.br
+		}
+	} else {
+		stream = io.MultiReader(bytes.NewReader(prefix), sr.cr)
+	}
+	sr.dec = gob.NewDecoder(stream)
+	var hdr snapshotHeader
+	if err := sr.dec.Decode(&hdr); err != nil {
+		return sr, sr.corrupt(err)
+	}
+	sr.count = hdr.Count
+	return sr, nil
+}
+
+// next reads the next item, it returns io.EOF after the last item once the
+// whole snapshot has been verified
+func (sr *snapshotReader) next() (it snapshotItem, err error) {
+	if sr.read == sr.count {
+		if sr.br != nil {
+			// the decoder reads ahead, so the last block may have been
+			// verified already
+			if _, err := io.Copy(io.Discard, sr.br); err != nil {
+				return it, sr.corrupt(err)
+			}
+		}
+		return it, io.EOF
+	}
+	if err := sr.dec.Decode(&it); err != nil {
+		return it, sr.corrupt(err)
+	}
+	sr.read++
+	it.Value, err = deserialize(it.Value)
+	return it, err
+}
+
+// corrupt wraps an error of reading the snapshot in a SnapshotError, unless
+// it's an error of the underlying reader
+func (sr *snapshotReader) corrupt(err error) error {
+	if _, ok := err.(*SnapshotError); ok || err == sr.cr.err {
+		return err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &SnapshotError{Offset: sr.cr.n, Err: err}
+}
+
+// WriteTo writes a snapshot of the tree to w. The values are encoded with
+// encoding/gob, so the concrete types of the values other than the basic
+// types must be registered with gob.Register, or have a Serializer, see
+// RegisterSerializer.
+func (tr *BTree) WriteTo(w io.Writer) (n int64, err error) {
+	span := tr.span("WriteTo")
+	span.SetAttribute("tinybtree.items", int64(tr.length))
+	defer func() {
+		span.SetAttribute("tinybtree.bytes", n)
+		span.End(err)
+	}()
+	sw, err := newSnapshotWriter(w, tr.length)
+	if err != nil {
+		return sw.cw.n, err
+	}
+	tr.Scan(func(key int64, value interface{}) bool {
+		err = sw.write(key, value)
+		return err == nil
+	})
+	if err == nil {
+		err = sw.close()
+	}
+	return sw.cw.n, err
+}
+
+// ReadFrom reads a snapshot that was written by WriteTo and sets its items
+// in the tree. Snapshots of all earlier versions can be read. The checksums
+// of the snapshot are verified, a mismatch is reported as a SnapshotError
+// with ErrChecksum. The items that were read before the corruption was
+// detected are still set.
+func (tr *BTree) ReadFrom(r io.Reader) (n int64, err error) {
+	span := tr.span("ReadFrom")
+	items := 0
+	defer func() {
+		span.SetAttribute("tinybtree.items", int64(items))
+		span.SetAttribute("tinybtree.bytes", n)
+		span.End(err)
+	}()
+	sr, err := newSnapshotReader(r)
+	if err != nil {
+		return sr.cr.n, err
+	}
+	for ; ; items++ {
+		it, err := sr.next()
+		if err == io.EOF {
+			return sr.cr.n, nil
+		}
+		if err != nil {
+			return sr.cr.n, err
+		}
+		tr.Set(it.Key, it.Value)
+	}
+}
+
+// MigrateSnapshot rewrites a snapshot of any earlier version from r in the
+// current format to w. The items are copied one by one, without building a
+// tree in memory.
+func MigrateSnapshot(w io.Writer, r io.Reader) (n int64, err error) {
+	sr, err := newSnapshotReader(r)
+	if err != nil {
+		return 0, err
+	}
+	sw, err := newSnapshotWriter(w, sr.count)
+	if err != nil {
+		return sw.cw.n, err
+	}
+	for {
+		it, err := sr.next()
+		if err == io.EOF {
+			return sw.cw.n, sw.close()
+		}
+		if err != nil {
+			return sw.cw.n, err
+		}
+		if err := sw.write(it.Key, it.Value); err != nil {
+			return sw.cw.n, err
+		}
+	}
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type countReader struct {
+	r   io.Reader
+	n   int64
+	err error // the last error of r other than io.EOF
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,161 @@
+package tinybtree
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type snapshotValue struct {
+	Name string
+}
+
+func init() {
+	gob.Register(snapshotValue{})
+}
+
+func TestSnapshot(t *testing.T) {
+	var tr BTree
+	for _, i := range rand.Perm(1000) {
+		switch i % 4 {
+		case 0:
+			tr.Set(int64(i), i)
+		case 1:
+			tr.Set(int64(i), "x")
+		case 2:
+			tr.Set(int64(i), snapshotValue{Name: "y"})
+		case 3:
+			tr.Set(int64(i), nil)
+		}
+	}
+	var buf bytes.Buffer
+	n, err := tr.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(buf.Len()), n)
+	data := buf.Bytes()
+
+	var tr2 BTree
+	n, err = tr2.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, tr.Len(), tr2.Len())
+	tr.Diff(&tr2, func(key int64, l, r interface{}, kind DiffKind) {
+		t.Fatalf("%d %v: %v != %v", key, kind, l, r)
+	})
+
+	var tr3 BTree
+	_, err = tr3.ReadFrom(bytes.NewReader(data[:len(data)/2]))
+	if !errors.Is(err, ErrCorruptSnapshot) ||
+		!errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected %v, got %v", ErrCorruptSnapshot, err)
+	}
+}
+
+// writeSnapshotV0 writes a snapshot in the format of version 0
+func writeSnapshotV0(t *testing.T, tr *BTree) []byte {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(snapshotHeader{Count: tr.Len()}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Scan(func(key int64, value interface{}) bool {
+		if err := enc.Encode(snapshotItem{Key: key, Value: value}); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	})
+	return buf.Bytes()
+}
+
+func TestSnapshotVersions(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 500; i++ {
+		tr.Set(int64(i), snapshotValue{Name: "v"})
+	}
+	old := writeSnapshotV0(t, &tr)
+
+	var tr2 BTree
+	if _, err := tr2.ReadFrom(bytes.NewReader(old)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 500, tr2.Len())
+
+	var buf bytes.Buffer
+	n, err := MigrateSnapshot(&buf, bytes.NewReader(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, snapshotMagic, string(buf.Bytes()[:len(snapshotMagic)]))
+	var cur bytes.Buffer
+	tr.WriteTo(&cur)
+	assert.Equal(t, cur.Bytes(), buf.Bytes())
+
+	// migrating the current version is a copy
+	var again bytes.Buffer
+	if _, err := MigrateSnapshot(&again, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+
+	future := append([]byte(snapshotMagic), snapshotVersion+1)
+	var tr3 BTree
+	_, err = tr3.ReadFrom(bytes.NewReader(future))
+	assert.Equal(t, ErrSnapshotVersion, err)
+	_, err = MigrateSnapshot(io.Discard, bytes.NewReader(future))
+	assert.Equal(t, ErrSnapshotVersion, err)
+}
+
+func TestSnapshotChecksums(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 5000; i++ {
+		tr.Set(int64(i), "some value to fill the blocks")
+	}
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if len(data) < 2*blockSize {
+		t.Fatalf("expected several blocks, got %d bytes", len(data))
+	}
+	// the second block starts after the magic, version and first block
+	second := int64(len(snapshotMagic) + 1 + 4 + blockSize + 4)
+	for _, tt := range []struct {
+		pos    int
+		offset int64
+	}{
+		{len(snapshotMagic) + 1 + 10, int64(len(snapshotMagic) + 1)},
+		{int(second) + 100, second},
+		{len(data) - 1, int64(len(data) - 8)},
+	} {
+		bad := append([]byte(nil), data...)
+		bad[tt.pos] ^= 0xff
+		var tr2 BTree
+		_, err := tr2.ReadFrom(bytes.NewReader(bad))
+		if !errors.Is(err, ErrChecksum) || !errors.Is(err, ErrCorruptSnapshot) {
+			t.Fatalf("expected %v, got %v", ErrChecksum, err)
+		}
+		var serr *SnapshotError
+		errors.As(err, &serr)
+		assert.Equal(t, tt.offset, serr.Offset)
+	}
+
+	// version 1 snapshots have no checksums
+	v1 := append([]byte(snapshotMagic), 1)
+	v1 = append(v1, writeSnapshotV0(t, &tr)...)
+	var tr3 BTree
+	if _, err := tr3.ReadFrom(bytes.NewReader(v1)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5000, tr3.Len())
+}
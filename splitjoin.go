@@ -0,0 +1,364 @@
+package tinybtree
+
+import "math"
+
+// subtree is a tree detached from a BTree while it's being split or joined.
+// Unlike the root of a BTree its root may have any number of items, and it's
+// nil when the subtree is empty.
+type subtree struct {
+	root   *node
+	height int
+}
+
+func (t subtree) count() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.count
+}
+
+// detach takes all nodes out of the tree
+func (tr *BTree) detach() subtree {
+	t := subtree{tr.root, tr.height}
+	tr.root, tr.height, tr.length = nil, 0, 0
+	tr.mods++
+	return t
+}
+
+// attach makes the subtree the contents of the tree
+func (tr *BTree) attach(t subtree) {
+	t = tr.fix(t)
+	tr.root, tr.height, tr.length = t.root, t.height, t.count()
+	tr.updateMinMax()
+	tr.mods++
+}
+
+// fix turns the root of the subtree into a valid root by dropping it when
+// it's empty and splitting it when it's full.
+func (tr *BTree) fix(t subtree) subtree {
+	for t.root != nil && t.root.numItems == 0 {
+		old := t.root
+		if t.height == 0 {
+			t = subtree{}
+		} else {
+			t.root, t.height = t.root.children[0], t.height-1
+		}
+		tr.freeNode(old)
+	}
+	if t.root != nil && t.root.numItems == maxItems {
+		n := tr.mut(t.root)
+		right, median := n.split(tr, t.height)
+		t.root = tr.newNode()
+		t.root.children[0] = n
+		t.root.setItem(0, median)
+		t.root.children[1] = right
+		t.root.numItems = 1
+		t.root.count = n.count + right.count + 1
+		t.height++
+		t.root.aggregate(tr, t.height)
+	}
+	return t
+}
+
+// partial builds a subtree from the items from index i up to j of the node
+// src at height and the children around them. The node n is reused when
+// it's not nil and the tree owns it.
+func (tr *BTree) partial(n, src *node, i, j, height int) subtree {
+	if n != nil && !tr.owned(n) {
+		n = nil
+	}
+	if i == j {
+		var t subtree
+		if height > 0 {
+			t = subtree{src.children[i], height - 1}
+		}
+		if n != nil {
+			tr.freeNode(n)
+		}
+		return t
+	}
+	if n == nil {
+		n = tr.newNode()
+	}
+	n.copyItems(0, src, i, j)
+	if height > 0 {
+		copy(n.children[:], src.children[i:j+1])
+	}
+	n.numItems = j - i
+	n.clearItems(n.numItems, maxItems)
+	n.count = n.numItems
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			n.count += n.children[i].count
+		}
+	}
+	for i := n.numItems + 1; i < maxItems+1; i++ {
+		n.children[i] = nil
+	}
+	n.aggregate(tr, height)
+	return subtree{n, height}
+}
+
+// splitAt splits the subtree into the items with keys less than key and the
+// items with keys greater or equal to key. Only the nodes on the path to key
+// are rebuilt, all others are reused as they are.
+func (tr *BTree) splitAt(t subtree, key int64) (l, r subtree) {
+	if t.root == nil {
+		return
+	}
+	return tr.splitNode(t.root, key, t.height)
+}
+
+// splitAfter splits the subtree into the items with keys less or equal to
+// key and the items with keys greater than key.
+func (tr *BTree) splitAfter(t subtree, key int64) (l, r subtree) {
+	if key == math.MaxInt64 {
+		return t, subtree{}
+	}
+	return tr.splitAt(t, key+1)
+}
+
+func (tr *BTree) splitNode(n *node, key int64, height int) (l, r subtree) {
+	i, _ := n.findFirst(key)
+	if height == 0 {
+		r = tr.partial(nil, n, i, n.numItems, 0)
+		l = tr.partial(n, n, 0, i, 0)
+		return l, r
+	}
+	cl, cr := tr.splitNode(n.children[i], key, height-1)
+	r = cr
+	if i < n.numItems {
+		sep := n.item(i)
+		rest := tr.partial(nil, n, i+1, n.numItems, height)
+		r = tr.join(cr, sep, rest)
+	}
+	l = cl
+	if i > 0 {
+		sep := n.item(i - 1)
+		rest := tr.partial(n, n, 0, i-1, height)
+		l = tr.join(rest, sep, cl)
+	} else {
+		tr.freeNode(n)
+	}
+	return l, r
+}
+
+// join concatenates l, sep and r. No key in l may be greater than the key of
+// sep, and no key in r may be less than it.
+func (tr *BTree) join(l subtree, sep item, r subtree) subtree {
+	switch {
+	case l.root == nil:
+		return tr.pushEdge(r, sep, false)
+	case r.root == nil:
+		return tr.pushEdge(l, sep, true)
+	case l.height == r.height:
+		n := tr.newNode()
+		n.setItem(0, sep)
+		n.children[0], n.children[1] = l.root, r.root
+		n.numItems = 1
+		n.count = l.root.count + r.root.count + 1
+		height := l.height + 1
+		n.fixChild(tr, 1, height)
+		n.fixChild(tr, 0, height)
+		n.aggregate(tr, height)
+		return tr.fix(subtree{n, height})
+	case l.height > r.height:
+		l.root = tr.mut(l.root)
+		l.root.joinRight(tr, sep, r, l.height)
+		return tr.fix(l)
+	default:
+		r.root = tr.mut(r.root)
+		r.root.joinLeft(tr, l, sep, r.height)
+		return tr.fix(r)
+	}
+}
+
+// concat concatenates l and r, using the last item of l to join them
+func (tr *BTree) concat(l, r subtree) subtree {
+	if l.root == nil {
+		return r
+	}
+	if r.root == nil {
+		return l
+	}
+	l.root = tr.mut(l.root)
+	sep, _ := l.root.delete(tr, true, freeKey, l.height)
+	return tr.join(tr.fix(l), sep, r)
+}
+
+// joinRight appends sep and the lower subtree r to the right edge of the
+// node at height.
+func (n *node) joinRight(tr *BTree, sep item, r subtree, height int) {
+	n.count += r.root.count + 1
+	if height == r.height+1 {
+		n.setItem(n.numItems, sep)
+		n.children[n.numItems+1] = r.root
+		n.numItems++
+		n.fixChild(tr, n.numItems, height)
+	} else {
+		i := n.numItems
+		n.mutChild(tr, i).joinRight(tr, sep, r, height-1)
+		if n.children[i].numItems == maxItems {
+			n.splitChild(tr, i, height)
+		}
+	}
+	n.aggregate(tr, height)
+}
+
+// joinLeft prepends the lower subtree l and sep to the left edge of the node
+// at height.
+func (n *node) joinLeft(tr *BTree, l subtree, sep item, height int) {
+	n.count += l.root.count + 1
+	if height == l.height+1 {
+		n.copyItems(1, n, 0, n.numItems)
+		copy(n.children[1:n.numItems+2], n.children[:n.numItems+1])
+		n.setItem(0, sep)
+		n.children[0] = l.root
+		n.numItems++
+		n.fixChild(tr, 0, height)
+	} else {
+		n.mutChild(tr, 0).joinLeft(tr, l, sep, height-1)
+		if n.children[0].numItems == maxItems {
+			n.splitChild(tr, 0, height)
+		}
+	}
+	n.aggregate(tr, height)
+}
+
+// pushEdge inserts it as the first or the last item of the subtree
+func (tr *BTree) pushEdge(t subtree, it item, last bool) subtree {
+	if t.root == nil {
+		n := tr.newNode()
+		n.setItem(0, it)
+		n.numItems = 1
+		n.count = 1
+		n.aggregate(tr, 0)
+		return subtree{n, 0}
+	}
+	t.root = tr.mut(t.root)
+	t.root.setEdge(tr, it, last, t.height)
+	return tr.fix(t)
+}
+
+func (n *node) setEdge(tr *BTree, it item, last bool, height int) {
+	i := 0
+	if last {
+		i = n.numItems
+	}
+	n.count++
+	if height == 0 {
+		n.copyItems(i+1, n, i, n.numItems)
+		n.setItem(i, it)
+		n.numItems++
+	} else {
+		n.mutChild(tr, i).setEdge(tr, it, last, height-1)
+		if n.children[i].numItems == maxItems {
+			n.splitChild(tr, i, height)
+		}
+	}
+	n.aggregate(tr, height)
+}
+
+// fixChild rebalances the child at index i until it has enough items. The
+// child may be far below the minimum, such as a root that was joined below
+// this node.
+func (n *node) fixChild(tr *BTree, i, height int) {
+	for n.numItems > 0 {
+		if i > n.numItems {
+			i = n.numItems
+		}
+		if n.children[i].numItems >= minItems {
+			return
+		}
+		n.rebalance(tr, i, height)
+	}
+}
+
+// MoveRange moves the items within the range [lo, hi] to dst and returns the
+// number of moved items. Both trees are split along the range boundaries, so
+// the nodes inside the range move over as they are. When dst already has
+// items within the range the two are merged item by item, and the moved
+// values replace the ones of dst unless it's in multi mode.
+func (tr *BTree) MoveRange(dst *BTree, lo, hi int64) int {
+	tr.checkWrite()
+	dst.checkWrite()
+	if tr.root == nil || dst == tr || lo > hi {
+		return 0
+	}
+	span := tr.span("MoveRange")
+	defer span.End(nil)
+	a, b := tr.splitAt(tr.detach(), lo)
+	m, c := tr.splitAfter(b, hi)
+	tr.attach(tr.concat(a, c))
+	moved := m.count()
+	span.SetAttribute("tinybtree.moved", int64(moved))
+	if moved == 0 {
+		return 0
+	}
+	m.root.adopt(tr, dst, m.height)
+	if tr.bloom != nil || dst.bloom != nil {
+		m.root.each(func(key int64, value interface{}) {
+			if tr.bloom != nil {
+				tr.bloom.remove(key)
+			}
+			if dst.bloom != nil {
+				dst.bloom.add(key)
+			}
+		}, m.height)
+	}
+	if tr.ret != nil || tr.subs != nil {
+		m.root.each(func(key int64, value interface{}) {
+			if tr.ret != nil {
+				tr.ret.Release(value)
+			}
+			tr.emit(Event{Kind: EventDelete, Key: key, Value: value})
+		}, m.height)
+	}
+	if dst.agg != nil {
+		m.root = dst.mut(m.root)
+		m.root.reaggregate(dst, m.height)
+	}
+	var events []Event
+	d, e := dst.splitAt(dst.detach(), lo)
+	dm, f := dst.splitAfter(e, hi)
+	if dm.root == nil && (dst.ret != nil || dst.subs != nil) {
+		m.root.each(func(key int64, value interface{}) {
+			if dst.ret != nil {
+				dst.ret.Retain(value)
+			}
+			if dst.subs != nil {
+				events = append(events,
+					Event{Kind: EventInsert, Key: key, Value: value})
+			}
+		}, m.height)
+	}
+	if dm.root != nil {
+		src := BTree{alloc: dst.alloc, gen: dst.generation()}
+		src.attach(m)
+		tmp := BTree{multi: dst.multi, agg: dst.agg, ret: dst.ret,
+			alloc: dst.alloc, gen: dst.generation()}
+		tmp.attach(dm)
+		c := newCursor(&src)
+		for ok := c.first(); ok; ok = c.next() {
+			it := c.item()
+			prev, replaced := tmp.set(it)
+			if replaced && dst.bloom != nil {
+				dst.bloom.remove(it.key)
+			}
+			if dst.subs != nil {
+				events = append(events, setEvent(it, prev, replaced))
+			}
+		}
+		src.root.free(dst, src.height)
+		m = tmp.detach()
+	}
+	dst.attach(dst.concat(dst.concat(d, m), f))
+	if dst.bloom != nil && dst.length > dst.bloom.max {
+		dst.rebuildBloom()
+	}
+	for _, e := range events {
+		dst.emit(e)
+	}
+	dst.enforceMaxLen()
+	return moved
+}
@@ -0,0 +1,128 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func treeItems(tr *BTree) (keys []int64, values []interface{}) {
+	tr.Scan(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	return
+}
+
+func TestMoveRange(t *testing.T) {
+	for n := 0; n < 200; n++ {
+		src := New(&Options{Aggregator: sumAggregator{}})
+		dst := New(&Options{Aggregator: sumAggregator{}})
+		want := map[int64]int{}
+		wantDst := map[int64]int{}
+		N := rand.Intn(3000)
+		for i := 0; i < N; i++ {
+			key := int64(rand.Intn(10000))
+			src.Set(key, i)
+			want[key] = i
+		}
+		// dst either holds keys outside of the range or interleaved keys
+		M := rand.Intn(1000)
+		lo := int64(rand.Intn(10000))
+		hi := lo + int64(rand.Intn(3000))
+		for i := 0; i < M; i++ {
+			key := int64(rand.Intn(10000))
+			if n%2 == 0 && key >= lo && key <= hi {
+				continue
+			}
+			dst.Set(key, -i)
+			wantDst[key] = -i
+		}
+		var moved int
+		for key, value := range want {
+			if key >= lo && key <= hi {
+				wantDst[key] = value
+				delete(want, key)
+				moved++
+			}
+		}
+		assert.Equal(t, moved, src.MoveRange(dst, lo, hi))
+		for _, c := range []struct {
+			tr   *BTree
+			want map[int64]int
+		}{{src, want}, {dst, wantDst}} {
+			if err := c.tr.sane(); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, len(c.want), c.tr.Len())
+			var sum int
+			for key, value := range c.want {
+				v, ok := c.tr.Get(key)
+				if !ok || v != value {
+					t.Fatalf("expected %d for %d, got %v", value, key, v)
+				}
+				sum += value
+			}
+			if len(c.want) > 0 {
+				assert.Equal(t, sum, c.tr.Aggregate())
+			}
+		}
+	}
+}
+
+func TestMoveRangeEdges(t *testing.T) {
+	var src, dst BTree
+	assert.Equal(t, 0, src.MoveRange(&dst, 0, 100))
+	for i := 0; i < 1000; i++ {
+		src.Set(int64(i), i)
+	}
+	assert.Equal(t, 0, src.MoveRange(&dst, 10, 5))
+	assert.Equal(t, 0, src.MoveRange(&src, 0, 100))
+	assert.Equal(t, 0, src.MoveRange(&dst, 2000, 3000))
+	assert.Equal(t, 1000, src.Len())
+
+	assert.Equal(t, 1000, src.MoveRange(&dst, math.MinInt64, math.MaxInt64))
+	assert.Equal(t, 0, src.Len())
+	assert.Equal(t, 1000, dst.Len())
+	if err := src.sane(); err != nil {
+		t.Fatal(err)
+	}
+
+	// move everything back piece by piece
+	for lo := int64(0); lo < 1000; lo += 37 {
+		dst.MoveRange(&src, lo, lo+36)
+		if err := src.sane(); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.sane(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, _ := treeItems(&src)
+	assert.Equal(t, 1000, len(keys))
+	for i, key := range keys {
+		assert.Equal(t, int64(i), key)
+	}
+	assert.Equal(t, 0, dst.Len())
+}
+
+func TestMoveRangeMulti(t *testing.T) {
+	src := New(&Options{Multi: true})
+	dst := New(&Options{Multi: true})
+	for i := 0; i < 500; i++ {
+		src.Set(int64(i%50), i)
+		dst.Set(int64(i%50), -i)
+	}
+	assert.Equal(t, 100, src.MoveRange(dst, 10, 19))
+	assert.Equal(t, 400, src.Len())
+	assert.Equal(t, 600, dst.Len())
+	if err := dst.sane(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []interface{}{-10, -60, -110, -160, -210, -260, -310,
+		-360, -410, -460, 10, 60, 110, 160, 210, 260, 310, 360, 410, 460},
+		dst.GetAll(10))
+}
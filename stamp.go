@@ -0,0 +1,51 @@
+package tinybtree
+
+import "time"
+
+// StampMode is the kind of stamp that a tree records for each item
+type StampMode int
+
+const (
+	// NoStamp records no stamps
+	NoStamp StampMode = iota
+	// StampTime records the time in nanoseconds since the Unix epoch
+	StampTime
+	// StampVersion records a version that starts at one and is incremented
+	// for every value that is set in the tree
+	StampVersion
+)
+
+// stamp returns the slot with a new stamp. The stamp is kept in slot.num,
+// so an inline int is boxed instead.
+func (tr *BTree) stamp(s slot) slot {
+	if _, ok := s.value.(inlineInt); ok {
+		s.value = s.num
+	}
+	switch tr.stampMode {
+	case StampTime:
+		s.num = time.Now().UnixNano()
+	case StampVersion:
+		tr.version++
+		s.num = tr.version
+	}
+	return s
+}
+
+// GetWithMeta returns the value for key together with its stamp, which
+// records when the value was set. The stamp is zero when the tree records
+// no stamps, see Options.Stamp, or when the value came from a tree that
+// records none, such as through MoveRange. In multi mode it's the first
+// value for key.
+func (tr *BTree) GetWithMeta(key int64) (
+	value interface{}, stamp int64, gotten bool,
+) {
+	it := tr.get(key)
+	if it == nil {
+		return nil, 0, false
+	}
+	if _, ok := it.value.(inlineInt); ok || tr.stampMode == NoStamp {
+		// an inline int holds the value where the stamp would be
+		return it.val(), 0, true
+	}
+	return it.value, it.num, true
+}
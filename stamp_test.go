@@ -0,0 +1,89 @@
+package tinybtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStampVersion(t *testing.T) {
+	tr := New(&Options{Stamp: StampVersion})
+	tr.Set(1, "a")
+	tr.Set(2, "b")
+	tr.Set(1, "c")
+	tr.SetInt(3, 7)
+	tests := []struct {
+		key   int64
+		value interface{}
+		stamp int64
+	}{
+		{1, "c", 3}, {2, "b", 2}, {3, int64(7), 4},
+	}
+	for _, tt := range tests {
+		value, stamp, ok := tr.GetWithMeta(tt.key)
+		if !ok {
+			t.Fatal("expected true")
+		}
+		assert.Equal(t, tt.value, value)
+		assert.Equal(t, tt.stamp, stamp)
+	}
+	v, ok := tr.GetInt(3)
+	if !ok || v != 7 {
+		t.Fatalf("expected 7, got %v", v)
+	}
+	if _, _, ok := tr.GetWithMeta(4); ok {
+		t.Fatal("expected false")
+	}
+
+	// stamps move with the items
+	for i := int64(10); i < 1000; i++ {
+		tr.Set(i, i)
+	}
+	for i := int64(10); i < 1000; i += 2 {
+		tr.Delete(i)
+	}
+	_, stamp, _ := tr.GetWithMeta(11)
+	assert.Equal(t, int64(6), stamp)
+	_, stamp, _ = tr.GetWithMeta(999)
+	assert.Equal(t, int64(994), stamp)
+}
+
+func TestStampTime(t *testing.T) {
+	tr := New(&Options{Stamp: StampTime})
+	before := time.Now().UnixNano()
+	tr.Set(1, "a")
+	after := time.Now().UnixNano()
+	_, stamp, _ := tr.GetWithMeta(1)
+	if stamp < before || stamp > after {
+		t.Fatalf("expected stamp within [%d, %d], got %d", before, after,
+			stamp)
+	}
+
+	var plain BTree
+	plain.SetInt(1, 5)
+	value, stamp, ok := plain.GetWithMeta(1)
+	if !ok {
+		t.Fatal("expected true")
+	}
+	assert.Equal(t, int64(5), value)
+	assert.Equal(t, int64(0), stamp)
+}
+
+func TestStampAdoptedInt(t *testing.T) {
+	var plain BTree
+	plain.SetInt(1, 42)
+	plain.Set(2, "two")
+	tr := New(&Options{Stamp: StampVersion})
+	tr.Set(3, "three")
+	plain.MoveRange(tr, 1, 2)
+	value, stamp, ok := tr.GetWithMeta(1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(42), value)
+	assert.Equal(t, int64(0), stamp)
+	value, _, _ = tr.GetWithMeta(2)
+	assert.Equal(t, "two", value)
+	value, stamp, _ = tr.GetWithMeta(3)
+	assert.Equal(t, "three", value)
+	assert.Equal(t, int64(1), stamp)
+}
@@ -0,0 +1,97 @@
+package tinybtree
+
+import (
+	"math"
+	"sync"
+)
+
+// SyncMap is an ordered map with the method names of sync.Map, so code that
+// uses a sync.Map with int64 keys can switch to it with few changes. It's
+// safe for concurrent use, with a lock around a BTree. Range visits the keys
+// in order. The zero value is an empty map.
+type SyncMap struct {
+	mu sync.RWMutex
+	tr BTree
+}
+
+// Load returns the value stored for key
+func (m *SyncMap) Load(key int64) (value interface{}, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tr.Get(key)
+}
+
+// Store sets the value for key
+func (m *SyncMap) Store(key int64, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tr.Set(key, value)
+}
+
+// LoadOrStore returns the existing value for key if present. Otherwise it
+// stores and returns value. The loaded result is true if the value was
+// loaded.
+func (m *SyncMap) LoadOrStore(key int64, value interface{}) (
+	actual interface{}, loaded bool,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if actual, loaded = m.tr.Get(key); loaded {
+		return actual, true
+	}
+	m.tr.Set(key, value)
+	return value, false
+}
+
+// LoadAndDelete deletes the value for key and returns it
+func (m *SyncMap) LoadAndDelete(key int64) (value interface{}, loaded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tr.Delete(key)
+}
+
+// Delete deletes the value for key
+func (m *SyncMap) Delete(key int64) {
+	m.LoadAndDelete(key)
+}
+
+// Swap stores value for key and returns the previous value
+func (m *SyncMap) Swap(key int64, value interface{}) (
+	previous interface{}, loaded bool,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tr.Set(key, value)
+}
+
+// rangeBatch is the number of items Range reads at a time
+const rangeBatch = 64
+
+// Range calls f for each key and value in key order until f returns false.
+// The items are read in small batches with the lock held and f is called
+// without it, so f may use the map. As with sync.Map, Range doesn't visit a
+// consistent snapshot of the map.
+func (m *SyncMap) Range(f func(key int64, value interface{}) bool) {
+	var keys [rangeBatch]int64
+	var values [rangeBatch]interface{}
+	pivot := int64(math.MinInt64)
+	for {
+		n := 0
+		m.mu.RLock()
+		m.tr.Ascend(pivot, func(key int64, value interface{}) bool {
+			keys[n], values[n] = key, value
+			n++
+			return n < rangeBatch
+		})
+		m.mu.RUnlock()
+		for i := 0; i < n; i++ {
+			if !f(keys[i], values[i]) {
+				return
+			}
+		}
+		if n < rangeBatch || keys[n-1] == math.MaxInt64 {
+			return
+		}
+		pivot = keys[n-1] + 1
+	}
+}
@@ -0,0 +1,85 @@
+package tinybtree
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMap(t *testing.T) {
+	var m SyncMap
+	m.Store(2, "b")
+	v, ok := m.Load(2)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "b", v)
+	actual, loaded := m.LoadOrStore(2, "x")
+	assert.Equal(t, true, loaded)
+	assert.Equal(t, "b", actual)
+	actual, loaded = m.LoadOrStore(1, "a")
+	assert.Equal(t, false, loaded)
+	assert.Equal(t, "a", actual)
+	prev, loaded := m.Swap(1, "c")
+	assert.Equal(t, true, loaded)
+	assert.Equal(t, "a", prev)
+	v, loaded = m.LoadAndDelete(1)
+	assert.Equal(t, true, loaded)
+	assert.Equal(t, "c", v)
+	_, loaded = m.LoadAndDelete(1)
+	assert.Equal(t, false, loaded)
+	m.Delete(2)
+	if _, ok := m.Load(2); ok {
+		t.Fatal("expected false")
+	}
+
+	// Range is ordered and may modify the map
+	for i := 999; i >= 0; i-- {
+		m.Store(int64(i), i)
+	}
+	m.Store(math.MaxInt64, -1)
+	var keys []int64
+	m.Range(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		m.Delete(key)
+		return true
+	})
+	assert.Equal(t, 1001, len(keys))
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, int64(i), keys[i])
+	}
+	assert.Equal(t, int64(math.MaxInt64), keys[1000])
+	assert.Equal(t, 0, m.tr.Len())
+
+	var n int
+	m.Store(1, 1)
+	m.Store(2, 2)
+	m.Range(func(key int64, value interface{}) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, 1, n)
+}
+
+func TestSyncMapConcurrent(t *testing.T) {
+	var m SyncMap
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := int64(g*1000 + i)
+				m.Store(key, i)
+				m.Load(key)
+				if i%100 == 0 {
+					m.Range(func(key int64, value interface{}) bool {
+						return true
+					})
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, 4000, m.tr.Len())
+}
@@ -0,0 +1,58 @@
+package tinybtree
+
+import "time"
+
+// TimeTree is an ordered map keyed by time. The times are stored as their
+// UnixNano, so they must be between the years 1678 and 2262, and the times
+// passed to the callbacks are in the local location without a monotonic
+// clock reading. The zero value is an empty tree.
+type TimeTree struct {
+	tr BTree
+}
+
+// Tree returns the tree holding the items keyed by UnixNano
+func (tt *TimeTree) Tree() *BTree {
+	return &tt.tr
+}
+
+// Len returns the number of items in the tree
+func (tt *TimeTree) Len() int {
+	return tt.tr.Len()
+}
+
+// SetTime sets or replaces the value for t
+func (tt *TimeTree) SetTime(t time.Time, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	return tt.tr.Set(t.UnixNano(), value)
+}
+
+// GetTime returns the value for t
+func (tt *TimeTree) GetTime(t time.Time) (value interface{}, gotten bool) {
+	return tt.tr.Get(t.UnixNano())
+}
+
+// DeleteTime deletes the value for t
+func (tt *TimeTree) DeleteTime(t time.Time) (prev interface{}, deleted bool) {
+	return tt.tr.Delete(t.UnixNano())
+}
+
+// AscendSince visits the items at or after t in order of time
+func (tt *TimeTree) AscendSince(
+	t time.Time,
+	iter func(t time.Time, value interface{}) bool,
+) {
+	tt.tr.Ascend(t.UnixNano(), func(key int64, value interface{}) bool {
+		return iter(time.Unix(0, key), value)
+	})
+}
+
+// DescendUntil visits the items at or before t in reverse order of time
+func (tt *TimeTree) DescendUntil(
+	t time.Time,
+	iter func(t time.Time, value interface{}) bool,
+) {
+	tt.tr.Descend(t.UnixNano(), func(key int64, value interface{}) bool {
+		return iter(time.Unix(0, key), value)
+	})
+}
@@ -0,0 +1,44 @@
+package tinybtree
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeTree(t *testing.T) {
+	var tt TimeTree
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		tt.SetTime(start.Add(time.Duration(i)*time.Minute), i)
+	}
+	assert.Equal(t, 100, tt.Len())
+	v, ok := tt.GetTime(start.Add(42 * time.Minute))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 42, v)
+	_, ok = tt.GetTime(start.Add(time.Second))
+	assert.Equal(t, false, ok)
+
+	var got []int
+	tt.AscendSince(start.Add(97*time.Minute-time.Second),
+		func(at time.Time, value interface{}) bool {
+			assert.Equal(t, true,
+				at.Equal(start.Add(time.Duration(value.(int))*time.Minute)))
+			got = append(got, value.(int))
+			return true
+		})
+	assert.Equal(t, []int{97, 98, 99}, got)
+	got = nil
+	tt.DescendUntil(start.Add(2*time.Minute),
+		func(at time.Time, value interface{}) bool {
+			got = append(got, value.(int))
+			return true
+		})
+	assert.Equal(t, []int{2, 1, 0}, got)
+
+	prev, ok := tt.DeleteTime(start)
+	assert.Equal(t, 0, prev)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 99, tt.Tree().Len())
+}
@@ -0,0 +1,36 @@
+package tinybtree
+
+// Tracer starts spans around the bulk operations of a tree: Build, Compact,
+// CopyRange, ShrinkToFit, the snapshots, exports and imports, TrimBelow,
+// TrimAbove and MoveRange, so that long running maintenance shows up in
+// distributed traces. It's small enough to be adapted to OpenTelemetry or
+// any other tracing library in a few lines, without tinybtree depending on
+// one.
+type Tracer interface {
+	// Start starts a span of the named operation, such as
+	// "tinybtree.ShrinkToFit"
+	Start(name string) Span
+}
+
+// Span is an operation that was started by a Tracer
+type Span interface {
+	// SetAttribute records a number that describes the operation, such as
+	// "tinybtree.items"
+	SetAttribute(key string, value int64)
+	// End ends the span, err is the error the operation failed with
+	End(err error)
+}
+
+// nopSpan is the span of a tree without a Tracer
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value int64) {}
+func (nopSpan) End(err error)                        {}
+
+// span starts a span of the named operation with the Tracer of the tree
+func (tr *BTree) span(name string) Span {
+	if tr.tracer == nil {
+		return nopSpan{}
+	}
+	return tr.tracer.Start("tinybtree." + name)
+}
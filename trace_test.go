@@ -0,0 +1,170 @@
+package tinybtree
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSpan is a span recorded by testTracer
+type testSpan struct {
+	name  string
+	attrs map[string]int64
+	ended bool
+	err   error
+}
+
+func (s *testSpan) SetAttribute(key string, value int64) {
+	s.attrs[key] = value
+}
+
+func (s *testSpan) End(err error) {
+	s.ended, s.err = true, err
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(name string) Span {
+	s := &testSpan{name: name, attrs: map[string]int64{}}
+	t.spans = append(t.spans, s)
+	return s
+}
+
+// last returns the last span and checks that it has ended
+func (t *testTracer) last(tb testing.TB) *testSpan {
+	s := t.spans[len(t.spans)-1]
+	if !s.ended {
+		tb.Fatalf("span %s not ended", s.name)
+	}
+	return s
+}
+
+func TestTracer(t *testing.T) {
+	tracer := new(testTracer)
+	opts := &Options{Tracer: tracer}
+	b := NewBuilder(opts)
+	for i := 0; i < 1000; i++ {
+		b.Add(int64(i), i)
+	}
+	tr := b.Build()
+	s := tracer.last(t)
+	assert.Equal(t, "tinybtree.Build", s.name)
+	assert.Equal(t, int64(1000), s.attrs["tinybtree.items"])
+
+	assert.Equal(t, 100, tr.TrimBelow(100))
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.TrimBelow", s.name)
+	assert.Equal(t, int64(100), s.attrs["tinybtree.removed"])
+	assert.Equal(t, 100, tr.TrimAbove(899))
+	assert.Equal(t, int64(100), tracer.last(t).attrs["tinybtree.removed"])
+	assert.Equal(t, 100, tr.MoveRange(new(BTree), 200, 299))
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.MoveRange", s.name)
+	assert.Equal(t, int64(100), s.attrs["tinybtree.moved"])
+
+	reclaimed := tr.ShrinkToFit()
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.ShrinkToFit", s.name)
+	assert.Equal(t, int64(reclaimed), s.attrs["tinybtree.reclaimed_bytes"])
+
+	c := Compact([]*BTree{tr, tr.Clone()})
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.Compact", s.name)
+	assert.Equal(t, int64(2), s.attrs["tinybtree.trees"])
+	assert.Equal(t, int64(c.Len()), s.attrs["tinybtree.items"])
+	assert.Equal(t, Tracer(tracer), c.tracer)
+
+	cr := tr.CopyRange(300, 399)
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.CopyRange", s.name)
+	assert.Equal(t, int64(100), s.attrs["tinybtree.items"])
+	assert.Equal(t, Tracer(tracer), cr.tracer)
+
+	for _, export := range []func(w io.Writer) error{
+		func(w io.Writer) error { return tr.ExportCSV(w, nil) },
+		tr.ExportNDJSON,
+		func(w io.Writer) error {
+			return cr.ExportRecords(w, func(interface{}) ([]byte, error) {
+				return nil, nil
+			})
+		},
+	} {
+		var out bytes.Buffer
+		assert.Equal(t, nil, export(&out))
+		s = tracer.last(t)
+		assert.Equal(t, true, strings.HasPrefix(s.name, "tinybtree.Export"))
+		if s.attrs["tinybtree.items"] != int64(700) &&
+			s.attrs["tinybtree.items"] != int64(100) {
+			t.Fatalf("%s: unexpected attributes %v", s.name, s.attrs)
+		}
+		if s.name == "tinybtree.ExportRecords" {
+			imported := New(opts)
+			n, err := imported.ImportRecords(&out, nil)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, 100, n)
+			s = tracer.last(t)
+			assert.Equal(t, "tinybtree.ImportRecords", s.name)
+			assert.Equal(t, int64(100), s.attrs["tinybtree.items"])
+		}
+	}
+	stats, err := New(opts).ImportNDJSON(strings.NewReader(
+		"{\"key\":1,\"value\":2}\nnot json\n"), nil)
+	assert.Equal(t, nil, err)
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.ImportNDJSON", s.name)
+	assert.Equal(t, int64(stats.Imported), s.attrs["tinybtree.items"])
+	assert.Equal(t, int64(1), s.attrs["tinybtree.errors"])
+
+	var buf bytes.Buffer
+	n, err := tr.WriteTo(&buf)
+	assert.Equal(t, nil, err)
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.WriteTo", s.name)
+	assert.Equal(t, n, s.attrs["tinybtree.bytes"])
+	assert.Equal(t, int64(700), s.attrs["tinybtree.items"])
+
+	read := New(opts)
+	_, err = read.ReadFrom(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, nil, err)
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.ReadFrom", s.name)
+	assert.Equal(t, int64(700), s.attrs["tinybtree.items"])
+	assert.Equal(t, nil, s.err)
+
+	_, err = read.ReadFrom(bytes.NewReader(buf.Bytes()[:buf.Len()/2]))
+	s = tracer.last(t)
+	assert.Equal(t, err, s.err)
+	assert.Equal(t, true, s.err != nil)
+	var serr *SnapshotError
+	assert.Equal(t, true, errors.As(s.err, &serr))
+
+	buf.Reset()
+	n, err = tr.WriteProto(&buf)
+	assert.Equal(t, nil, err)
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.WriteProto", s.name)
+	assert.Equal(t, n, s.attrs["tinybtree.bytes"])
+	_, err = New(opts).ReadProto(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, nil, err)
+	s = tracer.last(t)
+	assert.Equal(t, "tinybtree.ReadProto", s.name)
+	assert.Equal(t, int64(700), s.attrs["tinybtree.items"])
+	assert.Equal(t, n, s.attrs["tinybtree.bytes"])
+}
+
+func TestTracerNone(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		tr.TrimAbove(1000)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
@@ -0,0 +1,107 @@
+package tinybtree
+
+// TransformValues replaces every value with the result of fn in a single
+// walk over the nodes, without searching for the keys or rebalancing. Stamps
+// are kept, so they still tell when the key was set. The fn function must
+// not modify the tree. Subscribers receive an EventReplace for every item
+// once the walk is done.
+func (tr *BTree) TransformValues(
+	fn func(key int64, value interface{}) interface{},
+) {
+	tr.checkWrite()
+	if tr.root == nil {
+		return
+	}
+	var events []Event
+	tr.root = tr.mut(tr.root)
+	tr.root.transform(tr, fn, &events, tr.height)
+	for _, e := range events {
+		tr.emit(e)
+	}
+}
+
+func (n *node) transform(
+	tr *BTree,
+	fn func(key int64, value interface{}) interface{},
+	events *[]Event,
+	height int,
+) {
+	for i := 0; i <= n.numItems; i++ {
+		if height > 0 {
+			n.mutChild(tr, i).transform(tr, fn, events, height-1)
+		}
+		if i == n.numItems {
+			break
+		}
+		n.transformItem(tr, i, fn, events)
+	}
+	n.aggregate(tr, height)
+}
+
+// transformItem replaces the value of the item at index i with the result
+// of fn
+func (n *node) transformItem(
+	tr *BTree,
+	i int,
+	fn func(key int64, value interface{}) interface{},
+	events *[]Event,
+) {
+	prev := n.vals[i].val()
+	value := fn(n.keys[i], prev)
+	s := slot{value: value}
+	if tr.stampMode != NoStamp {
+		s.num = n.vals[i].num
+	}
+	n.vals[i] = s
+	if tr.ret != nil {
+		tr.ret.Retain(value)
+		tr.ret.Release(prev)
+	}
+	if tr.subs != nil {
+		*events = append(*events, Event{Kind: EventReplace,
+			Key: n.keys[i], Value: value, Prev: prev})
+	}
+}
+
+// UpdateRange is like TransformValues but only replaces the values of the
+// items with a key within the range [lo, hi], walking down only to the
+// nodes that hold them, such as for marking all orders in a time window as
+// expired.
+func (tr *BTree) UpdateRange(
+	lo, hi int64,
+	fn func(key int64, value interface{}) interface{},
+) {
+	tr.checkWrite()
+	if tr.root == nil || lo > hi {
+		return
+	}
+	var events []Event
+	tr.root = tr.mut(tr.root)
+	tr.root.transformRange(tr, lo, hi, fn, &events, tr.height)
+	for _, e := range events {
+		tr.emit(e)
+	}
+}
+
+func (n *node) transformRange(
+	tr *BTree,
+	lo, hi int64,
+	fn func(key int64, value interface{}) interface{},
+	events *[]Event,
+	height int,
+) {
+	// the children left of the first key not less than lo hold smaller
+	// keys, the child right of the last key not greater than hi larger ones
+	i, _ := n.findFirst(lo)
+	for ; i <= n.numItems; i++ {
+		if height > 0 {
+			n.mutChild(tr, i).transformRange(tr, lo, hi, fn, events,
+				height-1)
+		}
+		if i == n.numItems || n.keys[i] > hi {
+			break
+		}
+		n.transformItem(tr, i, fn, events)
+	}
+	n.aggregate(tr, height)
+}
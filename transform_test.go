@@ -0,0 +1,78 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformValues(t *testing.T) {
+	refs := refCounter{}
+	tr := New(&Options{Aggregator: sumAggregator{}, Retainer: refs,
+		Stamp: StampVersion})
+	const N = 1000
+	for i := 0; i < N; i++ {
+		tr.Set(int64(i), i)
+	}
+	var events []Event
+	tr.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+	tr.TransformValues(func(key int64, value interface{}) interface{} {
+		return value.(int) * 2
+	})
+	for i := 0; i < N; i++ {
+		v, stamp, _ := tr.GetWithMeta(int64(i))
+		assert.Equal(t, i*2, v)
+		assert.Equal(t, int64(i+1), stamp)
+	}
+	assert.Equal(t, N*(N-1), tr.Aggregate())
+	assert.Equal(t, 10+12+14, tr.AggregateRange(5, 7))
+	assert.Equal(t, N, len(events))
+	assert.Equal(t, Event{Kind: EventReplace, Key: 3, Value: 6, Prev: 3},
+		events[3])
+	assert.Equal(t, N, len(refs))
+	assert.Equal(t, 1, refs[N*2-2])
+	assert.Equal(t, 0, refs[N-1])
+
+	var empty BTree
+	empty.TransformValues(func(key int64, value interface{}) interface{} {
+		t.Fatal("empty tree")
+		return nil
+	})
+}
+
+func TestUpdateRange(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Aggregator: sumAggregator{}, Multi: multi})
+		const N = 5000
+		for i := 0; i < N; i++ {
+			key := int64(i)
+			if multi {
+				key /= 3
+			}
+			tr.Set(key, 1)
+		}
+		c := tr.Clone()
+		var visited int
+		tr.UpdateRange(100, 1200, func(key int64, value interface{}) interface{} {
+			if key < 100 || key > 1200 {
+				t.Fatalf("key %d out of range", key)
+			}
+			visited++
+			return 2
+		})
+		count := tr.EstimateCount(100, 1200)
+		assert.Equal(t, count, visited)
+		assert.Equal(t, N+count, tr.Aggregate())
+		assert.Equal(t, 2*count, tr.AggregateRange(100, 1200))
+		assert.Equal(t, N, c.Aggregate())
+		tr.UpdateRange(10, 5, func(key int64, value interface{}) interface{} {
+			t.Fatal("empty range")
+			return nil
+		})
+		if err := tr.sane(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
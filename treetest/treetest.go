@@ -0,0 +1,137 @@
+// Package treetest helps to reproduce bugs in a tinybtree.BTree. A Recorder
+// logs the writes made to a tree, and Replay runs such a log against a new
+// tree, checking the structure of the tree after every write. The tree is
+// fully deterministic, the same log always builds the same nodes, so a log
+// attached to a bug report reproduces a corruption exactly.
+package treetest
+
+import (
+	"fmt"
+
+	"github.com/scarbo87/tinybtree"
+)
+
+// OpKind is the kind of an Op
+type OpKind int
+
+const (
+	// OpSet is a call to Set
+	OpSet OpKind = iota
+	// OpDelete is a call to Delete
+	OpDelete
+	// OpDeleteOne is a call to DeleteOne
+	OpDeleteOne
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpSet:
+		return "Set"
+	case OpDelete:
+		return "Delete"
+	case OpDeleteOne:
+		return "DeleteOne"
+	}
+	return fmt.Sprintf("OpKind(%d)", int(k))
+}
+
+// Op is a write to a tree. The Value is ignored by all but OpSet.
+type Op struct {
+	Kind  OpKind
+	Key   int64
+	Value interface{}
+}
+
+// GoString formats the op as a Go literal, so that a log printed with %#v
+// can be pasted into a test
+func (op Op) GoString() string {
+	return fmt.Sprintf("treetest.Op{Kind: treetest.Op%s, Key: %d, Value: %#v}",
+		op.Kind, op.Key, op.Value)
+}
+
+// Apply makes the write of op to tr
+func (op Op) Apply(tr *tinybtree.BTree) {
+	switch op.Kind {
+	case OpSet:
+		tr.Set(op.Key, op.Value)
+	case OpDelete:
+		tr.Delete(op.Key)
+	case OpDeleteOne:
+		tr.DeleteOne(op.Key)
+	default:
+		panic("treetest: unknown op kind " + op.Kind.String())
+	}
+}
+
+// Recorder makes writes to a tree and logs them
+type Recorder struct {
+	tr  *tinybtree.BTree
+	ops []Op
+}
+
+// NewRecorder returns a Recorder writing to tr
+func NewRecorder(tr *tinybtree.BTree) *Recorder {
+	return &Recorder{tr: tr}
+}
+
+// Tree returns the tree written to
+func (r *Recorder) Tree() *tinybtree.BTree {
+	return r.tr
+}
+
+// Ops returns the writes made so far
+func (r *Recorder) Ops() []Op {
+	return r.ops
+}
+
+// Do logs op and makes the write
+func (r *Recorder) Do(op Op) {
+	r.ops = append(r.ops, op)
+	op.Apply(r.tr)
+}
+
+// Set or replace a value for a key
+func (r *Recorder) Set(key int64, value interface{}) {
+	r.Do(Op{Kind: OpSet, Key: key, Value: value})
+}
+
+// Delete a value for a key
+func (r *Recorder) Delete(key int64) {
+	r.Do(Op{Kind: OpDelete, Key: key})
+}
+
+// DeleteOne deletes one of the values for a key
+func (r *Recorder) DeleteOne(key int64) {
+	r.Do(Op{Kind: OpDeleteOne, Key: key})
+}
+
+// ReplayError is returned by Replay when the tree is corrupt after an op
+type ReplayError struct {
+	// Index of the op in the log
+	Index int
+	Op    Op
+	Err   error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("treetest: op %d (%s %d): %v",
+		e.Index, e.Op.Kind, e.Op.Key, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// Replay makes the writes of ops to a new tree created with opts, calling
+// Verify on the tree after each of them. It returns the tree and, when
+// Verify failed, a *ReplayError for the first op after which it did.
+func Replay(opts *tinybtree.Options, ops []Op) (*tinybtree.BTree, error) {
+	tr := tinybtree.New(opts)
+	for i, op := range ops {
+		op.Apply(tr)
+		if err := tr.Verify(); err != nil {
+			return tr, &ReplayError{Index: i, Op: op, Err: err}
+		}
+	}
+	return tr, nil
+}
@@ -0,0 +1,49 @@
+package treetest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/scarbo87/tinybtree"
+)
+
+func TestReplay(t *testing.T) {
+	opts := &tinybtree.Options{Multi: true}
+	r := NewRecorder(tinybtree.New(opts))
+	for i := 0; i < 5000; i++ {
+		key := int64(rand.Intn(500))
+		switch rand.Intn(3) {
+		case 0:
+			r.Set(key, i)
+		case 1:
+			r.Delete(key)
+		case 2:
+			r.DeleteOne(key)
+		}
+	}
+	if len(r.Ops()) != 5000 {
+		t.Fatalf("expected 5000 ops, got %d", len(r.Ops()))
+	}
+	tr, err := Replay(opts, r.Ops())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Checksum() != r.Tree().Checksum() || tr.Len() != r.Tree().Len() {
+		t.Fatal("replayed tree differs")
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	err := &ReplayError{Index: 3, Op: Op{Kind: OpDelete, Key: 7},
+		Err: errors.New("bad")}
+	if err.Error() != "treetest: op 3 (Delete 7): bad" {
+		t.Fatal(err.Error())
+	}
+	op := Op{Kind: OpSet, Key: 1, Value: "a"}
+	s := fmt.Sprintf("%#v", op)
+	if s != `treetest.Op{Kind: treetest.OpSet, Key: 1, Value: "a"}` {
+		t.Fatal(s)
+	}
+}
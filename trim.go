@@ -0,0 +1,58 @@
+package tinybtree
+
+// TrimBelow removes the items with keys less than key and returns their
+// number. The tree is split along the path to key, so the removed nodes are
+// dropped as a whole instead of deleting the items one by one, unless the
+// tree has a Retainer, subscribers or a bloom filter, which are told about
+// every removed item.
+func (tr *BTree) TrimBelow(key int64) (removed int) {
+	tr.checkWrite()
+	if tr.root == nil {
+		return 0
+	}
+	span := tr.span("TrimBelow")
+	defer span.End(nil)
+	l, r := tr.splitAt(tr.detach(), key)
+	tr.attach(r)
+	removed = tr.drop(l)
+	span.SetAttribute("tinybtree.removed", int64(removed))
+	return removed
+}
+
+// TrimAbove removes the items with keys greater than key and returns their
+// number, see TrimBelow.
+func (tr *BTree) TrimAbove(key int64) (removed int) {
+	tr.checkWrite()
+	if tr.root == nil {
+		return 0
+	}
+	span := tr.span("TrimAbove")
+	defer span.End(nil)
+	l, r := tr.splitAfter(tr.detach(), key)
+	tr.attach(l)
+	removed = tr.drop(r)
+	span.SetAttribute("tinybtree.removed", int64(removed))
+	return removed
+}
+
+// drop releases the items of a subtree that was taken out of the tree and
+// frees its nodes
+func (tr *BTree) drop(t subtree) int {
+	if t.root == nil {
+		return 0
+	}
+	if tr.ret != nil || tr.subs != nil || tr.bloom != nil {
+		t.root.each(func(key int64, value interface{}) {
+			if tr.bloom != nil {
+				tr.bloom.remove(key)
+			}
+			if tr.ret != nil {
+				tr.ret.Release(value)
+			}
+			tr.emit(Event{Kind: EventDelete, Key: key, Value: value})
+		}, t.height)
+	}
+	n := t.count()
+	t.root.free(tr, t.height)
+	return n
+}
@@ -0,0 +1,46 @@
+package tinybtree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrim(t *testing.T) {
+	refs := refCounter{}
+	alloc := &trackingAllocator{live: map[*Node]bool{}}
+	tr := New(&Options{Retainer: refs, Allocator: alloc, BloomFilter: true})
+	assert.Equal(t, 0, tr.TrimBelow(10))
+	for _, i := range rand.Perm(10000) {
+		tr.Set(int64(i), i)
+	}
+	var deleted int
+	tr.Subscribe(func(e Event) {
+		if e.Kind == EventDelete {
+			deleted++
+		}
+	})
+	assert.Equal(t, 2500, tr.TrimBelow(2500))
+	assert.Equal(t, 2499, tr.TrimAbove(7500))
+	assert.Equal(t, 0, tr.TrimAbove(math.MaxInt64))
+	assert.Equal(t, 0, tr.TrimBelow(math.MinInt64))
+	assert.Equal(t, 4999, deleted)
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5001, tr.Len())
+	assert.Equal(t, 5001, len(refs))
+	assert.Equal(t, tr.countNodes(), len(alloc.live))
+	_, ok := tr.Get(2499)
+	assert.Equal(t, false, ok)
+	v, _ := tr.Get(2500)
+	assert.Equal(t, 2500, v)
+	v, _ = tr.Get(7500)
+	assert.Equal(t, 7500, v)
+
+	assert.Equal(t, 5001, tr.TrimAbove(0))
+	assert.Equal(t, 0, tr.Len())
+	assert.Equal(t, 0, len(alloc.live))
+}
@@ -0,0 +1,105 @@
+package tinybtree
+
+// Txn is a group of changes to a tree made within Update
+type Txn struct {
+	tr   *BTree
+	undo []undoEntry
+	seen map[int64]bool
+}
+
+// undoEntry holds the values of a key from before it was first changed
+type undoEntry struct {
+	key    int64
+	values []interface{}
+}
+
+// Update calls fn with a transaction on the tree. When fn returns an error
+// or panics all changes made through the transaction are rolled back. Only
+// one Update runs at a time, so concurrent transactions don't see each
+// other's changes. Changes that are made to the tree outside of Update are
+// not serialized.
+//
+// The values replaced or deleted within Update may be restored by a
+// rollback, so they are not put back into the Pool, and with a Retainer
+// they are retained until the transaction is done.
+func (tr *BTree) Update(fn func(tx *Txn) error) (err error) {
+	tr.txnMu.Lock()
+	defer tr.txnMu.Unlock()
+	tx := &Txn{tr: tr}
+	recycling, gen := tr.recycling, tr.gen
+	tr.recycling = false
+	done := false
+	defer func() {
+		if !done {
+			tx.rollback()
+		}
+		tx.release()
+		// a Clone within fn turns recycling off for good
+		if tr.gen == gen {
+			tr.recycling = recycling
+		}
+	}()
+	err = fn(tx)
+	done = err == nil
+	return err
+}
+
+// Get a value for key
+func (tx *Txn) Get(key int64) (value interface{}, gotten bool) {
+	return tx.tr.Get(key)
+}
+
+// Set or replace a value for a key
+func (tx *Txn) Set(key int64, value interface{}) (
+	prev interface{}, replaced bool,
+) {
+	tx.save(key)
+	return tx.tr.Set(key, value)
+}
+
+// Delete a value for a key
+func (tx *Txn) Delete(key int64) (prev interface{}, deleted bool) {
+	tx.save(key)
+	return tx.tr.Delete(key)
+}
+
+// save records the values of key the first time it's changed
+func (tx *Txn) save(key int64) {
+	if tx.seen[key] {
+		return
+	}
+	if tx.seen == nil {
+		tx.seen = make(map[int64]bool)
+	}
+	tx.seen[key] = true
+	values := tx.tr.GetAll(key)
+	if tx.tr.ret != nil {
+		for _, value := range values {
+			tx.tr.ret.Retain(value)
+		}
+	}
+	tx.undo = append(tx.undo, undoEntry{key, values})
+}
+
+// rollback restores the saved values
+func (tx *Txn) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		u := tx.undo[i]
+		tx.tr.Delete(u.key)
+		for _, value := range u.values {
+			tx.tr.Set(u.key, value)
+		}
+	}
+}
+
+// release releases the saved values once the transaction is done
+func (tx *Txn) release() {
+	if tx.tr.ret != nil {
+		for _, u := range tx.undo {
+			for _, value := range u.values {
+				tx.tr.ret.Release(value)
+			}
+		}
+	}
+	tx.undo, tx.seen = nil, nil
+}
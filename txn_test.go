@@ -0,0 +1,165 @@
+package tinybtree
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxn(t *testing.T) {
+	for _, multi := range []bool{false, true} {
+		tr := New(&Options{Multi: multi})
+		for i := 0; i < 100; i++ {
+			tr.Set(int64(i), i)
+		}
+		tr.Set(5, 55)
+		n := tr.Len()
+		errFail := errors.New("fail")
+		err := tr.Update(func(tx *Txn) error {
+			tx.Set(1, "a")
+			tx.Set(1, "b")
+			tx.Delete(5)
+			tx.Set(1000, "c")
+			tx.Delete(2000)
+			value, _ := tx.Get(1000)
+			assert.Equal(t, "c", value)
+			return errFail
+		})
+		assert.Equal(t, errFail, err)
+		assert.Equal(t, n, tr.Len())
+		assert.Equal(t, []interface{}{1}, tr.GetAll(1))
+		if multi {
+			assert.Equal(t, []interface{}{5, 55}, tr.GetAll(5))
+		} else {
+			assert.Equal(t, []interface{}{55}, tr.GetAll(5))
+		}
+		_, ok := tr.Get(1000)
+		assert.Equal(t, false, ok)
+		if err := tr.sane(); err != nil {
+			t.Fatal(err)
+		}
+
+		func() {
+			defer func() {
+				assert.Equal(t, "boom", recover())
+			}()
+			tr.Update(func(tx *Txn) error {
+				tx.Delete(3)
+				panic("boom")
+			})
+		}()
+		_, ok = tr.Get(3)
+		assert.Equal(t, true, ok)
+
+		err = tr.Update(func(tx *Txn) error {
+			tx.Delete(3)
+			tx.Set(4, "x")
+			return nil
+		})
+		assert.Equal(t, nil, err)
+		_, ok = tr.Get(3)
+		assert.Equal(t, false, ok)
+	}
+}
+
+func TestTxnSerialized(t *testing.T) {
+	var tr BTree
+	tr.Set(0, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tr.Update(func(tx *Txn) error {
+					value, _ := tx.Get(0)
+					tx.Set(0, value.(int)+1)
+					return nil
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	value, _ := tr.Get(0)
+	assert.Equal(t, 800, value)
+}
+
+func TestTxnRollbackPool(t *testing.T) {
+	tr := New(&Options{Pool: func() interface{} { return new(pooledPoint) }})
+	for i := 0; i < 10; i++ {
+		tr.SetPooled(int64(i), func(value interface{}) {
+			*value.(*pooledPoint) = pooledPoint{i, -i}
+		})
+	}
+	err := tr.Update(func(tx *Txn) error {
+		tx.Set(1, &pooledPoint{})
+		tx.Delete(2)
+		return errors.New("rollback")
+	})
+	assert.Equal(t, "rollback", err.Error())
+	// the restored values must not have gone into the pool
+	for i := 0; i < 100; i++ {
+		tr.SetPooled(int64(100+i), func(value interface{}) {
+			*value.(*pooledPoint) = pooledPoint{}
+		})
+	}
+	v, _ := tr.Get(1)
+	assert.Equal(t, pooledPoint{1, -1}, *v.(*pooledPoint))
+	v, _ = tr.Get(2)
+	assert.Equal(t, pooledPoint{2, -2}, *v.(*pooledPoint))
+
+	// recycling is back on after Update
+	tr.Delete(100)
+	assert.Equal(t, true, tr.recycling)
+}
+
+// freeingRetainer frees values when their count drops to zero and panics
+// when a freed value is retained again
+type freeingRetainer struct {
+	refs  map[interface{}]int
+	freed map[interface{}]bool
+}
+
+func (r *freeingRetainer) Retain(value interface{}) {
+	if r.freed[value] {
+		panic("retained a freed value")
+	}
+	r.refs[value]++
+}
+
+func (r *freeingRetainer) Release(value interface{}) {
+	r.refs[value]--
+	if r.refs[value] == 0 {
+		delete(r.refs, value)
+		r.freed[value] = true
+	}
+}
+
+func TestTxnRollbackRetainer(t *testing.T) {
+	ret := &freeingRetainer{map[interface{}]int{}, map[interface{}]bool{}}
+	tr := New(&Options{Retainer: ret})
+	for i := 0; i < 10; i++ {
+		tr.Set(int64(i), i)
+	}
+	err := tr.Update(func(tx *Txn) error {
+		tx.Set(1, 100)
+		tx.Delete(2)
+		return errors.New("rollback")
+	})
+	assert.Equal(t, "rollback", err.Error())
+	assert.Equal(t, 1, ret.refs[1])
+	assert.Equal(t, 1, ret.refs[2])
+	assert.Equal(t, true, ret.freed[100])
+
+	err = tr.Update(func(tx *Txn) error {
+		tx.Set(1, 200)
+		tx.Delete(2)
+		return nil
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, ret.refs[1])
+	assert.Equal(t, 0, ret.refs[2])
+	assert.Equal(t, true, ret.freed[2])
+}
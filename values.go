@@ -0,0 +1,75 @@
+package tinybtree
+
+// AppendValue adds a value for a key after the values that are already set
+// for it. It's Set under another name, for code that manages the values of
+// a key in multi mode as a list, outside of multi mode it replaces the
+// value.
+func (tr *BTree) AppendValue(key int64, value interface{}) {
+	tr.Set(key, value)
+}
+
+// CountValues returns the number of values for a key in O(log n) time
+func (tr *BTree) CountValues(key int64) int {
+	return tr.countRange(key, key)
+}
+
+// RemoveValue deletes the values for a key for which pred returns true,
+// keeping the order of the other values, and returns the number of deleted
+// values. The values to keep are moved behind the ones to delete, which
+// are then deleted from the front with DeleteOne, so the Retainer and the
+// subscribers are only told about the deleted values.
+func (tr *BTree) RemoveValue(key int64, pred func(value interface{}) bool) (
+	removed int,
+) {
+	tr.checkWrite()
+	if tr.root == nil {
+		return 0
+	}
+	first := tr.root.rank(key, tr.height)
+	count := tr.countRange(key, key)
+	slots := make([]slot, 0, count)
+	var kept []slot
+	for i := 0; i < count; i++ {
+		s := tr.root.at(first+i, tr.height).slot
+		if pred(s.val()) {
+			slots = append(slots, s)
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	removed = len(slots)
+	if removed == 0 {
+		return 0
+	}
+	slots = append(slots, kept...)
+	tr.root = tr.mut(tr.root)
+	for i, s := range slots {
+		tr.root.setAt(tr, first+i, s, tr.height)
+	}
+	for i := 0; i < removed; i++ {
+		tr.DeleteOne(key)
+	}
+	return removed
+}
+
+// setAt replaces the value of the item at index, see at
+func (n *node) setAt(tr *BTree, index int, s slot, height int) {
+	defer n.aggregate(tr, height)
+	if height == 0 {
+		n.vals[index] = s
+		return
+	}
+	for i := 0; i < n.numItems; i++ {
+		count := n.children[i].count
+		if index < count {
+			n.mutChild(tr, i).setAt(tr, index, s, height-1)
+			return
+		}
+		if index == count {
+			n.vals[i] = s
+			return
+		}
+		index -= count + 1
+	}
+	n.mutChild(tr, n.numItems).setAt(tr, index, s, height-1)
+}
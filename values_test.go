@@ -0,0 +1,42 @@
+package tinybtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveValue(t *testing.T) {
+	refs := refCounter{}
+	tr := New(&Options{Multi: true, Retainer: refs,
+		Aggregator: sumAggregator{}})
+	for i := 0; i < 1000; i++ {
+		tr.AppendValue(int64(i%3), i)
+	}
+	assert.Equal(t, 334, tr.CountValues(0))
+	assert.Equal(t, 333, tr.CountValues(2))
+	assert.Equal(t, 0, tr.CountValues(3))
+
+	c := tr.Clone()
+	even := func(value interface{}) bool { return value.(int)%2 == 0 }
+	assert.Equal(t, 167, tr.RemoveValue(0, even))
+	assert.Equal(t, 0, tr.RemoveValue(0, even))
+	assert.Equal(t, 0, tr.RemoveValue(5, even))
+	assert.Equal(t, 167, tr.CountValues(0))
+	values := tr.GetAll(0)
+	for i, v := range values {
+		assert.Equal(t, 3+i*6, v)
+	}
+	if err := tr.sane(); err != nil {
+		t.Fatal(err)
+	}
+	sum := 0
+	for _, v := range values {
+		sum += v.(int)
+	}
+	assert.Equal(t, sum, tr.AggregateRange(0, 0))
+	// the removed values are still held by the clone, the others twice
+	assert.Equal(t, 1, refs[0])
+	assert.Equal(t, 2, refs[3])
+	assert.Equal(t, 334, c.CountValues(0))
+}
@@ -0,0 +1,149 @@
+package tinybtree
+
+// View is a read-only view of a tree whose values are transformed and
+// filtered while they're visited. Nothing is copied, the functions run on
+// every access.
+type View struct {
+	tr *BTree
+	// fn transforms a value, or returns false when the item is filtered out.
+	// It's passed the keys of the view.
+	fn    func(key int64, value interface{}) (interface{}, bool)
+	delta int64 // added to the keys of the tree, see Offset
+}
+
+// View returns a view of the tree which passes the items through unchanged
+func (tr *BTree) View() View {
+	return View{tr: tr}
+}
+
+// OffsetView returns a view of the tree with delta added to every key, see
+// View.Offset
+func (tr *BTree) OffsetView(delta int64) View {
+	return View{tr: tr, delta: delta}
+}
+
+func (v View) then(
+	next func(key int64, value interface{}) (interface{}, bool),
+) View {
+	prev := v.fn
+	if prev == nil {
+		v.fn = next
+		return v
+	}
+	v.fn = func(key int64, value interface{}) (interface{}, bool) {
+		value, ok := prev(key, value)
+		if !ok {
+			return nil, false
+		}
+		return next(key, value)
+	}
+	return v
+}
+
+// Map returns a view with the values replaced by the result of fn
+func (v View) Map(fn func(key int64, value interface{}) interface{}) View {
+	return v.then(func(key int64, value interface{}) (interface{}, bool) {
+		return fn(key, value), true
+	})
+}
+
+// Offset returns a view with delta added to every key, such as for moving
+// timestamps to another epoch without rewriting them. The keys are
+// translated while they're visited, and the pivots and the keys passed to
+// Get the other way around. The translated keys must fit in an int64.
+func (v View) Offset(delta int64) View {
+	prev := v.fn
+	if prev != nil {
+		// the functions so far see the keys from before the offset
+		v.fn = func(key int64, value interface{}) (interface{}, bool) {
+			return prev(key-delta, value)
+		}
+	}
+	v.delta += delta
+	return v
+}
+
+// Filter returns a view with only the items for which pred returns true
+func (v View) Filter(pred func(key int64, value interface{}) bool) View {
+	return v.then(func(key int64, value interface{}) (interface{}, bool) {
+		return value, pred(key, value)
+	})
+}
+
+func (v View) wrap(
+	iter func(key int64, value interface{}) bool,
+) func(key int64, value interface{}) bool {
+	if v.fn == nil && v.delta == 0 {
+		return iter
+	}
+	return func(key int64, value interface{}) bool {
+		key += v.delta
+		if v.fn == nil {
+			return iter(key, value)
+		}
+		value, ok := v.fn(key, value)
+		if !ok {
+			return true
+		}
+		return iter(key, value)
+	}
+}
+
+// untranslate returns the key of the tree for a key of the view. When it
+// doesn't fit in an int64, out is -1 for a key below all keys of the tree
+// and 1 for one above them.
+func (v View) untranslate(key int64) (tkey int64, out int) {
+	tkey = key - v.delta
+	if v.delta > 0 && tkey > key {
+		return 0, -1
+	}
+	if v.delta < 0 && tkey < key {
+		return 0, 1
+	}
+	return tkey, 0
+}
+
+// Get a value for key
+func (v View) Get(key int64) (value interface{}, gotten bool) {
+	tkey, out := v.untranslate(key)
+	if out != 0 {
+		return nil, false
+	}
+	value, gotten = v.tr.Get(tkey)
+	if gotten && v.fn != nil {
+		value, gotten = v.fn(key, value)
+	}
+	return
+}
+
+// Scan all items in the view
+func (v View) Scan(iter func(key int64, value interface{}) bool) {
+	v.tr.Scan(v.wrap(iter))
+}
+
+// Ascend the view within the range [pivot, last]
+func (v View) Ascend(pivot int64, iter func(key int64, value interface{}) bool) {
+	switch tkey, out := v.untranslate(pivot); out {
+	case -1:
+		v.tr.Scan(v.wrap(iter))
+	case 0:
+		v.tr.Ascend(tkey, v.wrap(iter))
+	}
+}
+
+// Reverse all items in the view
+func (v View) Reverse(iter func(key int64, value interface{}) bool) {
+	v.tr.Reverse(v.wrap(iter))
+}
+
+// Descend the view within the range [pivot, first]
+func (v View) Descend(
+	pivot int64, iter func(key int64, value interface{}) bool,
+) {
+	switch tkey, out := v.untranslate(pivot); out {
+	case 0:
+		v.tr.Descend(tkey, v.wrap(iter))
+	case 1:
+		v.tr.Reverse(v.wrap(iter))
+	}
+}
@@ -0,0 +1,161 @@
+package tinybtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestView(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 100; i++ {
+		tr.Set(int64(i), i)
+	}
+	v := tr.View().
+		Filter(func(key int64, value interface{}) bool {
+			return value.(int)%2 == 0
+		}).
+		Map(func(key int64, value interface{}) interface{} {
+			return value.(int) * 10
+		}).
+		Filter(func(key int64, value interface{}) bool {
+			return value.(int) < 500
+		})
+
+	var values []interface{}
+	v.Scan(func(key int64, value interface{}) bool {
+		values = append(values, value)
+		return true
+	})
+	assert.Equal(t, []interface{}{0, 20, 40, 60, 80, 100, 120, 140, 160, 180,
+		200, 220, 240, 260, 280, 300, 320, 340, 360, 380, 400, 420, 440, 460,
+		480}, values)
+
+	values = nil
+	v.Descend(45, func(key int64, value interface{}) bool {
+		values = append(values, value)
+		return len(values) < 3
+	})
+	assert.Equal(t, []interface{}{440, 420, 400}, values)
+
+	values = nil
+	v.Ascend(45, func(key int64, value interface{}) bool {
+		values = append(values, value)
+		return true
+	})
+	assert.Equal(t, []interface{}{460, 480}, values)
+
+	var n int
+	v.Reverse(func(key int64, value interface{}) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 25, n)
+
+	value, ok := v.Get(10)
+	assert.Equal(t, 100, value)
+	assert.Equal(t, true, ok)
+	_, ok = v.Get(11)
+	assert.Equal(t, false, ok)
+	_, ok = v.Get(60)
+	assert.Equal(t, false, ok)
+
+	// the view follows changes to the tree
+	tr.Set(10, 12)
+	value, _ = v.Get(10)
+	assert.Equal(t, 120, value)
+
+	value, ok = tr.View().Get(11)
+	assert.Equal(t, 11, value)
+	assert.Equal(t, true, ok)
+}
+
+func TestOffsetView(t *testing.T) {
+	var tr BTree
+	for i := 0; i < 10; i++ {
+		tr.Set(int64(i), i)
+	}
+	v := tr.OffsetView(100)
+	value, ok := v.Get(103)
+	assert.Equal(t, 3, value)
+	assert.Equal(t, true, ok)
+	_, ok = v.Get(3)
+	assert.Equal(t, false, ok)
+
+	var keys []int64
+	v.Ascend(105, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []int64{105, 106, 107, 108, 109}, keys)
+	keys = nil
+	v.Descend(101, func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []int64{101, 100}, keys)
+
+	// the filter before the offset sees the keys of the tree, the one
+	// after it the keys of the view
+	v = tr.View().
+		Filter(func(key int64, value interface{}) bool {
+			return key%2 == 0
+		}).
+		Offset(-10).
+		Filter(func(key int64, value interface{}) bool {
+			return key > -6
+		}).
+		Offset(1)
+	keys = nil
+	v.Scan(func(key int64, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []int64{-3, -1}, keys)
+	value, ok = v.Get(-3)
+	assert.Equal(t, 6, value)
+	assert.Equal(t, true, ok)
+}
+
+func TestOffsetViewBounds(t *testing.T) {
+	var tr BTree
+	for i := -2; i <= 2; i++ {
+		tr.Set(int64(i), i)
+	}
+	keys := func(v View, descend bool, pivot int64) (keys []int64) {
+		iter := func(key int64, value interface{}) bool {
+			keys = append(keys, key)
+			return true
+		}
+		if descend {
+			v.Descend(pivot, iter)
+		} else {
+			v.Ascend(pivot, iter)
+		}
+		return keys
+	}
+	up, down := tr.OffsetView(10), tr.OffsetView(-10)
+	assert.Equal(t, []int64{8, 9, 10, 11, 12},
+		keys(up, false, math.MinInt64))
+	assert.Equal(t, []int64(nil), keys(up, true, math.MinInt64))
+	assert.Equal(t, []int64{-8, -9, -10, -11, -12},
+		keys(down, true, math.MaxInt64))
+	assert.Equal(t, []int64(nil), keys(down, false, math.MaxInt64))
+	assert.Equal(t, []int64{12}, keys(up, false, 12))
+	assert.Equal(t, []int64{-12}, keys(down, true, -12))
+
+	_, ok := up.Get(math.MinInt64)
+	assert.Equal(t, false, ok)
+	_, ok = down.Get(math.MaxInt64)
+	assert.Equal(t, false, ok)
+	v, ok := up.Get(12)
+	assert.Equal(t, 2, v)
+
+	// keys at the bounds of the tree that fit after the offset
+	tr.Set(math.MinInt64, "min")
+	v, _ = down.Offset(20).Get(math.MinInt64 + 10)
+	assert.Equal(t, "min", v)
+	assert.Equal(t, []int64{math.MinInt64 + 10, 8},
+		keys(up, false, math.MinInt64)[:2])
+}
@@ -0,0 +1,110 @@
+package tinybtree
+
+// SortedSet is a set of unique members ordered by an int64 score, in the
+// style of a Redis sorted set. The scores are the keys of a tree in multi
+// mode, members with the same score are in the order they were added. The
+// members must be comparable.
+type SortedSet struct {
+	tr     *BTree
+	scores map[interface{}]int64
+}
+
+// NewSortedSet returns a new empty SortedSet
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		tr:     New(&Options{Multi: true}),
+		scores: make(map[interface{}]int64),
+	}
+}
+
+// ZAdd adds member with score, or moves an existing member to score.
+// Returns true when the member was added.
+func (z *SortedSet) ZAdd(score int64, member interface{}) (added bool) {
+	old, ok := z.scores[member]
+	if ok {
+		if old == score {
+			return false
+		}
+		z.remove(old, member)
+	}
+	z.tr.Set(score, member)
+	z.scores[member] = score
+	return !ok
+}
+
+// ZScore returns the score of member
+func (z *SortedSet) ZScore(member interface{}) (score int64, ok bool) {
+	score, ok = z.scores[member]
+	return score, ok
+}
+
+// ZRem removes member. Returns false when it's not in the set.
+func (z *SortedSet) ZRem(member interface{}) (removed bool) {
+	score, ok := z.scores[member]
+	if !ok {
+		return false
+	}
+	z.remove(score, member)
+	delete(z.scores, member)
+	return true
+}
+
+// remove deletes member from the tree. The other members with the same
+// score are set again, which keeps their order.
+func (z *SortedSet) remove(score int64, member interface{}) {
+	members := z.tr.GetAll(score)
+	z.tr.Delete(score)
+	for _, m := range members {
+		if m != member {
+			z.tr.Set(score, m)
+		}
+	}
+}
+
+// ZCard returns the number of members
+func (z *SortedSet) ZCard() int {
+	return z.tr.Len()
+}
+
+// ZCount returns the number of members with a score within [min, max] in
+// O(log n) time.
+func (z *SortedSet) ZCount(min, max int64) int {
+	return z.tr.countRange(min, max)
+}
+
+// ZRangeByScore visits the members with a score within [min, max] in
+// ascending order of score.
+func (z *SortedSet) ZRangeByScore(
+	min, max int64,
+	iter func(score int64, member interface{}) bool,
+) {
+	z.tr.Ascend(min, func(score int64, member interface{}) bool {
+		return score <= max && iter(score, member)
+	})
+}
+
+// ZRevRangeByScore visits the members with a score within [min, max] in
+// descending order of score.
+func (z *SortedSet) ZRevRangeByScore(
+	max, min int64,
+	iter func(score int64, member interface{}) bool,
+) {
+	z.tr.Descend(max, func(score int64, member interface{}) bool {
+		return score >= min && iter(score, member)
+	})
+}
+
+// ZRemRangeByScore removes the members with a score within [min, max] and
+// returns the number of removed members.
+func (z *SortedSet) ZRemRangeByScore(min, max int64) int {
+	if min > max {
+		return 0
+	}
+	removed := New(&Options{Multi: true})
+	n := z.tr.MoveRange(removed, min, max)
+	removed.Scan(func(score int64, member interface{}) bool {
+		delete(z.scores, member)
+		return true
+	})
+	return n
+}
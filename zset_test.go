@@ -0,0 +1,60 @@
+package tinybtree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedSet(t *testing.T) {
+	z := NewSortedSet()
+	assert.Equal(t, true, z.ZAdd(10, "a"))
+	assert.Equal(t, true, z.ZAdd(20, "b"))
+	assert.Equal(t, true, z.ZAdd(20, "c"))
+	assert.Equal(t, true, z.ZAdd(30, "d"))
+	assert.Equal(t, false, z.ZAdd(10, "a"))
+	assert.Equal(t, 4, z.ZCard())
+
+	members := func(min, max int64) (ms []interface{}) {
+		z.ZRangeByScore(min, max, func(score int64, m interface{}) bool {
+			ms = append(ms, m)
+			return true
+		})
+		return ms
+	}
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"},
+		members(math.MinInt64, math.MaxInt64))
+	assert.Equal(t, []interface{}{"b", "c"}, members(11, 29))
+	assert.Equal(t, 2, z.ZCount(20, 20))
+	assert.Equal(t, 4, z.ZCount(math.MinInt64, math.MaxInt64))
+	assert.Equal(t, 0, z.ZCount(21, 29))
+
+	var rev []interface{}
+	z.ZRevRangeByScore(30, 20, func(score int64, m interface{}) bool {
+		rev = append(rev, m)
+		return true
+	})
+	assert.Equal(t, []interface{}{"d", "c", "b"}, rev)
+
+	// moving a member keeps the others with the same score in order
+	assert.Equal(t, false, z.ZAdd(5, "b"))
+	score, ok := z.ZScore("b")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(5), score)
+	assert.Equal(t, []interface{}{"b", "a", "c", "d"},
+		members(math.MinInt64, math.MaxInt64))
+	assert.Equal(t, 4, z.ZCard())
+
+	assert.Equal(t, true, z.ZRem("a"))
+	assert.Equal(t, false, z.ZRem("a"))
+	_, ok = z.ZScore("a")
+	assert.Equal(t, false, ok)
+
+	assert.Equal(t, 2, z.ZRemRangeByScore(5, 20))
+	assert.Equal(t, []interface{}{"d"}, members(math.MinInt64, math.MaxInt64))
+	_, ok = z.ZScore("b")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, true, z.ZAdd(1, "b"))
+	assert.Equal(t, 2, z.ZCard())
+}